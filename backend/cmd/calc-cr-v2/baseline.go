@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
+	"github.com/JustinWhittecar/slic/internal/simdb"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ─── Baseline panel ─────────────────────────────────────────────────────────
+//
+// By default every CR is measured against the hand-built HBK-4P. -baseline
+// picks other variants by ID or model code, each with an optional weight
+// ('MAD-3R:2,1234'), and -baseline-era picks one light, medium, heavy and
+// assault introduced in an era: for each class the variant of median BV.
+
+// baselineSpec is a panel entry as the coordinator resolved it; workers
+// build the same panel from these.
+type baselineSpec struct {
+	Variant *sim.Variant // nil for the built-in HBK-4P
+	ID      int          // 0 if the built-in HBK-4P isn't in the database
+	Name    string
+	Weight  float64
+	Turns   float64
+}
+
+// weightClasses are the tonnage bands -baseline-era picks a mech from.
+var weightClasses = []struct {
+	name     string
+	min, max int
+}{
+	{"light", 20, 35},
+	{"medium", 40, 55},
+	{"heavy", 60, 75},
+	{"assault", 80, 100},
+}
+
+// resolveBaselines turns -baseline or -baseline-era into a panel; with
+// neither it is the built-in HBK-4P alone.
+func resolveBaselines(ctx context.Context, pool *pgxpool.Pool, list, era string) ([]baselineSpec, error) {
+	type pick struct {
+		id     int
+		weight float64
+	}
+	var picks []pick
+	switch {
+	case list != "" && era != "":
+		return nil, fmt.Errorf("-baseline and -baseline-era can't be used together")
+	case list != "":
+		for _, entry := range strings.Split(list, ",") {
+			key, weight := strings.TrimSpace(entry), 1.0
+			if k, w, ok := strings.Cut(key, ":"); ok {
+				var err error
+				if weight, err = strconv.ParseFloat(w, 64); err != nil || weight <= 0 {
+					return nil, fmt.Errorf("baseline %q: weight must be a positive number", entry)
+				}
+				key = k
+			}
+			id, err := lookupVariantID(ctx, pool, key)
+			if err != nil {
+				return nil, err
+			}
+			picks = append(picks, pick{id, weight})
+		}
+	case era != "":
+		for _, wc := range weightClasses {
+			id, err := medianBVVariant(ctx, pool, era, wc.min, wc.max)
+			if err != nil {
+				return nil, fmt.Errorf("%s baseline: %w", wc.name, err)
+			}
+			if id == 0 {
+				log.Printf("No %s mechs introduced in %s, leaving it out of the panel", wc.name, era)
+				continue
+			}
+			picks = append(picks, pick{id, 1})
+		}
+		if len(picks) == 0 {
+			return nil, fmt.Errorf("no mechs introduced in era %q", era)
+		}
+	default:
+		var id int
+		err := pool.QueryRow(ctx, `SELECT COALESCE(MIN(id), 0) FROM variants WHERE model_code = 'HBK-4P'`).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		return []baselineSpec{{ID: id, Name: "Hunchback HBK-4P (built-in)", Weight: 1}}, nil
+	}
+
+	all, err := simdb.LoadVariants(ctx, pool, "")
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]*sim.Variant{}
+	for i := range all {
+		byID[all[i].ID] = &all[i]
+	}
+	variants := make([]sim.Variant, len(picks))
+	for i, p := range picks {
+		v := byID[p.id]
+		if v == nil {
+			return nil, fmt.Errorf("baseline variant %d has no stats", p.id)
+		}
+		variants[i] = *v
+	}
+	simdb.LoadWeapons(ctx, pool, variants)
+	specs := make([]baselineSpec, len(picks))
+	for i, p := range picks {
+		v := &variants[i]
+		specs[i] = baselineSpec{Variant: v, ID: v.ID, Name: v.Name + " " + v.ModelCode, Weight: p.weight}
+	}
+	return specs, nil
+}
+
+// lookupVariantID finds a variant by ID or exact model code.
+func lookupVariantID(ctx context.Context, pool *pgxpool.Pool, key string) (int, error) {
+	if id, err := strconv.Atoi(key); err == nil {
+		return id, nil
+	}
+	rows, err := pool.Query(ctx, `SELECT id FROM variants WHERE model_code = $1 ORDER BY id`, key)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, strconv.Itoa(id))
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("no variant with model code %q", key)
+	case 1:
+		return strconv.Atoi(ids[0])
+	}
+	return 0, fmt.Errorf("model code %q is ambiguous (variants %s), give an ID", key, strings.Join(ids, ", "))
+}
+
+// medianBVVariant is the variant of median BV among those of minTons to
+// maxTons introduced in era, or 0 if there are none.
+func medianBVVariant(ctx context.Context, pool *pgxpool.Pool, era string, minTons, maxTons int) (int, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT v.id FROM variants v
+		JOIN chassis c ON c.id = v.chassis_id
+		JOIN variant_stats vs ON vs.variant_id = v.id
+		JOIN eras e ON e.name = $1
+		WHERE v.mul_id IS NOT NULL AND v.mul_id > 0 AND v.battle_value > 0
+		  AND v.intro_year BETWEEN e.start_year AND e.end_year
+		  AND COALESCE(vs.tonnage, c.tonnage) BETWEEN $2 AND $3
+		ORDER BY v.battle_value, v.id`, era, minTons, maxTons)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[len(ids)/2], nil
+}
+
+// buildPanel builds the baseline mechs of specs.
+func buildPanel(specs []baselineSpec, mtfs map[string]*ingestion.MTFData) []sim.Baseline {
+	panel := make([]sim.Baseline, len(specs))
+	for i, s := range specs {
+		m := sim.BuildHBK4P()
+		if s.Variant != nil {
+			m = sim.BuildMechState(s.Variant, sim.LookupMTF(mtfs, s.Variant))
+		}
+		panel[i] = sim.Baseline{ID: s.ID, Name: s.Name, Mech: m, Weight: s.Weight, Turns: s.Turns}
+	}
+	return panel
+}
+
+// describePanel is the panel as recorded in the run manifest.
+func describePanel(specs []baselineSpec) string {
+	parts := make([]string, len(specs))
+	for i, s := range specs {
+		parts[i] = s.Name
+		if s.Variant != nil {
+			parts[i] += " #" + strconv.Itoa(s.ID)
+		}
+		if s.Weight != 1 {
+			parts[i] += fmt.Sprintf(" ×%g", s.Weight)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/sim"
+)
+
+// ─── Board pool ─────────────────────────────────────────────────────────────
+//
+// Duels are fought on MegaMek's standard boards from SLIC_BOARD_DIR. Without
+// a checkout, or with -gen-boards, the pool is generated instead from the
+// -board-profiles terrain mixes, the same boards for the same flags, so a
+// run can be repeated and workers agree with their coordinator.
+
+// defaultGenBoards is the size of the generated pool that stands in for a
+// missing board directory.
+const defaultGenBoards = 16
+
+// boardPool is what the -gen-boards, -board-profiles and -board-seed flags
+// ask for.
+type boardPool struct {
+	dir      string
+	generate int
+	profiles string
+	seed     uint64
+}
+
+// load returns the pool and its hash for the run manifest: that of the
+// directory's files, or of the generated boards as written out.
+func (bp boardPool) load() ([]*sim.Board, string, error) {
+	if bp.generate == 0 {
+		boards, err := sim.LoadBoardPool(bp.dir)
+		if err == nil && len(boards) >= 2 {
+			return boards, hashFiles(bp.dir, func(path string) bool {
+				return strings.HasSuffix(path, ".board") && !strings.Contains(path, "/unofficial/")
+			}), nil
+		}
+		log.Printf("No board pool in %s (%d boards, err %v), generating %d boards instead", bp.dir, len(boards), err, defaultGenBoards)
+		bp.generate = defaultGenBoards
+	}
+	if bp.generate < 2 {
+		return nil, "", fmt.Errorf("need at least 2 boards")
+	}
+
+	keys := sim.BoardProfileKeys()
+	if bp.profiles != "" && bp.profiles != "all" {
+		keys = strings.Split(bp.profiles, ",")
+	}
+	profiles := make([]sim.BoardProfile, len(keys))
+	for i, key := range keys {
+		p, err := sim.ParseBoardProfile(key)
+		if err != nil {
+			return nil, "", err
+		}
+		profiles[i] = p
+	}
+	boards := sim.GenerateBoardPool(profiles, bp.generate, bp.seed)
+	h := sha256.New()
+	for _, b := range boards {
+		if err := b.WriteBoard(h); err != nil {
+			return nil, "", err
+		}
+	}
+	log.Printf("Generated %d boards (%s, seed %d)", len(boards), strings.Join(keys, ", "), bp.seed)
+	return boards, hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// writeBoards saves boards to dir as MegaMek .board files.
+func writeBoards(dir string, boards []*sim.Board) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i, b := range boards {
+		if err := b.SaveBoard(filepath.Join(dir, fmt.Sprintf("slic-%02d.board", i+1))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
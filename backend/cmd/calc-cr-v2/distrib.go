@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
+)
+
+// ─── Distributed workers ────────────────────────────────────────────────────
+//
+// With -serve, the batch run also hands out variants over HTTP to worker
+// processes started with -worker. A job is one whole variant: its ratings
+// come from its own RNG stream, so a remote worker returns exactly what a
+// local one would, and adaptive sampling needs all of a variant's sims in
+// one place anyway. Workers need no database: they get the run settings and
+// the variants from the coordinator, load the boards and MTF files from
+// their own copies, and refuse to start if the manifest hashes disagree.
+//
+// The coordinator listens on localhost unless -serve names a host, and every
+// request must carry the run's token, which workers take from
+// SLIC_WORKER_TOKEN.
+//
+//	GET  /run                      the runSpec
+//	POST /job                      the next jobSpec; 204 once there is none
+//	POST /result?index=N&lease=L   a simResult for job N, leased as L
+
+// tokenEnv holds the shared secret workers present to the coordinator.
+const tokenEnv = "SLIC_WORKER_TOKEN"
+
+// jobQueue hands out variant indexes to local and remote workers. Every job
+// handed out is leased, and only a result under the job's current lease is
+// taken. A remote worker's lease runs out: if its result isn't back by then,
+// the job goes back on the queue for someone else. Results go to results,
+// which is closed once every job is done, or once the run is interrupted
+// and no worker still holds a live lease.
+type jobQueue struct {
+	ctx     context.Context // the run; done when interrupted
+	lease   time.Duration
+	results chan simResult
+	n       int
+
+	mu        sync.Mutex
+	pending   []int
+	leases    map[int]jobLease
+	lastLease int
+	done      map[int]bool
+	left      int
+	sending   int // results being sent, which results can't close under
+	closed    bool
+}
+
+// jobLease is a job's current holder.
+type jobLease struct {
+	id    int
+	until time.Time // zero for local workers: never expires
+}
+
+func newJobQueue(ctx context.Context, n int, lease time.Duration) *jobQueue {
+	q := &jobQueue{
+		ctx:     ctx,
+		lease:   lease,
+		results: make(chan simResult, 100),
+		n:       n,
+		leases:  map[int]jobLease{},
+		done:    map[int]bool{},
+		left:    n,
+	}
+	for i := 0; i < n; i++ {
+		q.pending = append(q.pending, i)
+	}
+	q.mu.Lock()
+	q.check()
+	q.mu.Unlock()
+
+	// After an interrupt nothing else calls check, so watch for the
+	// last remote lease to come back or run out
+	go func() {
+		<-ctx.Done()
+		for {
+			q.mu.Lock()
+			q.check()
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			time.Sleep(time.Second)
+		}
+	}()
+	return q
+}
+
+// check requeues expired leases (drops them once interrupted) and closes
+// results when the run is over. q.mu must be held.
+func (q *jobQueue) check() {
+	if q.closed {
+		return
+	}
+	now := time.Now()
+	for idx, l := range q.leases {
+		if l.until.IsZero() || now.Before(l.until) {
+			continue
+		}
+		delete(q.leases, idx)
+		if q.ctx.Err() == nil {
+			q.pending = append(q.pending, idx)
+			log.Printf("Lease on job %d ran out, requeued", idx)
+		}
+	}
+	if q.sending > 0 {
+		return
+	}
+	if q.left == 0 || q.ctx.Err() != nil && len(q.leases) == 0 {
+		close(q.results)
+		q.closed = true
+	}
+}
+
+// next hands out a job and its lease, waiting while every job left is
+// leased. False once the run is over or interrupted, or ctx is done.
+func (q *jobQueue) next(ctx context.Context, remote bool) (idx, lease int, ok bool) {
+	for {
+		q.mu.Lock()
+		q.check()
+		if q.closed || q.ctx.Err() != nil {
+			q.mu.Unlock()
+			return 0, 0, false
+		}
+		if len(q.pending) > 0 {
+			idx := q.pending[0]
+			q.pending = q.pending[1:]
+			q.lastLease++
+			l := jobLease{id: q.lastLease}
+			if remote {
+				l.until = time.Now().Add(q.lease)
+			}
+			q.leases[idx] = l
+			q.mu.Unlock()
+			return idx, l.id, true
+		}
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return 0, 0, false
+		case <-q.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// complete records the result of job idx under lease. False if that isn't
+// the job's current lease (a late result from an expired one, or a job that
+// was never handed out) or the run is over. The result is sent without
+// holding q.mu, so a slow consumer only holds up the caller.
+func (q *jobQueue) complete(idx, lease int, r simResult) bool {
+	q.mu.Lock()
+	if l, ok := q.leases[idx]; q.closed || !ok || l.id != lease {
+		q.mu.Unlock()
+		return false
+	}
+	q.done[idx] = true
+	q.left--
+	delete(q.leases, idx)
+	q.sending++
+	q.mu.Unlock()
+
+	q.results <- r
+
+	q.mu.Lock()
+	q.sending--
+	q.check()
+	q.mu.Unlock()
+	return true
+}
+
+// runSpec is what a remote worker needs to rate variants exactly as the
+// coordinator would.
+type runSpec struct {
+	Token     string `json:"-"` // never sent; workers bring their own
+	Manifest  runManifest
+	Baselines []baselineSpec
+}
+
+type jobSpec struct {
+	Index   int
+	Lease   int
+	Variant sim.Variant
+}
+
+// newToken returns a random token for a run whose coordinator wasn't given
+// one.
+func newToken() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// serveAddr is where -serve listens: addr, on localhost if it names no
+// host. Workers on other machines need it to name one, e.g. '0.0.0.0:8377'.
+func serveAddr(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	if _, err := strconv.Atoi(addr); err == nil {
+		return net.JoinHostPort("127.0.0.1", addr)
+	}
+	return addr
+}
+
+// authorized wraps h to refuse requests without the run's token.
+func authorized(token string, h http.HandlerFunc) http.HandlerFunc {
+	want := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// serveJobs serves q's jobs to remote workers on addr, until the returned
+// server is shut down.
+func serveJobs(addr string, spec runSpec, variants []sim.Variant, q *jobQueue) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /run", authorized(spec.Token, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(spec)
+	}))
+	mux.HandleFunc("POST /job", authorized(spec.Token, func(w http.ResponseWriter, r *http.Request) {
+		idx, lease, ok := q.next(r.Context(), true)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(jobSpec{idx, lease, variants[idx]})
+	}))
+	mux.HandleFunc("POST /result", authorized(spec.Token, func(w http.ResponseWriter, r *http.Request) {
+		idx, err := strconv.Atoi(r.URL.Query().Get("index"))
+		if err != nil || idx < 0 || idx >= len(variants) {
+			http.Error(w, "bad index", http.StatusBadRequest)
+			return
+		}
+		lease, err := strconv.Atoi(r.URL.Query().Get("lease"))
+		if err != nil {
+			http.Error(w, "bad lease", http.StatusBadRequest)
+			return
+		}
+		var res simResult
+		if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if res.ID != variants[idx].ID {
+			http.Error(w, "result is for another variant", http.StatusBadRequest)
+			return
+		}
+		if !q.complete(idx, lease, res) {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+
+	ln, err := net.Listen("tcp", serveAddr(addr))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Serving jobs to -worker processes on %s", ln.Addr())
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
+			log.Printf("-serve: %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+// runWorker rates variants for the coordinator at url with n goroutines
+// until it runs out of jobs or ctx is done. local is a manifest of this
+// machine's code and data, which must match the run's.
+func runWorker(ctx context.Context, url string, n int, local *runManifest, boards []*sim.Board, mtfs map[string]*ingestion.MTFData) error {
+	url = strings.TrimSuffix(url, "/")
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set; the coordinator logs it when it starts", tokenEnv)
+	}
+	var spec runSpec
+	if err := getJSON(url+"/run", token, &spec); err != nil {
+		return fmt.Errorf("run spec: %w", err)
+	}
+	if changes := manifestChanges(&spec.Manifest, local, "seed", "args", "ci", "baselines", "conditions", "strategies", "terrain", "scenarios"); len(changes) > 0 {
+		return fmt.Errorf("this worker differs from run %d: %s", spec.Manifest.ID, strings.Join(changes, "; "))
+	}
+	conditions, err := parseConditionKeys(spec.Manifest.Conditions)
+	if err != nil {
+		return err
+	}
+	strategies, err := parseStrategyKeys(spec.Manifest.Strategies)
+	if err != nil {
+		return err
+	}
+	terrains, err := parseTerrainKeys(spec.Manifest.Terrains, boards)
+	if err != nil {
+		return err
+	}
+	scenarios, err := parseScenarioKeys(spec.Manifest.Scenarios)
+	if err != nil {
+		return err
+	}
+	rt := &rater{
+		seed:   spec.Manifest.Seed,
+		boards: boards,
+		mtfs:   mtfs,
+		panel:  buildPanel(spec.Baselines, mtfs),
+		cfg: sim.RatingConfig{
+			BaselineRatio:   1.0,
+			TargetHalfWidth: spec.Manifest.CIWidth,
+			MaxSims:         spec.Manifest.CIMaxSims,
+			Budget:          spec.Manifest.CIBudget,
+		},
+		conditions: conditions,
+		strategies: strategies,
+		terrains:   terrains,
+		scenarios:  scenarios,
+	}
+	log.Printf("Working on run %d for %s with %d workers", spec.Manifest.ID, url, n)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rate := rt.worker()
+			for ctx.Err() == nil {
+				var job jobSpec
+				resp, err := post(url+"/job", token, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if resp.StatusCode == http.StatusNoContent {
+					resp.Body.Close()
+					return
+				}
+				err = json.NewDecoder(resp.Body).Decode(&job)
+				resp.Body.Close()
+				if err != nil {
+					errs <- fmt.Errorf("job: %w", err)
+					return
+				}
+
+				r := rate(&job.Variant)
+				body, _ := json.Marshal(r)
+				resp, err = post(url+"/result?index="+strconv.Itoa(job.Index)+"&lease="+strconv.Itoa(job.Lease), token, bytes.NewReader(body))
+				if err != nil {
+					errs <- err
+					return
+				}
+				resp.Body.Close()
+				switch resp.StatusCode {
+				case http.StatusOK:
+					log.Printf("  %s", r)
+				case http.StatusConflict:
+					log.Printf("  %s (already rated elsewhere)", r.Name)
+				default:
+					errs <- fmt.Errorf("result for %s: %s", r.Name, resp.Status)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// post sends body to the coordinator with the run's token.
+func post(url, token string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
+func getJSON(url, token string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
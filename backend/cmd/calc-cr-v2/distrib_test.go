@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// drained reports whether q.results is closed, after reading anything
+// still in it.
+func drained(q *jobQueue) bool {
+	for {
+		select {
+		case _, ok := <-q.results:
+			if !ok {
+				return true
+			}
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}
+}
+
+func TestJobQueueLeases(t *testing.T) {
+	// A remote lease that runs out goes back on the queue, and the late
+	// result under it is refused
+	q := newJobQueue(context.Background(), 1, 20*time.Millisecond)
+	idx, first, ok := q.next(context.Background(), true)
+	if !ok || idx != 0 {
+		t.Fatalf("next = %d, %v", idx, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	idx, second, ok := q.next(context.Background(), true)
+	if !ok || idx != 0 || second == first {
+		t.Fatalf("after the lease ran out, next = %d (lease %d, was %d), %v", idx, second, first, ok)
+	}
+	if q.complete(0, first, simResult{ID: 1}) {
+		t.Error("took a result under an expired lease")
+	}
+	if q.complete(0, 99, simResult{ID: 1}) {
+		t.Error("took a result under a lease never handed out")
+	}
+	if !q.complete(0, second, simResult{ID: 1}) {
+		t.Error("refused the result under the current lease")
+	}
+	if q.complete(0, second, simResult{ID: 1}) {
+		t.Error("took a job's result twice")
+	}
+	if !drained(q) {
+		t.Error("results not closed once every job was done")
+	}
+
+	// A local lease never runs out, so nobody else gets the job
+	q = newJobQueue(context.Background(), 1, time.Millisecond)
+	_, local, _ := q.next(context.Background(), false)
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if idx, _, ok := q.next(ctx, true); ok {
+		t.Errorf("job %d handed out again under a local lease", idx)
+	}
+	if !q.complete(0, local, simResult{ID: 1}) {
+		t.Error("refused the result under a local lease")
+	}
+}
+
+func TestJobQueueInterrupt(t *testing.T) {
+	// Once interrupted, no more jobs go out, but results stays open until
+	// the remote lease still out comes back
+	run, interrupt := context.WithCancel(context.Background())
+	q := newJobQueue(run, 3, time.Hour)
+	_, lease, _ := q.next(context.Background(), true)
+	interrupt()
+	if idx, _, ok := q.next(context.Background(), true); ok {
+		t.Errorf("job %d handed out after the interrupt", idx)
+	}
+	if drained(q) {
+		t.Fatal("results closed with a lease still out")
+	}
+	if !q.complete(0, lease, simResult{ID: 1}) {
+		t.Error("refused the result of a lease held over the interrupt")
+	}
+	if !drained(q) {
+		t.Error("results not closed once the last lease came back")
+	}
+}
+
+func TestJobQueueSlowConsumer(t *testing.T) {
+	// A result waiting on a full channel doesn't hold up handing out jobs
+	n := cap(newJobQueue(context.Background(), 0, time.Hour).results) + 2
+	q := newJobQueue(context.Background(), n, time.Hour)
+	for i := 0; i < n-1; i++ {
+		idx, lease, _ := q.next(context.Background(), false)
+		go q.complete(idx, lease, simResult{ID: idx})
+	}
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	idx, lease, ok := q.next(ctx, false)
+	if !ok {
+		t.Fatal("next blocked behind a result waiting to be sent")
+	}
+	go q.complete(idx, lease, simResult{ID: idx})
+	got := 0
+	for range q.results {
+		got++
+	}
+	if got != n {
+		t.Errorf("got %d results, want %d", got, n)
+	}
+}
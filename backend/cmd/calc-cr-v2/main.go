@@ -5,13 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"bytes"
-	"compress/gzip"
-	"database/sql"
 	"math"
+	"database/sql"
 	"math/rand/v2"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime"
 	"runtime/pprof"
 	"sort"
@@ -19,567 +18,15 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/JustinWhittecar/slic/internal/db"
-	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
+	"github.com/JustinWhittecar/slic/internal/simdb"
 	_ "modernc.org/sqlite"
 )
 
-// ─── Constants ──────────────────────────────────────────────────────────────
-
-const (
-	numSimsPerBoard = 10
-	numBoardPairs   = 20
-	maxTurns        = 200
-	gunnerySkill    = 4
-	pilotingSkill   = 5
-	kFactor         = 3.5
-)
-
-// ─── Simulation core ────────────────────────────────────────────────────────
-
-// simulateCombat2D runs one sim on a 2D hex board.
-// Attacker tries to destroy defender. Returns turns until defender destroyed/withdrawn.
-func simulateCombat2D(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) int {
-	attacker := cloneMech(attackerTemplate)
-	defender := cloneMech(defenderTemplate)
-
-	// Deploy: attacker rows 1-3, defender rows 15-17
-	attacker.Pos = HexCoord{Col: board.Width/2 + 1, Row: 2}
-	attacker.Facing = 3 // face south
-	defender.Pos = HexCoord{Col: board.Width/2 + 1, Row: board.Height - 1}
-	defender.Facing = 0 // face north
-
-	for turn := 1; turn <= maxTurns; turn++ {
-		if attacker.isDestroyed() || defender.isDestroyed() {
-			return turn - 1
-		}
-		if defender.isForcedWithdrawal() {
-			return turn
-		}
-		// Combat ineffective: gyro destroyed (permanently prone, 0 MP),
-		// or both legs destroyed (immobile + prone), or all weapons destroyed.
-		// Not "destroyed" per BMM but effectively out of the fight for CR purposes.
-		if defender.GyroHits >= 2 || (defender.IS[LocLL] <= 0 && defender.IS[LocRL] <= 0) {
-			return turn
-		}
-		defAllWeaponsGone := true
-		for i := range defender.Weapons {
-			if !defender.Weapons[i].Destroyed {
-				defAllWeaponsGone = false
-				break
-			}
-		}
-		if defAllWeaponsGone {
-			return turn
-		}
-
-		// Unjam RAC weapons (RAC clears jam after 1 turn)
-		for i := range attacker.Weapons {
-			w := &attacker.Weapons[i]
-			if w.Jammed && w.Category == catRotaryAC {
-				w.Jammed = false
-			}
-		}
-		for i := range defender.Weapons {
-			w := &defender.Weapons[i]
-			if w.Jammed && w.Category == catRotaryAC {
-				w.Jammed = false
-			}
-		}
-
-		// Handle shutdown — attacker
-		if attacker.IsShutdown {
-			// Restart requires a roll against shutdown avoidance TN (BMM p.52)
-			tn := heatShutdownTN(attacker.Heat)
-			if tn >= 13 || roll2d6(rng) < tn {
-				// Failed restart — stay shutdown, dissipate heat, skip turn
-				attacker.Heat += attacker.HeatPenalty
-				attacker.HeatPenalty = 0
-				attacker.Heat -= attacker.Dissipation
-				if attacker.Heat < 0 {
-					attacker.Heat = 0
-				}
-				// Still process defender heat
-				defender.Heat += defender.HeatPenalty
-				defender.HeatPenalty = 0
-				defender.Heat -= defender.Dissipation
-				if defender.Heat < 0 {
-					defender.Heat = 0
-				}
-				continue
-			}
-			// Successful restart
-			attacker.IsShutdown = false
-			// Involuntary shutdown PSR: piloting + 3 modifier (BMM p.52)
-			if !attacker.Prone {
-				psrTN := pilotingSkill + 3
-				if roll2d6(rng) < psrTN {
-					attacker.applyFall(rng)
-				}
-			}
-			if attacker.isDestroyed() {
-				return maxTurns // attacker died
-			}
-		}
-
-		// Handle shutdown — defender
-		if defender.IsShutdown {
-			tn := heatShutdownTN(defender.Heat)
-			if tn >= 13 || roll2d6(rng) < tn {
-				// Failed restart — stay shutdown, dissipate heat, skip turn for defender
-				defender.Heat += defender.HeatPenalty
-				defender.HeatPenalty = 0
-				defender.Heat -= defender.Dissipation
-				if defender.Heat < 0 {
-					defender.Heat = 0
-				}
-				attacker.Heat += attacker.HeatPenalty
-				attacker.HeatPenalty = 0
-				attacker.Heat -= attacker.Dissipation
-				if attacker.Heat < 0 {
-					attacker.Heat = 0
-				}
-				continue
-			}
-			defender.IsShutdown = false
-			if !defender.Prone {
-				psrTN := pilotingSkill + 3
-				if roll2d6(rng) < psrTN {
-					defender.applyFall(rng)
-				}
-			}
-			if defender.isDestroyed() {
-				return turn
-			}
-		}
-
-		// Stand from prone
-		if attacker.Prone {
-			attacker.Heat += 1
-			if attacker.rollPSRForStanding(rng) {
-				attacker.Prone = false
-			} else {
-				attacker.applyFall(rng)
-				if attacker.isDestroyed() {
-					return maxTurns
-				}
-			}
-		}
-		if defender.Prone {
-			defender.Heat += 1
-			if defender.rollPSRForStanding(rng) {
-				defender.Prone = false
-			} else {
-				defender.applyFall(rng)
-				if defender.isDestroyed() {
-					return turn
-				}
-			}
-		}
-
-		// Initiative
-		atkInit := rng.IntN(6) + 1
-		defInit := rng.IntN(6) + 1
-		atkMovesFirst := atkInit < defInit
-		if atkInit == defInit {
-			atkMovesFirst = rng.IntN(2) == 0
-		}
-
-		// Movement
-		atkWalk := 0
-		atkRun := 0
-		if !attacker.Prone {
-			atkWalk = attacker.effectiveWalkMP()
-			atkRun = attacker.effectiveRunMP()
-		}
-		defWalk := 0
-		defRun := 0
-		if !defender.Prone {
-			defWalk = defender.effectiveWalkMP()
-			defRun = defender.effectiveRunMP()
-		}
-
-		// Convert MechState to MechState2-like for tactics
-		atkM2 := &MechState2{
-			Pos: attacker.Pos, Facing: attacker.Facing,
-			WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP,
-			Tonnage: attacker.Tonnage, GunnerySkill: gunnerySkill,
-			Heat: attacker.Heat, OptimalRange: attacker.OptimalRange,
-		}
-		defM2 := &MechState2{
-			Pos: defender.Pos, Facing: defender.Facing,
-			WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP,
-			Tonnage: defender.Tonnage, GunnerySkill: gunnerySkill,
-			Heat: defender.Heat, OptimalRange: defender.OptimalRange,
-		}
-		// Copy weapons for damage estimation
-		for _, w := range attacker.Weapons {
-			if !w.Destroyed && !w.Jammed {
-				atkM2.Weapons = append(atkM2.Weapons, SimWeapon2{
-					Name: w.Name, Damage: w.Damage, Heat: w.Heat,
-					MinRange: w.MinRange, ShortRange: w.ShortRange,
-					MedRange: w.MedRange, LongRange: w.LongRange,
-					Location: w.Location, ToHitMod: w.ToHitMod,
-				})
-			}
-		}
-		for _, w := range defender.Weapons {
-			if !w.Destroyed && !w.Jammed {
-				defM2.Weapons = append(defM2.Weapons, SimWeapon2{
-					Name: w.Name, Damage: w.Damage, Heat: w.Heat,
-					MinRange: w.MinRange, ShortRange: w.ShortRange,
-					MedRange: w.MedRange, LongRange: w.LongRange,
-					Location: w.Location, ToHitMod: w.ToHitMod,
-				})
-			}
-		}
-
-		defOptions := collectAllMoveOptions(board, defM2)
-		atkOptions := collectAllMoveOptions(board, atkM2)
-
-		var atkChoice, defChoice ReachableHex
-
-		if atkMovesFirst {
-			// Attacker moves first (blind), defender sees
-			atkChoice = ChooseMovement(board, atkM2, defM2,
-				false, defM2.Pos, defM2.Facing, defOptions, rng, atkOptions)
-			defChoice = ChooseMovement(board, defM2, atkM2,
-				true, atkChoice.Coord, atkChoice.Facing, atkOptions, rng, defOptions)
-		} else {
-			// Defender moves first (blind), attacker sees
-			defChoice = ChooseMovement(board, defM2, atkM2,
-				false, atkM2.Pos, atkM2.Facing, atkOptions, rng, defOptions)
-			atkChoice = ChooseMovement(board, atkM2, defM2,
-				true, defChoice.Coord, defChoice.Facing, defOptions, rng, atkOptions)
-		}
-
-		// Apply movement
-		attacker.Pos = atkChoice.Coord
-		attacker.Facing = atkChoice.Facing
-		attacker.LastMoveMode = atkChoice.Mode
-		attacker.LastHexMoved = atkChoice.HexesMoved
-		attacker.Heat += atkChoice.MoveHeat
-
-		defender.Pos = defChoice.Coord
-		defender.Facing = defChoice.Facing
-		defender.LastMoveMode = defChoice.Mode
-		defender.LastHexMoved = defChoice.HexesMoved
-		defender.Heat += defChoice.MoveHeat
-
-		// Torso twist
-		attacker.TorsoTwist = BestTorsoTwist(attacker.Pos, attacker.Facing, defender.Pos)
-		defender.TorsoTwist = BestTorsoTwist(defender.Pos, defender.Facing, attacker.Pos)
-
-		// Reset AMS
-		defender.AMSUsedThisTurn = false
-
-		// LOS check
-		dist := HexDistance(attacker.Pos, defender.Pos)
-		los := CheckLOS(board, attacker.Pos, defender.Pos)
-
-		if los.CanSee && dist > 0 {
-			// Determine arc for hit table
-			defEffFacing := ((defender.Facing + defender.TorsoTwist) % 6 + 6) % 6
-			arcToDefender := DetermineArc(defender.Pos, defEffFacing, attacker.Pos)
-			isRear := arcToDefender == ArcRear
-
-			// Compute target number
-			defTMM := tmmFromHexesMoved(defChoice.HexesMoved, defChoice.Mode)
-			heatThisMod := heatToHitMod(attacker.Heat)
-			// BMM p.49: 2+ sensor hits = weapon fire impossible
-			if attacker.SensorHits >= 2 {
-				continue
-			}
-			baseTarget := gunnerySkill + attacker.SensorHits*2 + heatThisMod
-
-			// Attacker movement modifier
-			switch atkChoice.Mode {
-			case ModeWalk:
-				baseTarget += 1
-			case ModeRun:
-				baseTarget += 2
-			case ModeJump:
-				baseTarget += 3
-			}
-
-			// Target movement modifier
-			baseTarget += defTMM
-
-			// Terrain modifiers
-			baseTarget += los.WoodsMod
-			baseTarget += los.TargetCover
-			baseTarget += los.ElevationMod
-
-			// Prone modifiers
-			if attacker.Prone {
-				baseTarget += 2
-			}
-			if defender.Prone {
-				if dist <= 1 {
-					baseTarget -= 2 // adjacent: easier to hit (BMM p.28)
-				} else {
-					baseTarget += 1 // non-adjacent: harder to hit (BMM p.28)
-				}
-			}
-
-			// Select and fire weapons
-			firingWeapons, weaponHeatTotal := selectWeaponsEV(attacker, board, defender, dist, baseTarget)
-			attacker.Heat += weaponHeatTotal
-
-			totalDmgDealt := 0
-			for _, wi := range firingWeapons {
-				w := &attacker.Weapons[wi]
-				if w.AmmoKey != "" {
-					if attacker.Ammo[w.AmmoKey] <= 0 {
-						continue
-					}
-					attacker.Ammo[w.AmmoKey]--
-				}
-
-				target := baseTarget + w.ToHitMod + attacker.ArmActuatorHit[w.Location]
-				// Artemis V: -1 to-hit in addition to cluster bonus (BMM p.110)
-				if attacker.HasArtemisV && (w.Category == catLRM || w.Category == catSRM || w.Category == catMML || w.Category == catATM) {
-					target -= 1
-				}
-				rm := rangeModifier(w, dist)
-				if rm < 0 {
-					continue
-				}
-				target += rm
-				if w.MinRange > 0 && dist <= w.MinRange {
-					target += w.MinRange - dist + 1
-				}
-
-				dmg := resolveWeaponFire2D(w, target, isRear, attacker, defender, rng)
-				totalDmgDealt += dmg
-
-				if defender.isDestroyed() {
-					return turn
-				}
-			}
-
-			// PSR for 20+ damage
-			if totalDmgDealt >= 20 && !defender.Prone {
-				if !defender.rollPSR(1, rng) {
-					defender.applyFall(rng)
-					if defender.isDestroyed() {
-						return turn
-					}
-				}
-			}
-
-			// PSR from crits
-			if defender.NeedsPSRFromCrit && !defender.isDestroyed() {
-				defender.NeedsPSRFromCrit = false
-				if !defender.rollPSR(0, rng) {
-					defender.applyFall(rng)
-					if defender.isDestroyed() {
-						return turn
-					}
-				}
-			}
-		}
-
-		// Physical attacks
-		if dist == 1 {
-			resolvePhysical(attacker, defender, rng)
-			if defender.isDestroyed() {
-				return turn
-			}
-		}
-
-		// End of turn: engine crit heat + heat dissipation
-		// Engine hits do not produce heat if the 'Mech is shut down (BMM p.47)
-		if !attacker.IsShutdown {
-			attacker.Heat += attacker.EngineHits * 5
-		}
-		// Outside heat sources capped at 15 per turn (BMM p.52)
-		if attacker.HeatPenalty > 15 {
-			attacker.HeatPenalty = 15
-		}
-		attacker.Heat += attacker.HeatPenalty // plasma weapon heat from enemy
-		attacker.HeatPenalty = 0
-		attacker.Heat -= attacker.Dissipation
-		if attacker.Heat < 0 {
-			attacker.Heat = 0
-		}
-		if !defender.IsShutdown {
-			defender.Heat += defender.EngineHits * 5
-		}
-		if defender.HeatPenalty > 15 {
-			defender.HeatPenalty = 15
-		}
-		defender.Heat += defender.HeatPenalty // plasma weapon heat from enemy
-		defender.HeatPenalty = 0
-		defender.Heat -= defender.Dissipation
-		if defender.Heat < 0 {
-			defender.Heat = 0
-		}
-
-		// Heat shutdown/ammo explosion for attacker
-		shutdownP := heatShutdownProb(attacker.Heat)
-		if shutdownP >= 1.0 || (shutdownP > 0 && rng.Float64() < shutdownP) {
-			attacker.IsShutdown = true
-		}
-
-		ammoExpP := heatAmmoExpProb(attacker.Heat)
-		if ammoExpP > 0 && rng.Float64() < ammoExpP {
-			type ammoBin struct {
-				key string
-				loc int
-			}
-			var bins []ammoBin
-			for loc := 0; loc < NumLoc; loc++ {
-				for _, slot := range attacker.Slots[loc] {
-					sLower := strings.ToLower(slot)
-					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
-						key := parseAmmoSlotKey(slot)
-						if attacker.Ammo[key] > 0 {
-							bins = append(bins, ammoBin{key, loc})
-						}
-					}
-				}
-			}
-			if len(bins) > 0 {
-				bin := bins[rng.IntN(len(bins))]
-				attacker.ammoExplosion(bin.loc, bin.key, rng)
-			}
-		}
-
-		// Heat shutdown/ammo explosion for defender (BMM p.52)
-		shutdownPDef := heatShutdownProb(defender.Heat)
-		if shutdownPDef >= 1.0 || (shutdownPDef > 0 && rng.Float64() < shutdownPDef) {
-			defender.IsShutdown = true
-		}
-
-		ammoExpPDef := heatAmmoExpProb(defender.Heat)
-		if ammoExpPDef > 0 && rng.Float64() < ammoExpPDef {
-			type ammoBin struct {
-				key string
-				loc int
-			}
-			var bins []ammoBin
-			for loc := 0; loc < NumLoc; loc++ {
-				for _, slot := range defender.Slots[loc] {
-					sLower := strings.ToLower(slot)
-					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
-						key := parseAmmoSlotKey(slot)
-						if defender.Ammo[key] > 0 {
-							bins = append(bins, ammoBin{key, loc})
-						}
-					}
-				}
-			}
-			if len(bins) > 0 {
-				bin := bins[rng.IntN(len(bins))]
-				defender.ammoExplosion(bin.loc, bin.key, rng)
-				if defender.isDestroyed() {
-					return turn
-				}
-			}
-		}
-	}
-
-	return maxTurns
-}
-
-// (collectMoveOptions moved to tactics.go as collectAllMoveOptions)
-
-// ─── MechState2 — lightweight state for tactical AI ─────────────────────────
-
-type MechState2 struct {
-	Name          string
-	Pos           HexCoord
-	Facing        int
-	WalkMP        int
-	RunMP         int
-	JumpMP        int
-	Tonnage       int
-	GunnerySkill  int
-	PilotingSkill int
-	Weapons       []SimWeapon2
-	Prone         bool
-	Heat          int
-	OptimalRange  int
-}
-
-type SimWeapon2 struct {
-	Name       string
-	Damage     int
-	Heat       int
-	MinRange   int
-	ShortRange int
-	MedRange   int
-	LongRange  int
-	Location   int
-	ToHitMod   int
-	Destroyed  bool
-}
-
-// ─── Batch sim ──────────────────────────────────────────────────────────────
-
-// PrecomputedBoards holds pre-combined board pairs to avoid re-combining per variant.
-type PrecomputedBoards struct {
-	Boards []*Board
-}
-
-func precomputeBoardPairs(boards []*Board, nPairs int, rng *rand.Rand) *PrecomputedBoards {
-	pb := &PrecomputedBoards{Boards: make([]*Board, nPairs)}
-	for i := 0; i < nPairs; i++ {
-		b1 := boards[rng.IntN(len(boards))]
-		b2 := boards[rng.IntN(len(boards))]
-		pb.Boards[i] = CombineBoards(b1, b2)
-	}
-	return pb
-}
-
-func runSimsBatch2D(boards []*Board, attackerTemplate, defenderTemplate *MechState, nBoardPairs int, nSimsPerBoard int, rng *rand.Rand) float64 {
-	var results []int
-
-	for bp := 0; bp < nBoardPairs; bp++ {
-		b1 := boards[rng.IntN(len(boards))]
-		b2 := boards[rng.IntN(len(boards))]
-		combined := CombineBoards(b1, b2)
-
-		for s := 0; s < nSimsPerBoard; s++ {
-			turns := simulateCombat2D(combined, attackerTemplate, defenderTemplate, rng)
-			results = append(results, turns)
-		}
-	}
-
-	sort.Ints(results)
-	n := len(results)
-	if n == 0 {
-		return float64(maxTurns)
-	}
-	if n%2 == 0 {
-		return float64(results[n/2-1]+results[n/2]) / 2.0
-	}
-	return float64(results[n/2])
-}
-
-func runSimsBatch2DPre(preBoards *PrecomputedBoards, attackerTemplate, defenderTemplate *MechState, nSimsPerBoard int, rng *rand.Rand) float64 {
-	var results []int
-
-	for _, combined := range preBoards.Boards {
-		for s := 0; s < nSimsPerBoard; s++ {
-			turns := simulateCombat2D(combined, attackerTemplate, defenderTemplate, rng)
-			results = append(results, turns)
-		}
-	}
-
-	sort.Ints(results)
-	n := len(results)
-	if n == 0 {
-		return float64(maxTurns)
-	}
-	if n%2 == 0 {
-		return float64(results[n/2-1]+results[n/2]) / 2.0
-	}
-	return float64(results[n/2])
-}
-
 // ─── Main ───────────────────────────────────────────────────────────────────
 
 func main() {
@@ -592,8 +39,78 @@ func main() {
 	genReplays := flag.Bool("gen-replays", false, "Generate replay for every variant and store in SQLite")
 	genReplaysDB := flag.String("gen-replays-db", "/Users/puckopenclaw/projects/slic/slic.db", "SQLite DB path for storing replays")
 	genReplaysLimit := flag.Int("gen-replays-limit", 0, "Limit number of variants to process (0=all)")
+	battleMode := flag.String("battle", "", "Run force battles: 'HBK-4P,AS7-D vs MAD-3R,TDR-5S'")
+	battleSims := flag.Int("battle-sims", 100, "Number of battles for -battle")
+	scenarioFlag := flag.String("scenario", "", "Scenario for -battle, force A attacking ("+strings.Join(sim.ScenarioKeys(), ", ")+"); default a plain battle")
+	conditionsFlag := flag.String("conditions", "", "Comma-separated condition presets to also rate every mech under, or 'all'")
+	aiFlag := flag.String("ai", "", "AI strategy per side for -replay and -battle: 'attacker' or 'attacker,defender' ("+strings.Join(sim.StrategyKeys(), ", ")+")")
+	strategiesFlag := flag.String("strategies", "", "Comma-separated AI strategies to also rate every mech playing, or 'all'")
+	terrainFlag := flag.String("terrain", "", "Comma-separated terrain classes to also rate every mech on, or 'all' ("+strings.Join(sim.TerrainClassKeys(), ", ")+")")
+	scenariosFlag := flag.String("scenarios", "", "Comma-separated scenarios to also rate every mech playing, or 'all' ("+strings.Join(sim.ScenarioKeys(), ", ")+")")
+	ciWidth := flag.Float64("ci-width", 0.25, "Keep sampling each mech until its CR is within ± this at 95% confidence (0 = one round)")
+	ciMaxSims := flag.Int("ci-max-sims", 1000, "Cap on sims per side per mech when narrowing the CR interval (0 = no cap)")
+	ciBudget := flag.Duration("ci-budget", 30*time.Second, "Cap on extra time per mech spent narrowing the CR interval (0 = no cap)")
+	seedFlag := flag.Int64("seed", 0, "RNG seed for rating runs (0 = random); recorded in the run manifest")
+	compareFlag := flag.String("compare", "", "Compare two rating runs: 'runA,runB' (sim_runs ids)")
+	compareThreshold := flag.Float64("compare-threshold", 0.5, "CR change that makes a big mover for -compare")
+	resumeFlag := flag.Int("resume", 0, "Resume an interrupted rating run (sim_runs id), skipping the variants it already rated")
+	numWorkersFlag := flag.Int("workers", min(runtime.NumCPU(), 8), "Rating goroutines in this process (0 with -serve leaves all the work to -worker processes)")
+	serveAddr := flag.String("serve", "", "Also hand out variants to -worker processes on this address, e.g. ':8377' (localhost) or '0.0.0.0:8377'")
+	workerURL := flag.String("worker", "", "Run as a worker for the coordinator at this URL, e.g. 'http://box1:8377'")
+	leaseFlag := flag.Duration("lease", 15*time.Minute, "How long a -worker process may hold a variant before it is handed to someone else")
+	baselineFlag := flag.String("baseline", "", "Rate against these variants instead of the built-in HBK-4P: IDs or model codes with optional weights, e.g. 'HBK-4P,MAD-3R:2,1234'")
+	baselineEra := flag.String("baseline-era", "", "Rate against a panel of one light, medium, heavy and assault (median BV) introduced in this era, e.g. 'ilClan'")
+	tournamentFlag := flag.String("tournament", "", "Play a round-robin of duels among the -mech / -pool-* variants and store Elo ratings under this pool name, e.g. 'all'")
+	poolBVMax := flag.Int("pool-bv-max", 0, "Tournament pool: only variants with at most this BV (0 = any)")
+	poolEra := flag.String("pool-era", "", "Tournament pool: only variants available in this era, e.g. 'Succession Wars'")
+	tournamentOpponents := flag.Int("tournament-opponents", 30, "Opponents per variant in a -tournament (0 = everyone)")
+	tournamentDuels := flag.Int("tournament-duels", 10, "Duels per pairing in a -tournament, sides alternating")
+	genBoards := flag.Int("gen-boards", 0, fmt.Sprintf("Generate this many boards instead of loading SLIC_BOARD_DIR (0 = load them, generating %d if it has none)", defaultGenBoards))
+	boardProfiles := flag.String("board-profiles", "", "Comma-separated terrain profiles of generated boards, or 'all' ("+strings.Join(sim.BoardProfileKeys(), ", ")+")")
+	boardSeed := flag.Uint64("board-seed", 1, "RNG seed for generated boards")
+	writeBoardsDir := flag.String("write-boards", "", "Write the board pool to this directory as .board files and exit")
 	flag.Parse()
 
+	// Compare mode: diff two recorded runs, no sims
+	if *compareFlag != "" {
+		ids := strings.Split(*compareFlag, ",")
+		if len(ids) != 2 {
+			log.Fatalf("Compare format: 'runA,runB'")
+		}
+		a, errA := strconv.Atoi(strings.TrimSpace(ids[0]))
+		b, errB := strconv.Atoi(strings.TrimSpace(ids[1]))
+		if errA != nil || errB != nil {
+			log.Fatalf("Compare format: 'runA,runB'")
+		}
+		ctx := context.Background()
+		pool, err := db.Connect(ctx)
+		if err != nil {
+			log.Fatalf("DB: %v", err)
+		}
+		defer pool.Close()
+		if err := compareRuns(ctx, pool, a, b, *compareThreshold); err != nil {
+			log.Fatalf("Compare: %v", err)
+		}
+		return
+	}
+
+	var sideAI [2]sim.AI
+	aiKeys := strings.Split(*aiFlag, ",")
+	if len(aiKeys) > 2 {
+		log.Fatalf("-ai takes at most two strategies")
+	}
+	for i := range sideAI {
+		key := ""
+		if i < len(aiKeys) {
+			key = aiKeys[i]
+		}
+		ai, err := sim.ParseStrategy(key)
+		if err != nil {
+			log.Fatalf("-ai: %v", err)
+		}
+		sideAI[i] = ai
+	}
+
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -613,13 +130,39 @@ func main() {
 	}
 
 	log.Println("Loading boards...")
-	boards, err := LoadBoardPool(boardDirPath)
+	boards, boardHash, err := boardPool{boardDirPath, *genBoards, *boardProfiles, *boardSeed}.load()
 	if err != nil {
 		log.Fatalf("Error loading boards: %v", err)
 	}
 	log.Printf("Loaded %d standard 16x17 boards", len(boards))
-	if len(boards) < 2 {
-		log.Fatalf("Need at least 2 boards")
+	if *writeBoardsDir != "" {
+		if err := writeBoards(*writeBoardsDir, boards); err != nil {
+			log.Fatalf("Write boards: %v", err)
+		}
+		log.Printf("Wrote %d boards to %s", len(boards), *writeBoardsDir)
+		return
+	}
+
+	mtfDir := os.Getenv("SLIC_MTF_DIR")
+	if mtfDir == "" {
+		mtfDir = "/Users/puckopenclaw/projects/slic/data/megamek-data/data/mekfiles"
+	}
+
+	// Worker mode: rate variants for a coordinator, no database
+	if *workerURL != "" {
+		log.Println("Loading MTF files...")
+		mtfMap, err := sim.LoadMTFs(mtfDir)
+		if err != nil {
+			log.Printf("Loading MTF files: %v", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		local := newRunManifest(0, boardHash, mtfDir, 0, 0, 0)
+		if err := runWorker(ctx, *workerURL, max(*numWorkersFlag, 1), local, boards, mtfMap); err != nil {
+			log.Fatalf("Worker: %v", err)
+		}
+		log.Println("Done: no more jobs")
+		return
 	}
 
 	// Connect to DB
@@ -631,20 +174,11 @@ func main() {
 	defer pool.Close()
 
 	// Load MTF files
-	mtfDir := "/Users/puckopenclaw/projects/slic/data/megamek-data/data/mekfiles"
 	log.Println("Loading MTF files...")
-	mtfMap := make(map[string]*ingestion.MTFData)
-	_ = filepath.Walk(mtfDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".mtf") {
-			return nil
-		}
-		data, err := ingestion.ParseMTF(path)
-		if err != nil {
-			return nil
-		}
-		mtfMap[data.FullName()] = data
-		return nil
-	})
+	mtfMap, err := sim.LoadMTFs(mtfDir)
+	if err != nil {
+		log.Printf("Loading MTF files: %v", err)
+	}
 	log.Printf("Loaded %d MTF files", len(mtfMap))
 
 	// Replay mode
@@ -656,43 +190,36 @@ func main() {
 		atkName := strings.TrimSpace(parts[0])
 		defName := strings.TrimSpace(parts[1])
 
-		atkVariants, err := loadVariantsFromDB(ctx, pool, atkName)
+		atkVariants, err := simdb.LoadVariants(ctx, pool, atkName)
 		if err != nil || len(atkVariants) == 0 {
 			log.Fatalf("Attacker not found: %s", atkName)
 		}
-		defVariants, err := loadVariantsFromDB(ctx, pool, defName)
+		defVariants, err := simdb.LoadVariants(ctx, pool, defName)
 		if err != nil || len(defVariants) == 0 {
 			log.Fatalf("Defender not found: %s", defName)
 		}
 
-		loadWeaponsForVariants(ctx, pool, atkVariants)
-		loadWeaponsForVariants(ctx, pool, defVariants)
+		simdb.LoadWeapons(ctx, pool, atkVariants)
+		simdb.LoadWeapons(ctx, pool, defVariants)
 
-		atkMTF := mtfMap[atkVariants[0].Name+" "+atkVariants[0].ModelCode]
-		if atkMTF == nil {
-			atkMTF = mtfMap[atkVariants[0].Name]
-		}
-		defMTF := mtfMap[defVariants[0].Name+" "+defVariants[0].ModelCode]
-		if defMTF == nil {
-			defMTF = mtfMap[defVariants[0].Name]
-		}
+		atkMTF := sim.LookupMTF(mtfMap, &atkVariants[0])
+		defMTF := sim.LookupMTF(mtfMap, &defVariants[0])
 
-		atkTemplate := buildMechState(&atkVariants[0], atkMTF)
-		defTemplate := buildMechState(&defVariants[0], defMTF)
-		atkTemplate.OptimalRange = calcOptimalRange(atkTemplate)
-		defTemplate.OptimalRange = calcOptimalRange(defTemplate)
+		atkTemplate := sim.BuildMechState(&atkVariants[0], atkMTF)
+		defTemplate := sim.BuildMechState(&defVariants[0], defMTF)
 		atkTemplate.DebugName = atkName
 		defTemplate.DebugName = defName
+		atkTemplate.AI, defTemplate.AI = sideAI[0], sideAI[1]
 
 		rng := rand.New(rand.NewPCG(uint64(*replaySeed), 0))
 		b1 := boards[rng.IntN(len(boards))]
 		b2 := boards[rng.IntN(len(boards))]
-		combined := CombineBoards(b1, b2)
+		combined := sim.CombineBoards(b1, b2)
 
 		log.Printf("Running duel replay: %s vs %s (seed %d)", atkName, defName, *replaySeed)
-		replay := simulateDuelReplay(combined, atkTemplate, defTemplate, rng)
+		replay := sim.SimulateDuelReplay(combined, atkTemplate, defTemplate, rng)
 
-		data, err := replayToJSON(replay)
+		data, err := sim.ReplayToJSON(replay)
 		if err != nil {
 			log.Fatalf("JSON: %v", err)
 		}
@@ -708,6 +235,68 @@ func main() {
 		return
 	}
 
+	// Battle mode: force vs force
+	if *battleMode != "" {
+		parts := strings.SplitN(*battleMode, " vs ", 2)
+		if len(parts) != 2 {
+			log.Fatalf("Battle format: 'A1,A2 vs B1,B2'")
+		}
+		scenario, err := sim.ParseScenario(*scenarioFlag)
+		if err != nil {
+			log.Fatalf("-scenario: %v", err)
+		}
+		var forces [2]sim.Force
+		for i, part := range parts {
+			forces[i].Name = strings.TrimSpace(part)
+			for _, name := range strings.Split(part, ",") {
+				name = strings.TrimSpace(name)
+				vs, err := simdb.LoadVariants(ctx, pool, name)
+				if err != nil || len(vs) == 0 {
+					log.Fatalf("Variant not found: %s", name)
+				}
+				v := &vs[0]
+				for j := range vs {
+					if vs[j].ModelCode == name {
+						v = &vs[j]
+						break
+					}
+				}
+				one := []sim.Variant{*v}
+				simdb.LoadWeapons(ctx, pool, one)
+				mtf := sim.LookupMTF(mtfMap, &one[0])
+				m := sim.BuildMechState(&one[0], mtf)
+				m.AI = sideAI[i]
+				forces[i].Units = append(forces[i].Units, sim.ForceUnit{Mech: m})
+			}
+		}
+
+		log.Printf("Running %d battles: %s vs %s", *battleSims, forces[0].Name, forces[1].Name)
+		res, err := sim.RunBattles(ctx, sim.BattleConfig{
+			Boards:   boards,
+			A:        forces[0],
+			B:        forces[1],
+			Seed:     uint64(*replaySeed),
+			N:        *battleSims,
+			Scenario: scenario,
+		})
+		if err != nil {
+			log.Fatalf("Battle: %v", err)
+		}
+		fmt.Printf("\nSims: %d  Draws: %d  Mean turns: %.1f\n", res.Sims, res.Draws, res.MeanTurns)
+		for _, f := range []sim.ForceStats{res.A, res.B} {
+			fmt.Printf("\n%s — win rate %.1f%%", f.Name, f.WinRate*100)
+			if scenario != nil {
+				fmt.Printf(", %.2f objective points", f.MeanPoints)
+			}
+			fmt.Println()
+			fmt.Printf("%-35s %9s %8s %6s\n", "Unit", "Survival", "Damage", "Kills")
+			for _, u := range f.Units {
+				fmt.Printf("%-35s %8.0f%% %8.1f %6.2f\n", u.Name, u.SurvivalRate*100, u.MeanDamageDealt, u.MeanKills)
+			}
+		}
+		return
+	}
+
 	// Gen-replays mode: generate a replay for every variant, store in SQLite
 	if *genReplays {
 		log.Println("=== GEN-REPLAYS MODE ===")
@@ -728,19 +317,18 @@ func main() {
 		)`)
 
 		// Load all variants from Postgres
-		allVariants, err := loadVariantsFromDB(ctx, pool, "")
+		allVariants, err := simdb.LoadVariants(ctx, pool, "")
 		if err != nil {
 			log.Fatalf("Load variants: %v", err)
 		}
-		loadWeaponsForVariants(ctx, pool, allVariants)
+		simdb.LoadWeapons(ctx, pool, allVariants)
 
 		limit := len(allVariants)
 		if *genReplaysLimit > 0 && *genReplaysLimit < limit {
 			limit = *genReplaysLimit
 		}
 
-		hbkTemplate := buildHBK4P()
-		hbkTemplate.OptimalRange = calcOptimalRange(hbkTemplate)
+		hbkTemplate := sim.BuildHBK4P()
 
 		log.Printf("Generating replays for %d variants...", limit)
 
@@ -792,17 +380,13 @@ func main() {
 				defer genWg.Done()
 				for idx := range genJobs {
 					v := &allVariants[idx]
-					mtf := mtfMap[v.Name+" "+v.ModelCode]
-					if mtf == nil {
-						mtf = mtfMap[v.Name]
-					}
-					mechTemplate := buildMechState(v, mtf)
-					mechTemplate.OptimalRange = calcOptimalRange(mechTemplate)
+					mtf := sim.LookupMTF(mtfMap, v)
+					mechTemplate := sim.BuildMechState(v, mtf)
 
 					// Run 5 sims with different seeds, pick median by turn count
 					const numDuelSims = 5
 					type simResult struct {
-						replay *ReplayData
+						replay *sim.ReplayData
 						turns  int
 					}
 					var simResults []simResult
@@ -810,27 +394,21 @@ func main() {
 						rng := rand.New(rand.NewPCG(uint64(v.ID), uint64(s)))
 						b1 := boards[rng.IntN(len(boards))]
 						b2 := boards[rng.IntN(len(boards))]
-						combined := CombineBoards(b1, b2)
-						r := simulateDuelReplay(combined, mechTemplate, hbkTemplate, rng)
+						combined := sim.CombineBoards(b1, b2)
+						r := sim.SimulateDuelReplay(combined, mechTemplate, hbkTemplate, rng)
 						simResults = append(simResults, simResult{r, len(r.Turns)})
 					}
 					// Sort by turns, pick median
 					sort.Slice(simResults, func(i, j int) bool { return simResults[i].turns < simResults[j].turns })
 					replay := simResults[numDuelSims/2].replay
 
-					jsonData, err := replayToJSON(replay)
+					data, err := sim.EncodeReplay(replay)
 					if err != nil {
-						log.Printf("JSON %s: %v", v.Name, err)
+						log.Printf("Encode %s: %v", v.Name, err)
 						continue
 					}
 
-					// Gzip compress
-					var buf bytes.Buffer
-					gz := gzip.NewWriter(&buf)
-					gz.Write(jsonData)
-					gz.Close()
-
-					results <- replayResult{v.ID, buf.Bytes()}
+					results <- replayResult{v.ID, data}
 
 					n := genProcessed.Add(1)
 					if n%100 == 0 {
@@ -866,140 +444,273 @@ func main() {
 
 	// Load variants
 	log.Println("Loading variants from DB...")
-	variants, err := loadVariantsFromDB(ctx, pool, filter)
+	variants, err := simdb.LoadVariants(ctx, pool, filter)
 	if err != nil {
 		log.Fatalf("Load variants: %v", err)
 	}
 	log.Printf("Loaded %d variants", len(variants))
 
+	// Tournament mode: duels within the pool, no rating run
+	if *tournamentFlag != "" {
+		ids, err := loadPoolIDs(ctx, pool, *poolBVMax, *poolEra)
+		if err != nil {
+			log.Fatalf("Tournament pool: %v", err)
+		}
+		inPool := variants[:0]
+		for _, v := range variants {
+			if ids[v.ID] {
+				inPool = append(inPool, v)
+			}
+		}
+		variants = inPool
+		simdb.LoadWeapons(ctx, pool, variants)
+
+		seed := *seedFlag
+		if seed == 0 {
+			seed = rand.Int64N(math.MaxInt64)
+		}
+		tourCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		tc := tournamentConfig{*tournamentFlag, *tournamentOpponents, *tournamentDuels, max(*numWorkersFlag, 1), seed}
+		if err := runTournament(tourCtx, pool, tc, variants, boards, mtfMap); err != nil {
+			log.Fatalf("Tournament: %v", err)
+		}
+		return
+	}
+
+	// Condition presets, each rated on its own
+	conditions, err := parseConditionKeys(*conditionsFlag)
+	if err != nil {
+		log.Fatalf("-conditions: %v", err)
+	}
+
+	// AI strategies, each rated against the panel playing the Tactician
+	strategies, err := parseStrategyKeys(*strategiesFlag)
+	if err != nil {
+		log.Fatalf("-strategies: %v", err)
+	}
+
+	// Terrain classes, each rated on the pool's boards of that class
+	terrains, err := parseTerrainKeys(*terrainFlag, boards)
+	if err != nil {
+		log.Fatalf("-terrain: %v", err)
+	}
+
+	// Scenarios, each played against the panel
+	scenarios, err := parseScenarioKeys(*scenariosFlag)
+	if err != nil {
+		log.Fatalf("-scenarios: %v", err)
+	}
+
+	// Every variant gets its own RNG stream from the run seed, so a run
+	// with the same seed, code and data reproduces its ratings exactly (as
+	// long as -ci-budget doesn't cut sampling short)
+	seed := *seedFlag
+	if seed == 0 {
+		seed = rand.Int64N(math.MaxInt64)
+	}
+	baselines, err := resolveBaselines(ctx, pool, *baselineFlag, *baselineEra)
+	if err != nil {
+		log.Fatalf("Baseline: %v", err)
+	}
+	manifest := newRunManifest(seed, boardHash, mtfDir, *ciWidth, *ciMaxSims, *ciBudget)
+	manifest.Baselines = describePanel(baselines)
+	manifest.Conditions = ratedKeys(conditions, func(rc ratedCondition) string { return rc.key })
+	manifest.Strategies = ratedKeys(strategies, func(rs ratedStrategy) string { return rs.key })
+	manifest.Terrains = ratedKeys(terrains, func(t ratedTerrain) string { return t.key })
+	manifest.Scenarios = ratedKeys(scenarios, func(rs ratedScenario) string { return rs.key })
+	switch {
+	case *resumeFlag != 0 && *testMode:
+		log.Fatalf("-resume can't be used with -test, which records nothing")
+	case *resumeFlag != 0:
+		done, err := manifest.resume(ctx, pool, *resumeFlag)
+		if err != nil {
+			log.Fatalf("Resume: %v", err)
+		}
+		seed = manifest.Seed
+		remaining := variants[:0]
+		for _, v := range variants {
+			if !done[v.ID] {
+				remaining = append(remaining, v)
+			}
+		}
+		log.Printf("Resuming run %d: %d variants already rated, %d to go", manifest.ID, len(variants)-len(remaining), len(remaining))
+		variants = remaining
+	case !*testMode:
+		if err := manifest.insert(ctx, pool); err != nil {
+			log.Fatalf("Record run: %v", err)
+		}
+	}
+	log.Printf("Run %d: engine %s, git %s, seed %d, boards %s, mtf %s",
+		manifest.ID, manifest.EngineVersion, manifest.GitSHA, seed, manifest.BoardHash, manifest.MTFHash)
+
 	// Load weapons
 	log.Println("Loading weapons...")
-	loadWeaponsForVariants(ctx, pool, variants)
-
-	// Build HBK-4P baseline
-	log.Println("Running HBK-4P baseline...")
-	hbkTemplate := buildHBK4P()
-	hbkTemplate.OptimalRange = calcOptimalRange(hbkTemplate)
-
-	baseRng := rand.New(rand.NewPCG(42, 0))
-	// HBK-4P mirror match is symmetric by definition — baseline ratio is always 1.0.
-	// We still run offense to get the median turns (used for display/reference).
-	baselineOffense := runSimsBatch2D(boards, hbkTemplate, hbkTemplate, 50, numSimsPerBoard, baseRng)
-	baselineDefense := baselineOffense // symmetric: same mech on both sides
+	simdb.LoadWeapons(ctx, pool, variants)
+
+	// Build the baseline panel
+	log.Println("Running baseline mirror matches...")
+	panel := buildPanel(baselines, mtfMap)
+	for i := range panel {
+		// A mirror match is symmetric by definition — baseline ratio is always 1.0.
+		// We still run offense to get the median turns (used for display/reference).
+		baseRng := rand.New(rand.NewPCG(42, uint64(i)))
+		panel[i].Turns = sim.RunSimsBatch2D(boards, panel[i].Mech, panel[i].Mech, 50, sim.NumSimsPerBoard, baseRng)
+		baselines[i].Turns = panel[i].Turns
+		log.Printf("Baseline %s: offense=%.1f defense=%.1f weight=%g",
+			panel[i].Name, panel[i].Turns, panel[i].Turns, panel[i].Weight)
+	}
 	baselineRatio := 1.0
-	log.Printf("HBK-4P baseline: offense=%.1f defense=%.1f ratio=%.3f",
-		baselineOffense, baselineDefense, baselineRatio)
+
+	// Baselines score exactly 5.0 against themselves by definition, so only
+	// the mech's own sampling noise goes into its interval
+	rt := &rater{
+		seed:   seed,
+		boards: boards,
+		mtfs:   mtfMap,
+		panel:  panel,
+		cfg: sim.RatingConfig{
+			BaselineRatio:   baselineRatio,
+			TargetHalfWidth: *ciWidth,
+			MaxSims:         *ciMaxSims,
+			Budget:          *ciBudget,
+		},
+		conditions: conditions,
+		strategies: strategies,
+		terrains:   terrains,
+		scenarios:  scenarios,
+	}
 
 	// Process variants
-	numWorkers := runtime.NumCPU()
-	if numWorkers > 8 {
-		numWorkers = 8
+	numWorkers := *numWorkersFlag
+	if numWorkers < 1 && *serveAddr == "" {
+		numWorkers = 1
 	}
 	log.Printf("Processing %d variants with %d workers...", len(variants), numWorkers)
 
-	results := make(chan simResult, 100)
-	jobs := make(chan int, len(variants))
+	// The first SIGINT stops handing out variants and lets the workers
+	// finish the ones they have; a second one kills the process
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-runCtx.Done()
+		stop()
+		log.Println("Interrupted: finishing the variants in progress (interrupt again to abort)")
+	}()
 
-	var processed atomic.Int64
-	var wg sync.WaitGroup
+	queue := newJobQueue(runCtx, len(variants), *leaseFlag)
+	var jobServer *http.Server
+	if *serveAddr != "" {
+		token := os.Getenv(tokenEnv)
+		if token == "" {
+			token = newToken()
+			log.Printf("Workers need %s=%s", tokenEnv, token)
+		}
+		spec := runSpec{token, *manifest, baselines}
+		jobServer, err = serveJobs(*serveAddr, spec, variants, queue)
+		if err != nil {
+			log.Fatalf("-serve: %v", err)
+		}
+	}
 
+	var processed atomic.Int64
 	for w := 0; w < numWorkers; w++ {
-		wg.Add(1)
 		go func() {
-			defer wg.Done()
-			localRng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
-			// Pre-combine board pairs per worker (avoids re-combining per variant)
-			preBoards := precomputeBoardPairs(boards, numBoardPairs, localRng)
-			for idx := range jobs {
-				v := &variants[idx]
-
-				// HBK-4P is the reference mech — hardcode to exactly 5.00
-				if v.ModelCode == "HBK-4P" {
-					results <- simResult{v.ID, v.Name + " " + v.ModelCode, baselineOffense, baselineDefense, 5.0, 6}
-					processed.Add(1)
-					continue
-				}
-
-				mtf := mtfMap[v.Name]
-				if mtf == nil {
-					altName := strings.TrimSuffix(v.Name, " "+v.ModelCode)
-					altName = altName + " " + v.ModelCode
-					mtf = mtfMap[altName]
-				}
-
-				mechTemplate := buildMechState(v, mtf)
-				mechTemplate.OptimalRange = calcOptimalRange(mechTemplate)
-
-				offTurns := runSimsBatch2DPre(preBoards, mechTemplate, hbkTemplate, numSimsPerBoard, localRng)
-				defTurns := runSimsBatch2DPre(preBoards, hbkTemplate, mechTemplate, numSimsPerBoard, localRng)
-
-				ratio := defTurns / offTurns
-				score := 5.0 + kFactor*math.Log(ratio/baselineRatio)
-				if score < 1 {
-					score = 1
+			rate := rt.worker()
+			for {
+				idx, lease, ok := queue.next(runCtx, false)
+				if !ok {
+					return
 				}
-				if score > 10 {
-					score = 10
-				}
-
-				results <- simResult{v.ID, v.Name + " " + v.ModelCode, offTurns, defTurns, score, mechTemplate.OptimalRange}
+				r := rate(&variants[idx])
+				queue.complete(idx, lease, r)
 
 				n := processed.Add(1)
 				if n%50 == 0 || *testMode || filter != "" {
-					log.Printf("  [%d/%d] %s %s: off=%.1f def=%.1f CR=%.2f",
-						n, len(variants), v.Name, v.ModelCode, offTurns, defTurns, score)
+					log.Printf("  [%d/%d] %s", n, len(variants), r)
 				}
 			}
 		}()
 	}
 
-	for i := range variants {
-		jobs <- i
-	}
-	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Collect results
+	// Collect results, committing each variant as it comes in (on ctx, not
+	// runCtx, so results finished after an interrupt are still saved)
 	var allResults []simResult
 	updated := 0
-	for r := range results {
+	for r := range queue.results {
 		allResults = append(allResults, r)
 		if !*testMode {
-			_, err := pool.Exec(ctx, `
-				UPDATE variant_stats SET combat_rating = $2, offense_turns = $3, defense_turns = $4, heat_neutral_range = $5
-				WHERE variant_id = $1`, r.id, r.score, r.offense, r.defense, strconv.Itoa(r.optimalRange))
-			if err != nil {
-				log.Printf("Update %d: %v", r.id, err)
+			if err := saveResult(ctx, pool, manifest.ID, r); err != nil {
+				log.Printf("Update %d: %v", r.ID, err)
 				continue
 			}
 			updated++
 		}
 	}
+	if jobServer != nil {
+		// Workers still asking for jobs get a 204 first
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		jobServer.Shutdown(shutdownCtx)
+		cancel()
+	}
+
+	if runCtx.Err() != nil {
+		if !*testMode {
+			log.Printf("Stopped after saving %d variants; resume with -resume %d", updated, manifest.ID)
+		}
+		return
+	}
 
 	if *testMode || filter != "" {
 		// Sort by score
 		sort.Slice(allResults, func(i, j int) bool {
-			return allResults[i].score > allResults[j].score
+			return allResults[i].Score > allResults[j].Score
 		})
 
 		fmt.Println("\n═══════════════════════════════════════════")
 		fmt.Println("V2 Test Results")
 		fmt.Println("═══════════════════════════════════════════")
-		fmt.Printf("%-35s %8s %8s %6s\n", "Mech", "Offense", "Defense", "CR")
+		fmt.Printf("%-35s %8s %8s %13s %6s", "Mech", "Offense", "Defense", "CR", "Sims")
+		for _, rc := range conditions {
+			fmt.Printf(" %12s", rc.key)
+		}
+		for _, rs := range strategies {
+			fmt.Printf(" %12s", rs.key)
+		}
+		for _, t := range terrains {
+			fmt.Printf(" %12s", t.key)
+		}
+		for _, rs := range scenarios {
+			fmt.Printf(" %12s", rs.key)
+		}
+		fmt.Println()
 		fmt.Println("───────────────────────────────────────────")
 		for _, r := range allResults {
-			fmt.Printf("%-35s %8.1f %8.1f %6.2f\n", r.name, r.offense, r.defense, r.score)
+			fmt.Printf("%-35s %8.1f %8.1f %6.2f ± %4.2f %6d", r.Name, r.Offense, r.Defense, r.Score, r.ScoreCI, r.Sims)
+			for _, rc := range conditions {
+				fmt.Printf(" %12.2f", r.ConditionCR[rc.key])
+			}
+			for _, rs := range strategies {
+				fmt.Printf(" %12.2f", r.StrategyCR[rs.key])
+			}
+			for _, t := range terrains {
+				fmt.Printf(" %12.2f", r.TerrainCR[t.key])
+			}
+			for _, rs := range scenarios {
+				fmt.Printf(" %12.2f", r.ScenarioCR[rs.key])
+			}
+			fmt.Println()
 		}
 
 		// Write results file
 		writeTestResults(allResults)
 	}
 
-	log.Printf("Done! Updated %d variants", updated)
+	if !*testMode {
+		if err := manifest.finish(ctx, pool); err != nil {
+			log.Printf("Finish run %d: %v", manifest.ID, err)
+		}
+	}
+	log.Printf("Done! Updated %d variants in run %d (%s)", updated, manifest.ID, time.Since(manifest.StartedAt).Round(time.Second))
 }
 
 func writeTestResults(results []simResult) {
@@ -1013,21 +724,21 @@ func writeTestResults(results []simResult) {
 	fmt.Fprintln(f, "# SLIC Combat Rating V2 — Test Results")
 	fmt.Fprintln(f, "")
 	fmt.Fprintln(f, "## Configuration")
-	fmt.Fprintf(f, "- Sims per board pair: %d\n", numSimsPerBoard)
-	fmt.Fprintf(f, "- Board pairs per mech: %d\n", numBoardPairs)
-	fmt.Fprintf(f, "- Total sims per mech: %d\n", numSimsPerBoard*numBoardPairs)
-	fmt.Fprintf(f, "- Max turns: %d\n", maxTurns)
-	fmt.Fprintf(f, "- Gunnery/Piloting: %d/%d\n", gunnerySkill, pilotingSkill)
+	fmt.Fprintf(f, "- Sims per board pair: %d\n", sim.NumSimsPerBoard)
+	fmt.Fprintf(f, "- Board pairs per mech: %d\n", sim.NumBoardPairs)
+	fmt.Fprintf(f, "- Sims per mech: %d per side, more until the CR is within the -ci-width\n", sim.NumSimsPerBoard*sim.NumBoardPairs)
+	fmt.Fprintf(f, "- Max turns: %d\n", sim.MaxTurns)
+	fmt.Fprintf(f, "- Gunnery/Piloting: %d/%d\n", sim.DefaultGunnery, sim.DefaultPiloting)
 	fmt.Fprintln(f, "- Board: 2x 16x17 standard boards combined (32x17)")
 	fmt.Fprintln(f, "- Deployment: attacker rows 1-3, defender rows 15-17")
 	fmt.Fprintln(f, "- 2D hex grid with terrain, LOS, arcs, torso twist")
 	fmt.Fprintln(f, "")
 	fmt.Fprintln(f, "## Results")
 	fmt.Fprintln(f, "")
-	fmt.Fprintf(f, "| %-35s | %8s | %8s | %6s |\n", "Mech", "Offense", "Defense", "CR")
-	fmt.Fprintf(f, "|%-37s|%10s|%10s|%8s|\n", strings.Repeat("-", 37), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 8))
+	fmt.Fprintf(f, "| %-35s | %8s | %8s | %13s |\n", "Mech", "Offense", "Defense", "CR")
+	fmt.Fprintf(f, "|%-37s|%10s|%10s|%15s|\n", strings.Repeat("-", 37), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 15))
 	for _, r := range results {
-		fmt.Fprintf(f, "| %-35s | %8.1f | %8.1f | %6.2f |\n", r.name, r.offense, r.defense, r.score)
+		fmt.Fprintf(f, "| %-35s | %8.1f | %8.1f | %6.2f ± %4.2f |\n", r.Name, r.Offense, r.Defense, r.Score, r.ScoreCI)
 	}
 	fmt.Fprintln(f, "")
 	fmt.Fprintln(f, "## Key Changes from V1")
@@ -1041,11 +752,29 @@ func writeTestResults(results []simResult) {
 	log.Println("Wrote results to V2_TEST_RESULTS.md")
 }
 
+// simResult is one variant's ratings; remote workers post it as JSON.
 type simResult struct {
-	id           int
-	name         string
-	offense      float64
-	defense      float64
-	score        float64
-	optimalRange int
+	ID           int
+	Name         string
+	Offense      float64
+	Defense      float64
+	Score        float64
+	ScoreCI      float64 // 95% half-width
+	Sims         int     // per side
+	OptimalRange int
+	ConditionCR  map[string]float64 // by condition preset
+	StrategyCR   map[string]float64 // by AI strategy
+	TerrainCR    map[string]float64 // by terrain class
+	ScenarioCR   map[string]float64 // by scenario
+	Baselines    []baselineScore    // by panel baseline
+}
+
+// baselineScore is a variant's rating against one baseline of the panel.
+type baselineScore struct {
+	ID      int // the baseline's variant ID
+	Weight  float64
+	Score   float64
+	ScoreCI float64
+	Offense float64
+	Defense float64
 }
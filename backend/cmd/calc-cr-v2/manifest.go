@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JustinWhittecar/slic/internal/sim"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ─── Run manifest ───────────────────────────────────────────────────────────
+
+// runManifest is one row of sim_runs: what produced a set of ratings.
+type runManifest struct {
+	ID            int
+	EngineVersion string
+	GitSHA        string
+	Seed          int64
+	BoardHash     string
+	MTFHash       string
+	MaxTurns      int
+	KFactor       float64
+	Gunnery       int
+	Piloting      int
+	BoardPairs    int
+	SimsPerBoard  int
+	CIWidth       float64
+	CIMaxSims     int
+	CIBudget      time.Duration
+	Baselines     string
+	Conditions    string // what the run also rated, as key lists
+	Strategies    string
+	Terrains      string
+	Scenarios     string
+	Args          string
+	Variants      int
+	StartedAt     time.Time
+	FinishedAt    *time.Time
+}
+
+func newRunManifest(seed int64, boardHash, mtfDir string, ciWidth float64, ciMaxSims int, ciBudget time.Duration) *runManifest {
+	return &runManifest{
+		EngineVersion: sim.EngineVersion,
+		GitSHA:        gitSHA(),
+		Seed:          seed,
+		BoardHash:     boardHash,
+		MTFHash: hashFiles(mtfDir, func(path string) bool {
+			return strings.HasSuffix(strings.ToLower(path), ".mtf")
+		}),
+		MaxTurns:     sim.MaxTurns,
+		KFactor:      sim.KFactor,
+		Gunnery:      sim.DefaultGunnery,
+		Piloting:     sim.DefaultPiloting,
+		BoardPairs:   sim.NumBoardPairs,
+		SimsPerBoard: sim.NumSimsPerBoard,
+		CIWidth:      ciWidth,
+		CIMaxSims:    ciMaxSims,
+		CIBudget:     ciBudget,
+		Args:         strings.Join(os.Args[1:], " "),
+		StartedAt:    time.Now(),
+	}
+}
+
+func (m *runManifest) insert(ctx context.Context, pool *pgxpool.Pool) error {
+	return pool.QueryRow(ctx, `
+		INSERT INTO sim_runs (engine_version, git_sha, seed, board_hash, mtf_hash, max_turns, k_factor,
+		                      gunnery, piloting, board_pairs, sims_per_board, ci_width, ci_max_sims, ci_budget_ms,
+		                      baselines, conditions, strategies, terrains, scenarios, args, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		RETURNING id`,
+		m.EngineVersion, m.GitSHA, m.Seed, m.BoardHash, m.MTFHash, m.MaxTurns, m.KFactor,
+		m.Gunnery, m.Piloting, m.BoardPairs, m.SimsPerBoard, m.CIWidth, m.CIMaxSims, m.CIBudget.Milliseconds(),
+		m.Baselines, m.Conditions, m.Strategies, m.Terrains, m.Scenarios, m.Args, m.StartedAt).Scan(&m.ID)
+}
+
+// finish marks the run complete. Variants counts every variant the run
+// rated, including those rated before a resume.
+func (m *runManifest) finish(ctx context.Context, pool *pgxpool.Pool) error {
+	now := time.Now()
+	m.FinishedAt = &now
+	return pool.QueryRow(ctx, `
+		UPDATE sim_runs SET variants = (SELECT count(*) FROM variant_run_ratings WHERE run_id = $1), finished_at = $2
+		WHERE id = $1
+		RETURNING variants`, m.ID, now).Scan(&m.Variants)
+}
+
+func loadRunManifest(ctx context.Context, pool *pgxpool.Pool, id int) (*runManifest, error) {
+	m := &runManifest{ID: id}
+	var budgetMS int64
+	err := pool.QueryRow(ctx, `
+		SELECT engine_version, git_sha, seed, board_hash, mtf_hash, max_turns, k_factor,
+		       gunnery, piloting, board_pairs, sims_per_board, ci_width, ci_max_sims, ci_budget_ms,
+		       baselines, conditions, strategies, terrains, scenarios, args, variants, started_at, finished_at
+		FROM sim_runs WHERE id = $1`, id).Scan(
+		&m.EngineVersion, &m.GitSHA, &m.Seed, &m.BoardHash, &m.MTFHash, &m.MaxTurns, &m.KFactor,
+		&m.Gunnery, &m.Piloting, &m.BoardPairs, &m.SimsPerBoard, &m.CIWidth, &m.CIMaxSims, &budgetMS,
+		&m.Baselines, &m.Conditions, &m.Strategies, &m.Terrains, &m.Scenarios, &m.Args, &m.Variants, &m.StartedAt, &m.FinishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("run %d: %w", id, err)
+	}
+	m.CIBudget = time.Duration(budgetMS) * time.Millisecond
+	return m, nil
+}
+
+// fields lists the settings that can explain a difference between two runs.
+func (m *runManifest) fields() [][2]string {
+	return [][2]string{
+		{"engine", m.EngineVersion},
+		{"git", m.GitSHA},
+		{"seed", strconv.FormatInt(m.Seed, 10)},
+		{"boards", m.BoardHash},
+		{"mtf", m.MTFHash},
+		{"max turns", strconv.Itoa(m.MaxTurns)},
+		{"k-factor", strconv.FormatFloat(m.KFactor, 'g', -1, 64)},
+		{"skills", fmt.Sprintf("%d/%d", m.Gunnery, m.Piloting)},
+		{"sims", fmt.Sprintf("%d pairs x %d", m.BoardPairs, m.SimsPerBoard)},
+		{"ci", fmt.Sprintf("±%g, %d sims, %s", m.CIWidth, m.CIMaxSims, m.CIBudget)},
+		{"baselines", m.Baselines},
+		{"conditions", m.Conditions},
+		{"strategies", m.Strategies},
+		{"terrain", m.Terrains},
+		{"scenarios", m.Scenarios},
+		{"args", m.Args},
+	}
+}
+
+// manifestChanges lists the settings that differ from a to b, other than
+// those named in skip.
+func manifestChanges(a, b *runManifest, skip ...string) []string {
+	var changes []string
+	fa, fb := a.fields(), b.fields()
+	for i := range fa {
+		if fa[i][1] != fb[i][1] && !slices.Contains(skip, fa[i][0]) {
+			changes = append(changes, fmt.Sprintf("%s %s → %s", fa[i][0], fa[i][1], fb[i][1]))
+		}
+	}
+	return changes
+}
+
+// gitSHA is the commit the binary was built from, with "-dirty" if the tree
+// had local changes; it falls back to asking git for `go run`.
+func gitSHA() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		rev, dirty := "", false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				rev = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if rev != "" {
+			if dirty {
+				rev += "-dirty"
+			}
+			return rev
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	rev := strings.TrimSpace(string(out))
+	if status, err := exec.Command("git", "status", "--porcelain").Output(); err == nil && len(status) > 0 {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+// hashFiles hashes the paths and contents of the files under dir that keep
+// accepts, in path order. It returns the first 16 hex digits, or "" if dir
+// can't be read.
+func hashFiles(dir string, keep func(path string) bool) string {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !keep(path) {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, path := range paths {
+		rel, _ := filepath.Rel(dir, path)
+		io.WriteString(h, rel+"\x00")
+		f, err := os.Open(path)
+		if err != nil {
+			return ""
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return ""
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ─── Checkpointing ──────────────────────────────────────────────────────────
+//
+// Each variant's ratings are committed in one transaction as soon as they
+// come in, ending with its variant_run_ratings row, so the variants an
+// interrupted run has finished are exactly those with a row. Resuming reruns
+// the rest with the run's seed, which gives each variant the same RNG stream
+// it would have had.
+
+// resume takes over run id: m adopts its ID, seed and start time, and gets
+// back the variants already rated. It refuses a run that has finished or
+// was made with different code, data or settings, including what else it
+// rated each variant under.
+func (m *runManifest) resume(ctx context.Context, pool *pgxpool.Pool, id int) (map[int]bool, error) {
+	prev, err := loadRunManifest(ctx, pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if prev.FinishedAt != nil {
+		return nil, fmt.Errorf("run %d finished at %s", id, prev.FinishedAt.Format(time.DateTime))
+	}
+	if changes := manifestChanges(prev, m, "seed", "args"); len(changes) > 0 {
+		return nil, fmt.Errorf("run %d was made with different settings: %s", id, strings.Join(changes, "; "))
+	}
+	m.ID, m.Seed, m.Args, m.StartedAt = prev.ID, prev.Seed, prev.Args, prev.StartedAt
+
+	rows, err := pool.Query(ctx, `SELECT variant_id FROM variant_run_ratings WHERE run_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[int]bool{}
+	for rows.Next() {
+		var vid int
+		if err := rows.Scan(&vid); err != nil {
+			return nil, err
+		}
+		done[vid] = true
+	}
+	return done, rows.Err()
+}
+
+// saveResult commits one variant's ratings for run runID.
+func saveResult(ctx context.Context, pool *pgxpool.Pool, runID int, r simResult) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE variant_stats SET combat_rating = $2, offense_turns = $3, defense_turns = $4, heat_neutral_range = $5,
+			       combat_rating_ci = $6, run_id = $7
+			WHERE variant_id = $1`, r.ID, r.Score, r.Offense, r.Defense, strconv.Itoa(r.OptimalRange), r.ScoreCI, runID)
+		if err != nil {
+			return err
+		}
+		for cond, cr := range r.ConditionCR {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO variant_condition_ratings (variant_id, condition, combat_rating) VALUES ($1, $2, $3)
+				ON CONFLICT (variant_id, condition) DO UPDATE SET combat_rating = EXCLUDED.combat_rating`, r.ID, cond, cr)
+			if err != nil {
+				return fmt.Errorf("%s: %w", cond, err)
+			}
+		}
+		for strat, cr := range r.StrategyCR {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO variant_strategy_ratings (variant_id, strategy, combat_rating) VALUES ($1, $2, $3)
+				ON CONFLICT (variant_id, strategy) DO UPDATE SET combat_rating = EXCLUDED.combat_rating`, r.ID, strat, cr)
+			if err != nil {
+				return fmt.Errorf("%s: %w", strat, err)
+			}
+		}
+		for terrain, cr := range r.TerrainCR {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO variant_terrain_ratings (variant_id, terrain, combat_rating) VALUES ($1, $2, $3)
+				ON CONFLICT (variant_id, terrain) DO UPDATE SET combat_rating = EXCLUDED.combat_rating`, r.ID, terrain, cr)
+			if err != nil {
+				return fmt.Errorf("%s: %w", terrain, err)
+			}
+		}
+		for scenario, cr := range r.ScenarioCR {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO variant_scenario_ratings (variant_id, scenario, combat_rating) VALUES ($1, $2, $3)
+				ON CONFLICT (variant_id, scenario) DO UPDATE SET combat_rating = EXCLUDED.combat_rating`, r.ID, scenario, cr)
+			if err != nil {
+				return fmt.Errorf("%s: %w", scenario, err)
+			}
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM variant_baseline_ratings WHERE variant_id = $1`, r.ID); err != nil {
+			return err
+		}
+		for _, b := range r.Baselines {
+			if b.ID == 0 {
+				continue // the built-in HBK-4P with no variant row
+			}
+			_, err := tx.Exec(ctx, `
+				INSERT INTO variant_baseline_ratings (variant_id, baseline_id, weight, combat_rating, combat_rating_ci,
+				                                      offense_turns, defense_turns, run_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, r.ID, b.ID, b.Weight, b.Score, b.ScoreCI, b.Offense, b.Defense, runID)
+			if err != nil {
+				return fmt.Errorf("baseline %d: %w", b.ID, err)
+			}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO variant_run_ratings (run_id, variant_id, combat_rating, combat_rating_ci, offense_turns, defense_turns)
+			VALUES ($1, $2, $3, $4, $5, $6)`, runID, r.ID, r.Score, r.ScoreCI, r.Offense, r.Defense)
+		return err
+	})
+}
+
+// ─── Run comparison ─────────────────────────────────────────────────────────
+
+type runDiff struct {
+	name     string
+	crA, crB float64
+	ciA, ciB float64
+	delta    float64
+}
+
+// compareRuns prints what changed between runs a and b and every variant
+// whose CR moved by at least threshold. A move bigger than both confidence
+// intervals together is marked significant; anything less may be noise.
+func compareRuns(ctx context.Context, pool *pgxpool.Pool, a, b int, threshold float64) error {
+	ma, err := loadRunManifest(ctx, pool, a)
+	if err != nil {
+		return err
+	}
+	mb, err := loadRunManifest(ctx, pool, b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Run %d (%s) vs run %d (%s)\n", a, ma.StartedAt.Format(time.DateTime), b, mb.StartedAt.Format(time.DateTime))
+	changes := manifestChanges(ma, mb)
+	for _, c := range changes {
+		fmt.Println("  " + c)
+	}
+	if len(changes) == 0 {
+		fmt.Println("  same settings")
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT v.name || ' ' || v.model_code, ra.combat_rating, ra.combat_rating_ci, rb.combat_rating, rb.combat_rating_ci
+		FROM variant_run_ratings ra
+		JOIN variant_run_ratings rb ON rb.variant_id = ra.variant_id AND rb.run_id = $2
+		JOIN variants v ON v.id = ra.variant_id
+		WHERE ra.run_id = $1`, a, b)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var diffs []runDiff
+	sumSq := 0.0
+	for rows.Next() {
+		var d runDiff
+		if err := rows.Scan(&d.name, &d.crA, &d.ciA, &d.crB, &d.ciB); err != nil {
+			return err
+		}
+		d.delta = d.crB - d.crA
+		sumSq += d.delta * d.delta
+		diffs = append(diffs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		return fmt.Errorf("runs %d and %d rated no variants in common", a, b)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return math.Abs(diffs[i].delta) > math.Abs(diffs[j].delta) })
+	movers, significant := 0, 0
+	fmt.Printf("\n%-35s %13s %13s %7s\n", "Mech", "Run "+strconv.Itoa(a), "Run "+strconv.Itoa(b), "Δ")
+	for _, d := range diffs {
+		if math.Abs(d.delta) < threshold {
+			break
+		}
+		movers++
+		flag := ""
+		if math.Abs(d.delta) > d.ciA+d.ciB {
+			flag = "  significant"
+			significant++
+		}
+		fmt.Printf("%-35s %6.2f ± %4.2f %6.2f ± %4.2f %+7.2f%s\n", d.name, d.crA, d.ciA, d.crB, d.ciB, d.delta, flag)
+	}
+	fmt.Printf("\n%d variants compared, RMS Δ %.2f; %d moved by %.2f or more, %d beyond their intervals\n",
+		len(diffs), math.Sqrt(sumSq/float64(len(diffs))), movers, threshold, significant)
+	return nil
+}
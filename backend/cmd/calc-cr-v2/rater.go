@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
+)
+
+// ─── Rating a variant ───────────────────────────────────────────────────────
+
+// ratedCondition is a condition preset every mech is also rated under.
+type ratedCondition struct {
+	key string
+	c   sim.Conditions
+}
+
+// ratedStrategy is an AI strategy every mech is also rated playing.
+type ratedStrategy struct {
+	key string
+	ai  sim.AI
+}
+
+// ratedTerrain is a terrain class every mech is also rated on, with the
+// pool's boards of that class.
+type ratedTerrain struct {
+	key    string
+	boards []*sim.Board
+}
+
+// ratedScenario is a scenario every mech is also rated playing.
+type ratedScenario struct {
+	key string
+	sc  *sim.Scenario
+}
+
+// parseConditionKeys parses a -conditions list, or 'all'.
+func parseConditionKeys(list string) ([]ratedCondition, error) {
+	keys := strings.Split(list, ",")
+	if list == "all" {
+		keys = sim.ConditionKeys()
+	}
+	var conditions []ratedCondition
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		c, err := sim.ParseConditions(key)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, ratedCondition{key, c})
+	}
+	return conditions, nil
+}
+
+// parseStrategyKeys parses a -strategies list, or 'all'.
+func parseStrategyKeys(list string) ([]ratedStrategy, error) {
+	keys := strings.Split(list, ",")
+	if list == "all" {
+		keys = sim.StrategyKeys()
+	}
+	var strategies []ratedStrategy
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		ai, err := sim.ParseStrategy(key)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, ratedStrategy{key, ai})
+	}
+	return strategies, nil
+}
+
+// parseTerrainKeys parses a -terrain list, or 'all', sorting boards into
+// the classes. A class with no boards in the pool is left out.
+func parseTerrainKeys(list string, boards []*sim.Board) ([]ratedTerrain, error) {
+	keys := strings.Split(list, ",")
+	if list == "all" {
+		keys = sim.TerrainClassKeys()
+	}
+	var classes map[string][]*sim.Board
+	var terrains []ratedTerrain
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		key, err := sim.ParseTerrainClass(key)
+		if err != nil {
+			return nil, err
+		}
+		if classes == nil {
+			classes = sim.ClassifyBoards(boards)
+		}
+		if len(classes[key]) == 0 {
+			log.Printf("No %s boards in the pool, not rating it", key)
+			continue
+		}
+		terrains = append(terrains, ratedTerrain{key, classes[key]})
+	}
+	return terrains, nil
+}
+
+// parseScenarioKeys parses a -scenarios list, or 'all'.
+func parseScenarioKeys(list string) ([]ratedScenario, error) {
+	keys := strings.Split(list, ",")
+	if list == "all" {
+		keys = sim.ScenarioKeys()
+	}
+	var scenarios []ratedScenario
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		sc, err := sim.ParseScenario(key)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, ratedScenario{key, sc})
+	}
+	return scenarios, nil
+}
+
+// ratedKeys lists the keys of what a run also rates, for its manifest.
+func ratedKeys[T any](rated []T, key func(T) string) string {
+	keys := make([]string, len(rated))
+	for i, r := range rated {
+		keys[i] = key(r)
+	}
+	return strings.Join(keys, ",")
+}
+
+// rater rates variants for one run. Everything a variant's ratings depend
+// on is here or in the variant itself, and each variant draws from its own
+// RNG stream seeded by the run seed and its ID, so a variant gets the same
+// ratings whichever worker or process rates it.
+type rater struct {
+	seed       int64
+	boards     []*sim.Board
+	mtfs       map[string]*ingestion.MTFData
+	panel      []sim.Baseline
+	cfg        sim.RatingConfig
+	conditions []ratedCondition
+	strategies []ratedStrategy
+	terrains   []ratedTerrain
+	scenarios  []ratedScenario
+}
+
+// worker returns a function that rates one variant at a time. Each worker
+// pre-combines its own board pairs (avoids re-combining per variant); they
+// all draw the same pairs from the seed.
+func (rt *rater) worker() func(v *sim.Variant) simResult {
+	preBoards := sim.PrecomputeBoardPairs(rt.boards, sim.NumBoardPairs, rand.New(rand.NewPCG(uint64(rt.seed), 0)))
+	condBoards := make([]*sim.PrecomputedBoards, len(rt.conditions))
+	for i, rc := range rt.conditions {
+		condBoards[i] = preBoards.WithConditions(rc.c)
+	}
+	// Each terrain class draws its own pairs, from streams no variant uses
+	terrBoards := make([]*sim.PrecomputedBoards, len(rt.terrains))
+	for i, t := range rt.terrains {
+		terrBoards[i] = sim.PrecomputeBoardPairs(t.boards, sim.NumBoardPairs, rand.New(rand.NewPCG(uint64(rt.seed), math.MaxUint64-uint64(i))))
+	}
+	// panelCR is the weighted CR of one fixed round against each baseline
+	// on boards; a baseline scores 5.0 against itself unless mirrors is set
+	panelCR := func(boards *sim.PrecomputedBoards, mt *sim.MechState, id int, mirrors bool, rng *rand.Rand) float64 {
+		scores := make([]float64, len(rt.panel))
+		for i, b := range rt.panel {
+			if b.ID == id && !mirrors {
+				scores[i] = 5.0
+				continue
+			}
+			off := sim.RunSimsBatch2DPre(boards, mt, b.Mech, sim.NumSimsPerBoard, rng)
+			def := sim.RunSimsBatch2DPre(boards, b.Mech, mt, sim.NumSimsPerBoard, rng)
+			scores[i] = sim.CombatRating(off, def, rt.cfg.BaselineRatio)
+		}
+		return sim.WeightedMean(scores, rt.panel)
+	}
+
+	return func(v *sim.Variant) simResult {
+		rng := rand.New(rand.NewPCG(uint64(rt.seed), uint64(v.ID)))
+		name := v.Name + " " + v.ModelCode
+
+		mechTemplate := sim.BuildMechState(v, sim.LookupMTF(rt.mtfs, v))
+		optimalRange := mechTemplate.OptimalRange
+		// A baseline plays as its own panel mech (the built-in HBK-4P is
+		// hand-built), so it scores exactly 5.0 against itself
+		for _, b := range rt.panel {
+			if b.ID == v.ID {
+				mechTemplate = b.Mech
+			}
+		}
+
+		rating := sim.RatePanel(preBoards, mechTemplate, v.ID, rt.panel, rt.cfg, rng)
+
+		// Every baseline's mirror match stays symmetric under any
+		// condition, so the baseline ratio is 1.0 for each of them too
+		condCR := make(map[string]float64, len(rt.conditions))
+		for i, rc := range rt.conditions {
+			condCR[rc.key] = panelCR(condBoards[i], mechTemplate, v.ID, false, rng)
+		}
+
+		terrCR := make(map[string]float64, len(rt.terrains))
+		for i, t := range rt.terrains {
+			terrCR[t.key] = panelCR(terrBoards[i], mechTemplate, v.ID, false, rng)
+		}
+
+		// The baselines always play as the Tactician, so a strategy's
+		// rating measures the mech, not the mirror
+		stratCR := make(map[string]float64, len(rt.strategies))
+		for _, rs := range rt.strategies {
+			styled := *mechTemplate
+			styled.AI = rs.ai
+			stratCR[rs.key] = panelCR(preBoards, &styled, v.ID, true, rng)
+		}
+
+		// Scenarios are played out as one-on-one battles, so a fast mech
+		// can win on the objective without winning the fight
+		scenCR := make(map[string]float64, len(rt.scenarios))
+		for _, rs := range rt.scenarios {
+			scenCR[rs.key] = sim.ScenarioRating(rt.boards, mechTemplate, v.ID, rs.sc, rt.panel, sim.NumSimsPerBoard, rng)
+		}
+
+		subs := make([]baselineScore, len(rt.panel))
+		for i, b := range rt.panel {
+			sr := rating.Sub[i]
+			subs[i] = baselineScore{b.ID, b.Weight, sr.CR.Value, sr.CR.HalfWidth(), sr.Offense.Value, sr.Defense.Value}
+		}
+		return simResult{v.ID, name, rating.Offense.Value, rating.Defense.Value, rating.CR.Value,
+			rating.CR.HalfWidth(), rating.Sims, optimalRange, condCR, stratCR, terrCR, scenCR, subs}
+	}
+}
+
+func (r simResult) String() string {
+	return fmt.Sprintf("%s: off=%.1f def=%.1f CR=%.2f ± %.2f (%d sims)", r.Name, r.Offense, r.Defense, r.Score, r.ScoreCI, r.Sims)
+}
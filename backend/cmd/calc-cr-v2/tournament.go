@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ─── Tournament mode ────────────────────────────────────────────────────────
+//
+// -tournament plays a (sampled) round-robin of mutual-fire duels among a
+// pool of variants and stores the Elo ratings fitted to it, with every
+// pairing's record, under the pool's name. Running a pool again replaces it.
+
+// tournamentConfig is what -tournament was asked to play.
+type tournamentConfig struct {
+	name      string
+	opponents int
+	duels     int
+	workers   int
+	seed      int64
+}
+
+// loadPoolIDs returns the IDs of the variants with a BV of at most bvMax
+// (0 = any) that are available in era (empty = any), picked as the mech
+// list's bv_max and era filters pick them.
+func loadPoolIDs(ctx context.Context, pool *pgxpool.Pool, bvMax int, era string) (map[int]bool, error) {
+	if era != "" {
+		var exists bool
+		if err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM eras WHERE name = $1)`, era).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("unknown era %q", era)
+		}
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT v.id FROM variants v
+		WHERE v.mul_id IS NOT NULL AND v.mul_id > 0 AND v.battle_value > 0
+		  AND ($1::int = 0 OR v.battle_value <= $1::int)
+		  AND ($2::text = '' OR v.intro_year <= (SELECT end_year FROM eras WHERE name = $2::text))`, bvMax, era)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// runTournament plays the tournament among variants, saves it and prints
+// the standings.
+func runTournament(ctx context.Context, pool *pgxpool.Pool, tc tournamentConfig, variants []sim.Variant, boards []*sim.Board, mtfs map[string]*ingestion.MTFData) error {
+	mechs := make([]*sim.MechState, len(variants))
+	for i := range variants {
+		mechs[i] = sim.BuildMechState(&variants[i], sim.LookupMTF(mtfs, &variants[i]))
+	}
+
+	log.Printf("Tournament %q: %d variants, %d opponents each (0 = all), %d duels per pairing, seed %d",
+		tc.name, len(variants), tc.opponents, tc.duels, tc.seed)
+	start := time.Now()
+	res, err := sim.RunTournament(ctx, sim.TournamentConfig{
+		Boards:    boards,
+		Mechs:     mechs,
+		Opponents: tc.opponents,
+		Duels:     tc.duels,
+		Seed:      uint64(tc.seed),
+		Workers:   tc.workers,
+		Progress: func(done, total int) {
+			if done%500 == 0 || done == total {
+				log.Printf("  Played %d/%d pairings", done, total)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Played %d pairings in %s", len(res.Matchups), time.Since(start).Round(time.Second))
+
+	standings := tournamentStandings(variants, res)
+	if err := saveTournament(ctx, pool, tc.name, standings, res.Matchups, variants); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	sort.Slice(standings, func(i, j int) bool { return standings[i].elo > standings[j].elo })
+	fmt.Printf("\n%-35s %7s %6s %6s %6s %6s\n", "Mech", "Elo", "Games", "Won", "Lost", "Drawn")
+	for i, s := range standings {
+		if i == 25 {
+			fmt.Printf("... %d more\n", len(standings)-i)
+			break
+		}
+		fmt.Printf("%-35s %7.0f %6d %6d %6d %6d\n", s.name, s.elo, s.wins+s.losses+s.draws, s.wins, s.losses, s.draws)
+	}
+	return nil
+}
+
+// standing is one variant's tournament totals.
+type standing struct {
+	id                  int
+	name                string
+	elo                 float64
+	wins, losses, draws int
+}
+
+func tournamentStandings(variants []sim.Variant, res *sim.TournamentResult) []standing {
+	standings := make([]standing, len(variants))
+	for i, v := range variants {
+		standings[i] = standing{id: v.ID, name: v.Name + " " + v.ModelCode, elo: res.Elo[i]}
+	}
+	for _, m := range res.Matchups {
+		a, b := &standings[m.A], &standings[m.B]
+		a.wins, a.losses, a.draws = a.wins+m.AWins, a.losses+m.BWins, a.draws+m.Draws
+		b.wins, b.losses, b.draws = b.wins+m.BWins, b.losses+m.AWins, b.draws+m.Draws
+	}
+	return standings
+}
+
+// saveTournament replaces the pool's ratings and matchups in one
+// transaction.
+func saveTournament(ctx context.Context, pool *pgxpool.Pool, name string, standings []standing, matchups []sim.Matchup, variants []sim.Variant) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM tournament_matchups WHERE pool = $1`, name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM tournament_ratings WHERE pool = $1`, name); err != nil {
+			return err
+		}
+
+		ratings := make([][]any, len(standings))
+		for i, s := range standings {
+			ratings[i] = []any{name, s.id, s.elo, s.wins + s.losses + s.draws, s.wins, s.losses, s.draws}
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"tournament_ratings"},
+			[]string{"pool", "variant_id", "elo", "games", "wins", "losses", "draws"},
+			pgx.CopyFromRows(ratings)); err != nil {
+			return fmt.Errorf("ratings: %w", err)
+		}
+
+		rows := make([][]any, 0, 2*len(matchups))
+		for _, m := range matchups {
+			a, b := variants[m.A].ID, variants[m.B].ID
+			rows = append(rows,
+				[]any{name, a, b, m.AWins, m.BWins, m.Draws},
+				[]any{name, b, a, m.BWins, m.AWins, m.Draws})
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"tournament_matchups"},
+			[]string{"pool", "variant_id", "opponent_id", "wins", "losses", "draws"},
+			pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("matchups: %w", err)
+		}
+		return nil
+	})
+}
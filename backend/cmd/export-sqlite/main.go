@@ -94,7 +94,9 @@ func main() {
 			has_targeting_computer INTEGER DEFAULT 0,
 			combat_rating REAL DEFAULT 0,
 			offense_turns REAL DEFAULT 0,
-			defense_turns REAL DEFAULT 0
+			defense_turns REAL DEFAULT 0,
+			combat_rating_ci REAL DEFAULT 0,
+			run_id INTEGER
 		)`,
 		`CREATE TABLE equipment (
 			id INTEGER PRIMARY KEY,
@@ -188,6 +190,98 @@ func main() {
 		)`,
 		`CREATE INDEX idx_external_ratings_variant ON external_ratings(variant_id)`,
 		`CREATE INDEX idx_external_ratings_source ON external_ratings(source)`,
+		`CREATE TABLE variant_quirks (
+			id INTEGER PRIMARY KEY,
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			quirk TEXT NOT NULL,
+			location TEXT,
+			weapon TEXT
+		)`,
+		`CREATE INDEX idx_variant_quirks_variant ON variant_quirks(variant_id)`,
+		`CREATE TABLE variant_condition_ratings (
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			condition TEXT NOT NULL,
+			combat_rating REAL NOT NULL,
+			PRIMARY KEY (variant_id, condition)
+		)`,
+		`CREATE INDEX idx_variant_condition_ratings_condition ON variant_condition_ratings(condition, combat_rating)`,
+		`CREATE TABLE variant_strategy_ratings (
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			strategy TEXT NOT NULL,
+			combat_rating REAL NOT NULL,
+			PRIMARY KEY (variant_id, strategy)
+		)`,
+		`CREATE INDEX idx_variant_strategy_ratings_strategy ON variant_strategy_ratings(strategy, combat_rating)`,
+		`CREATE TABLE variant_terrain_ratings (
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			terrain TEXT NOT NULL,
+			combat_rating REAL NOT NULL,
+			PRIMARY KEY (variant_id, terrain)
+		)`,
+		`CREATE INDEX idx_variant_terrain_ratings_terrain ON variant_terrain_ratings(terrain, combat_rating)`,
+		`CREATE TABLE variant_scenario_ratings (
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			scenario TEXT NOT NULL,
+			combat_rating REAL NOT NULL,
+			PRIMARY KEY (variant_id, scenario)
+		)`,
+		`CREATE INDEX idx_variant_scenario_ratings_scenario ON variant_scenario_ratings(scenario, combat_rating)`,
+		`CREATE TABLE sim_runs (
+			id INTEGER PRIMARY KEY,
+			engine_version TEXT NOT NULL,
+			git_sha TEXT NOT NULL DEFAULT '',
+			seed INTEGER NOT NULL,
+			board_hash TEXT NOT NULL,
+			mtf_hash TEXT NOT NULL,
+			max_turns INTEGER NOT NULL,
+			k_factor REAL NOT NULL,
+			gunnery INTEGER NOT NULL,
+			piloting INTEGER NOT NULL,
+			board_pairs INTEGER NOT NULL,
+			sims_per_board INTEGER NOT NULL,
+			ci_width REAL NOT NULL,
+			ci_max_sims INTEGER NOT NULL,
+			ci_budget_ms INTEGER NOT NULL,
+			baselines TEXT NOT NULL DEFAULT '',
+			conditions TEXT NOT NULL DEFAULT '',
+			strategies TEXT NOT NULL DEFAULT '',
+			terrains TEXT NOT NULL DEFAULT '',
+			scenarios TEXT NOT NULL DEFAULT '',
+			args TEXT NOT NULL DEFAULT '',
+			variants INTEGER NOT NULL DEFAULT 0,
+			started_at TEXT NOT NULL,
+			finished_at TEXT
+		)`,
+		`CREATE TABLE variant_baseline_ratings (
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			baseline_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			weight REAL NOT NULL,
+			combat_rating REAL NOT NULL,
+			combat_rating_ci REAL NOT NULL DEFAULT 0,
+			offense_turns REAL NOT NULL,
+			defense_turns REAL NOT NULL,
+			run_id INTEGER,
+			PRIMARY KEY (variant_id, baseline_id)
+		)`,
+		`CREATE TABLE tournament_ratings (
+			pool TEXT NOT NULL,
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			elo REAL NOT NULL,
+			games INTEGER NOT NULL,
+			wins INTEGER NOT NULL,
+			losses INTEGER NOT NULL,
+			draws INTEGER NOT NULL,
+			PRIMARY KEY (pool, variant_id)
+		)`,
+		`CREATE TABLE tournament_matchups (
+			pool TEXT NOT NULL,
+			variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			opponent_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+			wins INTEGER NOT NULL,
+			losses INTEGER NOT NULL,
+			draws INTEGER NOT NULL,
+			PRIMARY KEY (pool, variant_id, opponent_id)
+		)`,
 		// Indexes
 		`CREATE INDEX idx_variants_chassis ON variants(chassis_id)`,
 		`CREATE INDEX idx_variants_intro_year ON variants(intro_year)`,
@@ -215,15 +309,17 @@ func main() {
 		        cockpit_type, gyro_type, myomer_type, structure_type, armor_type,
 		        tmm, armor_coverage_pct, heat_neutral_damage, heat_neutral_range,
 		        max_damage, effective_heat_neutral_damage, tonnage, game_damage,
-		        has_targeting_computer, combat_rating, offense_turns, defense_turns
+		        has_targeting_computer, combat_rating, offense_turns, defense_turns,
+		        COALESCE(combat_rating_ci, 0), run_id
 		 FROM variant_stats`,
 		`INSERT INTO variant_stats (variant_id, walk_mp, run_mp, jump_mp, armor_total, internal_structure_total,
 		        heat_sink_count, heat_sink_type, engine_type, engine_rating,
 		        cockpit_type, gyro_type, myomer_type, structure_type, armor_type,
 		        tmm, armor_coverage_pct, heat_neutral_damage, heat_neutral_range,
 		        max_damage, effective_heat_neutral_damage, tonnage, game_damage,
-		        has_targeting_computer, combat_rating, offense_turns, defense_turns)
-		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, 27)
+		        has_targeting_computer, combat_rating, offense_turns, defense_turns,
+		        combat_rating_ci, run_id)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, 29)
 
 	copyTable(ctx, pg, sl, "equipment",
 		`SELECT id, name, type, damage, heat, min_range, short_range, medium_range, long_range,
@@ -271,6 +367,48 @@ func main() {
 		"SELECT id, variant_id, source, COALESCE(rating,''), COALESCE(url,''), COALESCE(notes,''), COALESCE(updated_at::text,'') FROM external_ratings",
 		"INSERT INTO external_ratings (id, variant_id, source, rating, url, notes, updated_at) VALUES (?,?,?,?,?,?,?)", 7)
 
+	copyTable(ctx, pg, sl, "variant_quirks",
+		"SELECT id, variant_id, quirk, location, weapon FROM variant_quirks",
+		"INSERT INTO variant_quirks (id, variant_id, quirk, location, weapon) VALUES (?,?,?,?,?)", 5)
+
+	copyTable(ctx, pg, sl, "variant_condition_ratings",
+		"SELECT variant_id, condition, combat_rating FROM variant_condition_ratings",
+		"INSERT INTO variant_condition_ratings (variant_id, condition, combat_rating) VALUES (?,?,?)", 3)
+
+	copyTable(ctx, pg, sl, "variant_strategy_ratings",
+		"SELECT variant_id, strategy, combat_rating FROM variant_strategy_ratings",
+		"INSERT INTO variant_strategy_ratings (variant_id, strategy, combat_rating) VALUES (?,?,?)", 3)
+
+	copyTable(ctx, pg, sl, "variant_terrain_ratings",
+		"SELECT variant_id, terrain, combat_rating FROM variant_terrain_ratings",
+		"INSERT INTO variant_terrain_ratings (variant_id, terrain, combat_rating) VALUES (?,?,?)", 3)
+
+	copyTable(ctx, pg, sl, "variant_scenario_ratings",
+		"SELECT variant_id, scenario, combat_rating FROM variant_scenario_ratings",
+		"INSERT INTO variant_scenario_ratings (variant_id, scenario, combat_rating) VALUES (?,?,?)", 3)
+
+	copyTable(ctx, pg, sl, "sim_runs",
+		`SELECT id, engine_version, git_sha, seed, board_hash, mtf_hash, max_turns, k_factor,
+		        gunnery, piloting, board_pairs, sims_per_board, ci_width, ci_max_sims, ci_budget_ms,
+		        baselines, conditions, strategies, terrains, scenarios, args, variants, started_at::text, finished_at::text
+		 FROM sim_runs`,
+		`INSERT INTO sim_runs (id, engine_version, git_sha, seed, board_hash, mtf_hash, max_turns, k_factor,
+		        gunnery, piloting, board_pairs, sims_per_board, ci_width, ci_max_sims, ci_budget_ms,
+		        baselines, conditions, strategies, terrains, scenarios, args, variants, started_at, finished_at)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, 24)
+
+	copyTable(ctx, pg, sl, "variant_baseline_ratings",
+		"SELECT variant_id, baseline_id, weight, combat_rating, combat_rating_ci, offense_turns, defense_turns, run_id FROM variant_baseline_ratings",
+		"INSERT INTO variant_baseline_ratings (variant_id, baseline_id, weight, combat_rating, combat_rating_ci, offense_turns, defense_turns, run_id) VALUES (?,?,?,?,?,?,?,?)", 8)
+
+	copyTable(ctx, pg, sl, "tournament_ratings",
+		"SELECT pool, variant_id, elo, games, wins, losses, draws FROM tournament_ratings",
+		"INSERT INTO tournament_ratings (pool, variant_id, elo, games, wins, losses, draws) VALUES (?,?,?,?,?,?,?)", 7)
+
+	copyTable(ctx, pg, sl, "tournament_matchups",
+		"SELECT pool, variant_id, opponent_id, wins, losses, draws FROM tournament_matchups",
+		"INSERT INTO tournament_matchups (pool, variant_id, opponent_id, wins, losses, draws) VALUES (?,?,?,?,?,?)", 6)
+
 	log.Println("Export complete!")
 }
 
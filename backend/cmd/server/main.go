@@ -17,6 +17,8 @@ import (
 	"github.com/JustinWhittecar/slic/internal/customerio"
 	"github.com/JustinWhittecar/slic/internal/db"
 	"github.com/JustinWhittecar/slic/internal/handlers"
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/sim"
 )
 
 func main() {
@@ -57,7 +59,31 @@ func main() {
 	}
 	cioClient := customerio.New(cioSiteID, cioAPIKey, cioAppAPIKey)
 
-	mechHandler := &handlers.MechHandlerSQLite{DB: sqlDB}
+	// Simulator: MegaMek boards and MTFs are optional
+	var simBoards []*sim.Board
+	if dir := os.Getenv("SLIC_BOARD_DIR"); dir != "" {
+		simBoards, err = sim.LoadBoardPool(dir)
+		if err != nil {
+			log.Printf("[WARN] loading boards from %s: %v", dir, err)
+		}
+	}
+	if len(simBoards) == 0 {
+		log.Println("[WARN] no board pool (SLIC_BOARD_DIR); duels run on generated boards")
+		var profiles []sim.BoardProfile
+		for _, key := range sim.BoardProfileKeys() {
+			profiles = append(profiles, sim.BoardProfiles[key])
+		}
+		simBoards = sim.GenerateBoardPool(profiles, 16, 1)
+	}
+	var simMTFs map[string]*ingestion.MTFData
+	if dir := os.Getenv("SLIC_MTF_DIR"); dir != "" {
+		simMTFs, err = sim.LoadMTFs(dir)
+		if err != nil {
+			log.Printf("[WARN] loading MTFs from %s: %v", dir, err)
+		}
+	}
+
+	mechHandler := handlers.NewMechHandlerSQLite(sqlDB)
 	feedbackHandler := handlers.NewFeedbackHandler(cioClient)
 	authHandler := handlers.NewAuthHandler(userDB)
 	authHandler.CIO = cioClient
@@ -69,6 +95,8 @@ func main() {
 	replayHandler := &handlers.ReplayHandler{DB: sqlDB}
 	recommendationsHandler := &handlers.RecommendationsHandler{DB: sqlDB}
 	equipmentHandler := &handlers.EquipmentHandler{DB: sqlDB}
+	simHandler := handlers.NewSimHandler(sqlDB, simBoards, simMTFs)
+	simHandler.UserDB = userDB
 
 	mux := http.NewServeMux()
 
@@ -81,6 +109,7 @@ func main() {
 	// Mech API
 	mux.HandleFunc("GET /api/mechs", mechHandler.List)
 	mux.HandleFunc("GET /api/mechs/{id}", mechHandler.GetByID)
+	mux.HandleFunc("GET /api/mechs/{id}/matchups", mechHandler.Matchups)
 
 	// Recommendations
 	mux.HandleFunc("GET /api/recommendations", recommendationsHandler.Recommend)
@@ -124,6 +153,12 @@ func main() {
 
 	// Replays
 	mux.HandleFunc("GET /api/variants/{id}/replay", replayHandler.GetReplay)
+	mux.HandleFunc("GET /api/variants/{id}/replay/stream", replayHandler.StreamReplay)
+	mux.HandleFunc("GET /api/variants/{id}/replay/analysis", replayHandler.Analysis)
+
+	// On-demand simulation
+	mux.HandleFunc("POST /api/sim/duel", simHandler.Duel)
+	mux.HandleFunc("POST /api/sim/lists", simHandler.ListMatchup)
 
 	// Shared lists (public)
 	mux.HandleFunc("GET /api/shared/{shareCode}", listsHandler.SharedView)
@@ -90,3 +90,21 @@ func TestISPoints(t *testing.T) {
 		t.Errorf("IS points for 100 tons = %d, want 171", ISPointsByTonnage[100])
 	}
 }
+
+func TestSkillAdjustedBV(t *testing.T) {
+	tests := []struct {
+		bv, g, p int
+		want     int
+	}{
+		{1000, 4, 5, 1000},
+		{1000, 3, 4, 1200},
+		{1000, 5, 6, 810},
+		{1000, 9, -1, 910}, // clamped to 8/0
+	}
+	for _, tt := range tests {
+		got := SkillAdjustedBV(tt.bv, tt.g, tt.p)
+		if got != tt.want {
+			t.Errorf("SkillAdjustedBV(%d,%d,%d) = %d, want %d", tt.bv, tt.g, tt.p, got, tt.want)
+		}
+	}
+}
@@ -198,3 +198,26 @@ func toLower(s string) string {
 	}
 	return string(b)
 }
+
+// skillMultipliers is the pilot skill BV multiplier table, indexed
+// [gunnery][piloting] and normalized so a 4/5 pilot is 1.0. It mirrors
+// BV_TABLE in the frontend's ListBuilder.
+var skillMultipliers = [9][9]float64{
+	// P: 0     1     2     3     4     5     6     7     8
+	{1.94, 1.85, 1.77, 1.68, 1.54, 1.40, 1.34, 1.27, 1.20}, // G0
+	{1.77, 1.69, 1.62, 1.54, 1.41, 1.28, 1.23, 1.17, 1.10}, // G1
+	{1.64, 1.57, 1.50, 1.43, 1.31, 1.20, 1.15, 1.10, 1.04}, // G2
+	{1.48, 1.42, 1.37, 1.31, 1.20, 1.08, 1.04, 0.99, 0.94}, // G3
+	{1.37, 1.31, 1.26, 1.21, 1.11, 1.00, 0.90, 0.81, 0.72}, // G4
+	{1.22, 1.17, 1.12, 1.07, 0.99, 0.90, 0.81, 0.72, 0.64}, // G5
+	{1.10, 1.06, 1.02, 0.98, 0.90, 0.80, 0.72, 0.65, 0.58}, // G6
+	{1.01, 0.97, 0.93, 0.90, 0.82, 0.74, 0.67, 0.61, 0.54}, // G7
+	{0.91, 0.88, 0.85, 0.81, 0.75, 0.69, 0.62, 0.57, 0.51}, // G8
+}
+
+// SkillAdjustedBV scales a 4/5-pilot BV to the given pilot skills
+func SkillAdjustedBV(bv, gunnery, piloting int) int {
+	g := min(max(gunnery, 0), 8)
+	p := min(max(piloting, 0), 8)
+	return int(math.Round(float64(bv) * skillMultipliers[g][p]))
+}
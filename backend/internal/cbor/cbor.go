@@ -0,0 +1,595 @@
+// Package cbor encodes and decodes the subset of CBOR (RFC 8949) that slic
+// stores and serves: integers, floats, strings, byte strings, booleans,
+// null, arrays and maps. Structs map to CBOR maps keyed by their json tag
+// names, honouring "-" and omitempty, so a type encodes to the same shape
+// in CBOR as in JSON. Indefinite-length items and tags are not supported.
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Major types
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorSimple = 7
+)
+
+// maxItems caps the length of a decoded string, array or map, so a corrupt
+// header cannot make the decoder allocate gigabytes.
+const maxItems = 1 << 24
+
+// Marshal returns the CBOR encoding of v.
+func Marshal(v any) ([]byte, error) {
+	e := &encoder{}
+	if err := e.value(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes one CBOR data item from data into v, which must be a
+// non-nil pointer. Trailing bytes are an error.
+func Unmarshal(data []byte, v any) error {
+	d := NewDecoder(bytes.NewReader(data))
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return errors.New("cbor: trailing data")
+	}
+	return nil
+}
+
+// Encoder writes a CBOR sequence (RFC 8742): data items back to back.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the encoding of v as the next item in the sequence.
+func (enc *Encoder) Encode(v any) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(b)
+	return err
+}
+
+// Decoder reads a CBOR sequence item by item.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next item into v, a non-nil pointer. It returns io.EOF
+// at the clean end of the sequence.
+func (dec *Decoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cbor: Decode needs a non-nil pointer, got %T", v)
+	}
+	if _, err := dec.r.Peek(1); err != nil {
+		return err
+	}
+	err := dec.value(rv.Elem())
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ─── Struct fields ──────────────────────────────────────────────────────────
+
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type → []field
+
+// fields lists t's exported fields by their json names.
+func fields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fs = append(fs, field{name, i, strings.Contains(","+opts+",", ",omitempty,")})
+	}
+	fieldCache.Store(t, fs)
+	return fs
+}
+
+// isEmpty is encoding/json's notion of an empty value for omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ─── Encoding ───────────────────────────────────────────────────────────────
+
+type encoder struct {
+	buf []byte
+}
+
+// head writes an item head: major type and argument in the shortest form.
+func (e *encoder) head(major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, m|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, m|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, m|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, m|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func (e *encoder) int(i int64) {
+	if i < 0 {
+		e.head(majorNegInt, uint64(-1-i))
+	} else {
+		e.head(majorUint, uint64(i))
+	}
+}
+
+// float writes f as a single when that loses nothing, else as a double.
+func (e *encoder) float(f float64) {
+	if f32 := float32(f); float64(f32) == f || math.IsNaN(f) {
+		b := math.Float32bits(f32)
+		e.buf = append(e.buf, majorSimple<<5|26, byte(b>>24), byte(b>>16), byte(b>>8), byte(b))
+		return
+	}
+	b := math.Float64bits(f)
+	e.buf = append(e.buf, majorSimple<<5|27, byte(b>>56), byte(b>>48), byte(b>>40), byte(b>>32),
+		byte(b>>24), byte(b>>16), byte(b>>8), byte(b))
+}
+
+func (e *encoder) null() {
+	e.buf = append(e.buf, majorSimple<<5|22)
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		e.null()
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, majorSimple<<5|21)
+		} else {
+			e.buf = append(e.buf, majorSimple<<5|20)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(majorUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.float(v.Float())
+	case reflect.String:
+		e.head(majorText, uint64(v.Len()))
+		e.buf = append(e.buf, v.String()...)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		return e.value(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.head(majorBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.head(majorArray, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cbor: unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		e.head(majorMap, uint64(len(keys)))
+		for _, k := range keys {
+			e.head(majorText, uint64(k.Len()))
+			e.buf = append(e.buf, k.String()...)
+			if err := e.value(v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fs := fields(v.Type())
+		n := 0
+		for _, f := range fs {
+			if !f.omitEmpty || !isEmpty(v.Field(f.index)) {
+				n++
+			}
+		}
+		e.head(majorMap, uint64(n))
+		for _, f := range fs {
+			fv := v.Field(f.index)
+			if f.omitEmpty && isEmpty(fv) {
+				continue
+			}
+			e.head(majorText, uint64(len(f.name)))
+			e.buf = append(e.buf, f.name...)
+			if err := e.value(fv); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// ─── Decoding ───────────────────────────────────────────────────────────────
+
+// head reads an item head. For simple values and floats the argument is
+// the raw bits.
+func (dec *Decoder) head() (major byte, info byte, n uint64, err error) {
+	b, err := dec.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		for i := 0; i < size; i++ {
+			c, err := dec.r.ReadByte()
+			if err != nil {
+				return 0, 0, 0, err
+			}
+			n = n<<8 | uint64(c)
+		}
+		return major, info, n, nil
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: unsupported additional info %d (major type %d)", info, major)
+}
+
+func (dec *Decoder) bytes(n uint64) ([]byte, error) {
+	if n > maxItems {
+		return nil, fmt.Errorf("cbor: string of %d bytes", n)
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(dec.r, b)
+	return b, err
+}
+
+// length checks an array or map length.
+func length(n uint64) (int, error) {
+	if n > maxItems {
+		return 0, fmt.Errorf("cbor: %d items", n)
+	}
+	return int(n), nil
+}
+
+// float decodes a half, single or double from its bits.
+func float(info byte, bits uint64) float64 {
+	switch info {
+	case 25:
+		// Half precision (RFC 8949 appendix D)
+		exp, mant := (bits>>10)&0x1f, float64(bits&0x3ff)
+		var f float64
+		switch exp {
+		case 0:
+			f = math.Ldexp(mant, -24)
+		case 31:
+			f = math.Inf(1)
+			if mant != 0 {
+				f = math.NaN()
+			}
+		default:
+			f = math.Ldexp(mant+1024, int(exp)-25)
+		}
+		if bits&0x8000 != 0 {
+			f = -f
+		}
+		return f
+	case 26:
+		return float64(math.Float32frombits(uint32(bits)))
+	}
+	return math.Float64frombits(bits)
+}
+
+// value decodes the next item into v. Items that do not fit v's type are
+// an error, except that map keys v has no field for are skipped.
+func (dec *Decoder) value(v reflect.Value) error {
+	major, info, n, err := dec.head()
+	if err != nil {
+		return err
+	}
+	return dec.decodeHead(v, major, info, n)
+}
+
+// decodeHead decodes an item whose head has been read into v. Null sets v
+// to its zero value.
+func (dec *Decoder) decodeHead(v reflect.Value, major, info byte, n uint64) error {
+	if major == majorSimple && info == 22 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return dec.decodeHead(v.Elem(), major, info, n)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("cbor: cannot decode into %s", v.Type())
+		}
+		x, err := dec.generic(major, info, n)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("cbor: cannot decode major type %d into %s", major, v.Type())
+	}
+
+	switch major {
+	case majorUint, majorNegInt:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n > math.MaxInt64 {
+				return fmt.Errorf("cbor: %d overflows %s", n, v.Type())
+			}
+			i := int64(n)
+			if major == majorNegInt {
+				i = -1 - i
+			}
+			if v.OverflowInt(i) {
+				return fmt.Errorf("cbor: %d overflows %s", i, v.Type())
+			}
+			v.SetInt(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if major == majorNegInt || v.OverflowUint(n) {
+				return fmt.Errorf("cbor: integer overflows %s", v.Type())
+			}
+			v.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			f := float64(n)
+			if major == majorNegInt {
+				f = -1 - f
+			}
+			v.SetFloat(f)
+		default:
+			return mismatch()
+		}
+	case majorBytes, majorText:
+		b, err := dec.bytes(n)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(b)
+		default:
+			return mismatch()
+		}
+	case majorArray:
+		size, err := length(n)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(v.Type(), size, size)
+			for i := 0; i < size; i++ {
+				if err := dec.value(s.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+		case reflect.Array:
+			if size != v.Len() {
+				return fmt.Errorf("cbor: %d items for %s", size, v.Type())
+			}
+			for i := 0; i < size; i++ {
+				if err := dec.value(v.Index(i)); err != nil {
+					return err
+				}
+			}
+		default:
+			return mismatch()
+		}
+	case majorMap:
+		size, err := length(n)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return mismatch()
+			}
+			m := reflect.MakeMapWithSize(v.Type(), size)
+			for i := 0; i < size; i++ {
+				var key string
+				if err := dec.value(reflect.ValueOf(&key).Elem()); err != nil {
+					return err
+				}
+				ev := reflect.New(v.Type().Elem()).Elem()
+				if err := dec.value(ev); err != nil {
+					return err
+				}
+				m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), ev)
+			}
+			v.Set(m)
+		case reflect.Struct:
+			fs := fields(v.Type())
+			v.Set(reflect.Zero(v.Type()))
+			for i := 0; i < size; i++ {
+				var key string
+				if err := dec.value(reflect.ValueOf(&key).Elem()); err != nil {
+					return err
+				}
+				fv := reflect.Value{}
+				for _, f := range fs {
+					if f.name == key {
+						fv = v.Field(f.index)
+						break
+					}
+				}
+				if !fv.IsValid() {
+					var skip any
+					fv = reflect.ValueOf(&skip).Elem()
+				}
+				if err := dec.value(fv); err != nil {
+					return err
+				}
+			}
+		default:
+			return mismatch()
+		}
+	case majorSimple:
+		switch {
+		case info == 20 || info == 21:
+			if v.Kind() != reflect.Bool {
+				return mismatch()
+			}
+			v.SetBool(info == 21)
+		case info >= 25 && info <= 27:
+			if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+				return mismatch()
+			}
+			v.SetFloat(float(info, n))
+		case info == 23:
+			v.Set(reflect.Zero(v.Type())) // undefined
+		default:
+			return fmt.Errorf("cbor: unsupported simple value %d", n)
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported major type %d", major)
+	}
+	return nil
+}
+
+// generic decodes an item whose head has been read as the types
+// encoding/json would use for an interface: map[string]any, []any, float64
+// for floats, int64 or uint64 for integers, string, []byte, bool or nil.
+func (dec *Decoder) generic(major, info byte, n uint64) (any, error) {
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		return dec.bytes(n)
+	case majorText:
+		b, err := dec.bytes(n)
+		return string(b), err
+	case majorArray:
+		size, err := length(n)
+		if err != nil {
+			return nil, err
+		}
+		a := make([]any, size)
+		for i := range a {
+			if err := dec.value(reflect.ValueOf(&a[i]).Elem()); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case majorMap:
+		var m map[string]any
+		err := dec.decodeHead(reflect.ValueOf(&m).Elem(), major, info, n)
+		return m, err
+	case majorSimple:
+		switch {
+		case info == 20 || info == 21:
+			return info == 21, nil
+		case info == 22 || info == 23:
+			return nil, nil
+		case info >= 25 && info <= 27:
+			return float(info, n), nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+	}
+	return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
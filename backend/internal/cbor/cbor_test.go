@@ -0,0 +1,126 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	// The examples of RFC 8949 appendix A, except that floats are written
+	// as singles rather than halves
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.5, "fa3fc00000"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]string{"b": "B", "a": "A"}, "a26161614161626142"},
+	}
+	for _, tt := range tests {
+		b, err := Marshal(tt.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(b); got != tt.want {
+			t.Errorf("Marshal(%v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+
+	// Half-precision floats decode though they are never written
+	var f float64
+	for in, want := range map[string]float64{"f93c00": 1, "f9c400": -4, "f97bff": 65504, "f90001": 5.960464477539063e-8, "f97c00": math.Inf(1)} {
+		b, _ := hex.DecodeString(in)
+		if err := Unmarshal(b, &f); err != nil || f != want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", in, f, err, want)
+		}
+	}
+}
+
+type inner struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score,omitempty"`
+}
+
+type outer struct {
+	ID      int            `json:"id"`
+	Flags   [3]bool        `json:"flags"`
+	Items   []inner        `json:"items,omitempty"`
+	Next    *inner         `json:"next,omitempty"`
+	Counts  map[string]int `json:"counts"`
+	Skipped string         `json:"-"`
+	Any     any            `json:"any"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := outer{
+		ID:     -42,
+		Flags:  [3]bool{true, false, true},
+		Items:  []inner{{"a", 0.25}, {"b", 0}},
+		Next:   &inner{Name: "n"},
+		Counts: map[string]int{"x": 1, "y": 1 << 40},
+		Any:    map[string]any{"k": []any{int64(1), "two", 3.5, nil}},
+	}
+	b, err := Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got outer
+	if err := Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+	}
+
+	// Unknown keys are skipped, so old readers survive new fields
+	b, _ = Marshal(map[string]any{"name": "x", "added": []any{map[string]any{"deep": true}}})
+	var in inner
+	if err := Unmarshal(b, &in); err != nil || in.Name != "x" {
+		t.Errorf("with an unknown key: %+v, %v", in, err)
+	}
+
+	// A sequence decodes item by item and ends with io.EOF
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i := 0; i < 3; i++ {
+		enc.Encode(inner{Name: "seq", Score: float64(i)})
+	}
+	dec := NewDecoder(&buf)
+	n := 0
+	for ; ; n++ {
+		var x inner
+		if err := dec.Decode(&x); err == io.EOF {
+			break
+		} else if err != nil || x.Score != float64(n) {
+			t.Fatalf("item %d: %+v, %v", n, x, err)
+		}
+	}
+	if n != 3 {
+		t.Errorf("decoded %d items, want 3", n)
+	}
+
+	// Truncated and mistyped input are errors
+	if err := Unmarshal(b[:len(b)-2], &in); err == nil {
+		t.Error("truncated input accepted")
+	}
+	if err := Unmarshal([]byte{0x63, 'a', 'b', 'c'}, &got); err == nil {
+		t.Error("a string decoded into a struct")
+	}
+}
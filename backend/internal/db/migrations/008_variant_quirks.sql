@@ -0,0 +1,11 @@
+-- Design quirks from the MTF quirk: and weaponquirk: lines.
+-- location/weapon are NULL for unit quirks.
+CREATE TABLE IF NOT EXISTS variant_quirks (
+    id SERIAL PRIMARY KEY,
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    quirk TEXT NOT NULL,
+    location TEXT,
+    weapon TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_quirks_variant ON variant_quirks(variant_id);
@@ -0,0 +1,10 @@
+-- Combat rating under each environmental condition preset (sim.ConditionPresets),
+-- written by calc-cr-v2 -conditions.
+CREATE TABLE IF NOT EXISTS variant_condition_ratings (
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    condition TEXT NOT NULL,
+    combat_rating REAL NOT NULL,
+    PRIMARY KEY (variant_id, condition)
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_condition_ratings_condition ON variant_condition_ratings(condition, combat_rating);
@@ -0,0 +1,11 @@
+-- Combat rating playing each AI strategy (sim.Strategies) against the run's
+-- baseline panel (the built-in HBK-4P unless -baseline or -baseline-era picks
+-- one), whose mechs play as the Tactician. Written by calc-cr-v2 -strategies.
+CREATE TABLE IF NOT EXISTS variant_strategy_ratings (
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    strategy TEXT NOT NULL,
+    combat_rating REAL NOT NULL,
+    PRIMARY KEY (variant_id, strategy)
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_strategy_ratings_strategy ON variant_strategy_ratings(strategy, combat_rating);
@@ -0,0 +1,3 @@
+-- 95% confidence half-width of combat_rating ("CR 5.6 ± 0.3"), written by
+-- calc-cr-v2.
+ALTER TABLE variant_stats ADD COLUMN IF NOT EXISTS combat_rating_ci real DEFAULT 0;
@@ -0,0 +1,37 @@
+-- One row per calc-cr-v2 rating run: everything needed to reproduce it or
+-- explain why ratings moved between two runs.
+CREATE TABLE IF NOT EXISTS sim_runs (
+    id SERIAL PRIMARY KEY,
+    engine_version TEXT NOT NULL,
+    git_sha TEXT NOT NULL DEFAULT '',
+    seed BIGINT NOT NULL,
+    board_hash TEXT NOT NULL,
+    mtf_hash TEXT NOT NULL,
+    max_turns INTEGER NOT NULL,
+    k_factor REAL NOT NULL,
+    gunnery INTEGER NOT NULL,
+    piloting INTEGER NOT NULL,
+    board_pairs INTEGER NOT NULL,
+    sims_per_board INTEGER NOT NULL,
+    ci_width REAL NOT NULL,
+    ci_max_sims INTEGER NOT NULL,
+    ci_budget_ms BIGINT NOT NULL,
+    args TEXT NOT NULL DEFAULT '',
+    variants INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+-- Every rating a run produced, for calc-cr-v2 -compare.
+CREATE TABLE IF NOT EXISTS variant_run_ratings (
+    run_id INTEGER NOT NULL REFERENCES sim_runs(id) ON DELETE CASCADE,
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    combat_rating REAL NOT NULL,
+    combat_rating_ci REAL NOT NULL DEFAULT 0,
+    offense_turns REAL NOT NULL,
+    defense_turns REAL NOT NULL,
+    PRIMARY KEY (run_id, variant_id)
+);
+
+-- The run that wrote each variant's current combat rating.
+ALTER TABLE variant_stats ADD COLUMN IF NOT EXISTS run_id INTEGER REFERENCES sim_runs(id) ON DELETE SET NULL;
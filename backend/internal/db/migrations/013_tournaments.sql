@@ -0,0 +1,23 @@
+-- Elo ratings from calc-cr-v2 -tournament: round-robin duels within a named
+-- pool of variants, e.g. 'all' or everything under a BV cap in an era.
+CREATE TABLE IF NOT EXISTS tournament_ratings (
+    pool TEXT NOT NULL,
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    elo REAL NOT NULL,
+    games INTEGER NOT NULL,
+    wins INTEGER NOT NULL,
+    losses INTEGER NOT NULL,
+    draws INTEGER NOT NULL,
+    PRIMARY KEY (pool, variant_id)
+);
+
+-- Each pairing's record, stored once from each side.
+CREATE TABLE IF NOT EXISTS tournament_matchups (
+    pool TEXT NOT NULL,
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    opponent_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    wins INTEGER NOT NULL,
+    losses INTEGER NOT NULL,
+    draws INTEGER NOT NULL,
+    PRIMARY KEY (pool, variant_id, opponent_id)
+);
@@ -0,0 +1,17 @@
+-- The baseline panel each rating run measured CRs against.
+ALTER TABLE sim_runs ADD COLUMN IF NOT EXISTS baselines TEXT NOT NULL DEFAULT '';
+UPDATE sim_runs SET baselines = 'Hunchback HBK-4P (built-in)' WHERE baselines = '';
+
+-- Each variant's CR against every baseline of the panel its combat_rating
+-- is the weighted mean of, written by calc-cr-v2.
+CREATE TABLE IF NOT EXISTS variant_baseline_ratings (
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    baseline_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    weight REAL NOT NULL,
+    combat_rating REAL NOT NULL,
+    combat_rating_ci REAL NOT NULL DEFAULT 0,
+    offense_turns REAL NOT NULL,
+    defense_turns REAL NOT NULL,
+    run_id INTEGER REFERENCES sim_runs(id) ON DELETE SET NULL,
+    PRIMARY KEY (variant_id, baseline_id)
+);
@@ -0,0 +1,10 @@
+-- Combat rating on the pool's boards of each terrain class (open, mixed,
+-- dense, urban; sim.TerrainClassKeys), written by calc-cr-v2 -terrain.
+CREATE TABLE IF NOT EXISTS variant_terrain_ratings (
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    terrain TEXT NOT NULL,
+    combat_rating REAL NOT NULL,
+    PRIMARY KEY (variant_id, terrain)
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_terrain_ratings_terrain ON variant_terrain_ratings(terrain, combat_rating);
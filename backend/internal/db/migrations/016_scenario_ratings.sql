@@ -0,0 +1,11 @@
+-- Combat rating playing each scenario (destruction, hold, breakthrough,
+-- ambush, recon, escort; sim.ScenarioKeys), written by calc-cr-v2
+-- -scenarios. 5.0 is an even record against the baseline panel.
+CREATE TABLE IF NOT EXISTS variant_scenario_ratings (
+    variant_id INTEGER NOT NULL REFERENCES variants(id) ON DELETE CASCADE,
+    scenario TEXT NOT NULL,
+    combat_rating REAL NOT NULL,
+    PRIMARY KEY (variant_id, scenario)
+);
+
+CREATE INDEX IF NOT EXISTS idx_variant_scenario_ratings_scenario ON variant_scenario_ratings(scenario, combat_rating);
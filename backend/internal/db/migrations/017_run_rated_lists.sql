@@ -0,0 +1,6 @@
+-- What else each rating run rated every variant under, so -resume can't
+-- mix ratings made with different lists.
+ALTER TABLE sim_runs ADD COLUMN IF NOT EXISTS conditions TEXT NOT NULL DEFAULT '';
+ALTER TABLE sim_runs ADD COLUMN IF NOT EXISTS strategies TEXT NOT NULL DEFAULT '';
+ALTER TABLE sim_runs ADD COLUMN IF NOT EXISTS terrains TEXT NOT NULL DEFAULT '';
+ALTER TABLE sim_runs ADD COLUMN IF NOT EXISTS scenarios TEXT NOT NULL DEFAULT '';
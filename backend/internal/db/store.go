@@ -84,6 +84,24 @@ func (s *Store) InsertVariantStats(ctx context.Context, tx pgx.Tx, variantID int
 	return err
 }
 
+func (s *Store) InsertVariantQuirks(ctx context.Context, tx pgx.Tx, variantID int, data *ingestion.MTFData) error {
+	for _, q := range data.Quirks {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO variant_quirks (variant_id, quirk) VALUES ($1, $2)`,
+			variantID, q); err != nil {
+			return err
+		}
+	}
+	for _, wq := range data.WeaponQuirks {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO variant_quirks (variant_id, quirk, location, weapon) VALUES ($1, $2, $3, $4)`,
+			variantID, wq.Quirk, wq.Location, wq.Weapon); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Store) IngestMTF(ctx context.Context, data *ingestion.MTFData) error {
 	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
@@ -105,6 +123,10 @@ func (s *Store) IngestMTF(ctx context.Context, data *ingestion.MTFData) error {
 		return fmt.Errorf("insert stats for %q: %w", data.FullName(), err)
 	}
 
+	if err := s.InsertVariantQuirks(ctx, tx, variantID, data); err != nil {
+		return fmt.Errorf("insert quirks for %q: %w", data.FullName(), err)
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -105,5 +105,15 @@ func ConnectUserDB(path string) (*sql.DB, error) {
 		}
 	}
 
+	// Migrate: special pilot abilities on list entries (comma-separated SPA keys)
+	var hasSPAs bool
+	db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('user_list_entries') WHERE name='spas'`).Scan(&hasSPAs)
+	if !hasSPAs {
+		if _, err := db.Exec(`ALTER TABLE user_list_entries ADD COLUMN spas TEXT NOT NULL DEFAULT ''`); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("add spas column: %w", err)
+		}
+	}
+
 	return db, nil
 }
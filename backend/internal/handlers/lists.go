@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/sim"
 )
 
 type ListsHandler struct {
@@ -24,10 +28,11 @@ type UserList struct {
 }
 
 type UserListEntry struct {
-	ID        int64 `json:"id"`
-	VariantID int   `json:"variant_id"`
-	Gunnery   int   `json:"gunnery"`
-	Piloting  int   `json:"piloting"`
+	ID        int64    `json:"id"`
+	VariantID int      `json:"variant_id"`
+	Gunnery   int      `json:"gunnery"`
+	Piloting  int      `json:"piloting"`
+	SPAs      []string `json:"spas,omitempty"` // sim.ParseSPA keys, e.g. "sniper"
 }
 
 func (h *ListsHandler) ListAll(w http.ResponseWriter, r *http.Request) {
@@ -106,15 +111,7 @@ func (h *ListsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Load entries
-	rows, err := h.DB.Query(`SELECT id, variant_id, gunnery, piloting FROM user_list_entries WHERE list_id = ?`, id)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var e UserListEntry
-			rows.Scan(&e.ID, &e.VariantID, &e.Gunnery, &e.Piloting)
-			l.Entries = append(l.Entries, e)
-		}
-	}
+	l.Entries, _ = loadListEntries(r.Context(), h.DB, id)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(l)
@@ -150,6 +147,15 @@ func (h *ListsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Entries != nil {
+		for _, e := range *req.Entries {
+			if _, err := sim.ParseSPAs(e.SPAs); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
 	if req.Name != "" {
 		h.DB.Exec(`UPDATE user_lists SET name=?, updated_at=CURRENT_TIMESTAMP WHERE id=?`, req.Name, id)
 	}
@@ -170,8 +176,8 @@ func (h *ListsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Entries != nil {
 		h.DB.Exec(`DELETE FROM user_list_entries WHERE list_id = ?`, id)
 		for _, e := range *req.Entries {
-			h.DB.Exec(`INSERT INTO user_list_entries (list_id, variant_id, gunnery, piloting) VALUES (?, ?, ?, ?)`,
-				id, e.VariantID, e.Gunnery, e.Piloting)
+			h.DB.Exec(`INSERT INTO user_list_entries (list_id, variant_id, gunnery, piloting, spas) VALUES (?, ?, ?, ?, ?)`,
+				id, e.VariantID, e.Gunnery, e.Piloting, strings.Join(e.SPAs, ","))
 		}
 		h.DB.Exec(`UPDATE user_lists SET updated_at=CURRENT_TIMESTAMP WHERE id=?`, id)
 	}
@@ -217,16 +223,31 @@ func (h *ListsHandler) SharedView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.DB.Query(`SELECT id, variant_id, gunnery, piloting FROM user_list_entries WHERE list_id = ?`, l.ID)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var e UserListEntry
-			rows.Scan(&e.ID, &e.VariantID, &e.Gunnery, &e.Piloting)
-			l.Entries = append(l.Entries, e)
-		}
-	}
+	l.Entries, _ = loadListEntries(r.Context(), h.DB, l.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(l)
 }
+
+// loadListEntries reads a list's entries in insertion order.
+func loadListEntries(ctx context.Context, db *sql.DB, listID int64) ([]UserListEntry, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, variant_id, gunnery, piloting, spas FROM user_list_entries WHERE list_id = ? ORDER BY id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []UserListEntry
+	for rows.Next() {
+		var e UserListEntry
+		var spas string
+		if err := rows.Scan(&e.ID, &e.VariantID, &e.Gunnery, &e.Piloting, &spas); err != nil {
+			return nil, err
+		}
+		if spas != "" {
+			e.SPAs = strings.Split(spas, ",")
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
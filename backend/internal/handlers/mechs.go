@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/JustinWhittecar/slic/internal/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,16 +30,28 @@ func (h *MechHandler) List(w http.ResponseWriter, r *http.Request) {
 		       COALESCE(vs.heat_sink_count,0), COALESCE(vs.heat_sink_type,''),
 		       COALESCE(vs.run_mp,0),
 		       COALESCE(v.rules_level,0), COALESCE(v.source,''), COALESCE(v.config,''),
-		       COALESCE(vs.combat_rating,0),
+		       COALESCE(vs.combat_rating,0), COALESCE(vs.combat_rating_ci,0),
+		       COALESCE(tr.elo,0),
+		       COALESCE(vtr.open_cr,0), COALESCE(vtr.mixed_cr,0), COALESCE(vtr.dense_cr,0), COALESCE(vtr.urban_cr,0),
 		       COALESCE(er.rating,'')
 		FROM variants v
 		JOIN chassis c ON c.id = v.chassis_id
 		LEFT JOIN variant_stats vs ON vs.variant_id = v.id
 		LEFT JOIN external_ratings er ON er.variant_id = v.id AND er.source = 'goonhammer'
+		LEFT JOIN tournament_ratings tr ON tr.variant_id = v.id AND tr.pool = $1
+		LEFT JOIN (
+			SELECT variant_id,
+			       MAX(CASE WHEN terrain = 'open' THEN combat_rating END) AS open_cr,
+			       MAX(CASE WHEN terrain = 'mixed' THEN combat_rating END) AS mixed_cr,
+			       MAX(CASE WHEN terrain = 'dense' THEN combat_rating END) AS dense_cr,
+			       MAX(CASE WHEN terrain = 'urban' THEN combat_rating END) AS urban_cr
+			FROM variant_terrain_ratings GROUP BY variant_id
+		) vtr ON vtr.variant_id = v.id
 		WHERE v.mul_id IS NOT NULL AND v.mul_id > 0 AND v.battle_value > 0`
 
-	args := []any{}
-	argN := 0
+	// Elo comes from the ?pool= tournament
+	args := []any{tournamentPool(r)}
+	argN := 1
 
 	nextArg := func() string {
 		argN++
@@ -125,6 +138,50 @@ func (h *MechHandler) List(w http.ResponseWriter, r *http.Request) {
 			args = append(args, n)
 		}
 	}
+	// Combat rating under a condition preset, e.g. condition=night&condition_cr_min=6
+	if cond := r.URL.Query().Get("condition"); cond != "" {
+		if v := r.URL.Query().Get("condition_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_condition_ratings vcr WHERE vcr.variant_id = v.id AND vcr.condition = " + nextArg()
+				args = append(args, cond)
+				query += " AND vcr.combat_rating >= " + nextArg() + ")"
+				args = append(args, n)
+			}
+		}
+	}
+	// Combat rating on a terrain class, e.g. terrain=urban&terrain_cr_min=6
+	if terrain := r.URL.Query().Get("terrain"); terrain != "" {
+		if v := r.URL.Query().Get("terrain_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_terrain_ratings vter WHERE vter.variant_id = v.id AND vter.terrain = " + nextArg()
+				args = append(args, terrain)
+				query += " AND vter.combat_rating >= " + nextArg() + ")"
+				args = append(args, n)
+			}
+		}
+	}
+	// Combat rating playing a scenario, e.g. scenario=breakthrough&scenario_cr_min=6
+	if scenario := r.URL.Query().Get("scenario"); scenario != "" {
+		if v := r.URL.Query().Get("scenario_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_scenario_ratings vscr WHERE vscr.variant_id = v.id AND vscr.scenario = " + nextArg()
+				args = append(args, scenario)
+				query += " AND vscr.combat_rating >= " + nextArg() + ")"
+				args = append(args, n)
+			}
+		}
+	}
+	// Combat rating playing an AI strategy, e.g. strategy=brawler&strategy_cr_min=6
+	if strat := r.URL.Query().Get("strategy"); strat != "" {
+		if v := r.URL.Query().Get("strategy_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_strategy_ratings vsr WHERE vsr.variant_id = v.id AND vsr.strategy = " + nextArg()
+				args = append(args, strat)
+				query += " AND vsr.combat_rating >= " + nextArg() + ")"
+				args = append(args, n)
+			}
+		}
+	}
 	if v := r.URL.Query().Get("intro_year_min"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
 			query += " AND v.intro_year >= " + nextArg()
@@ -234,7 +291,8 @@ func (h *MechHandler) List(w http.ResponseWriter, r *http.Request) {
 			&m.EngineType, &m.EngineRating,
 			&m.HeatSinkCount, &m.HeatSinkType,
 			&m.RunMP, &m.RulesLevel, &m.Source, &m.Config,
-			&m.CombatRating, &m.GoonhammerRating); err != nil {
+			&m.CombatRating, &m.CombatRatingCI, &m.Elo,
+			&m.OpenCR, &m.MixedCR, &m.DenseCR, &m.UrbanCR, &m.GoonhammerRating); err != nil {
 			http.Error(w, "scan error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -281,7 +339,8 @@ func (h *MechHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		       COALESCE(tmm,0), COALESCE(armor_coverage_pct,0), COALESCE(heat_neutral_damage,0),
 		       COALESCE(heat_neutral_range,''), COALESCE(max_damage,0), COALESCE(effective_heat_neutral_damage,0),
 		       COALESCE(has_targeting_computer, false),
-		       COALESCE(combat_rating, 0), COALESCE(offense_turns, 0), COALESCE(defense_turns, 0)
+		       COALESCE(combat_rating, 0), COALESCE(offense_turns, 0), COALESCE(defense_turns, 0),
+		       COALESCE(combat_rating_ci, 0)
 		FROM variant_stats WHERE variant_id = $1`, id).Scan(
 		&stats.WalkMP, &stats.RunMP, &stats.JumpMP, &stats.ArmorTotal, &stats.ISTotal,
 		&stats.HeatSinkCount, &stats.HeatSinkType, &stats.EngineType, &stats.EngineRating,
@@ -290,9 +349,26 @@ func (h *MechHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		&stats.TMM, &stats.ArmorCoveragePct, &stats.HeatNeutralDamage,
 		&stats.HeatNeutralRange, &stats.MaxDamage, &stats.EffHeatNeutralDamage,
 		&stats.HasTargetingComputer,
-		&stats.CombatRating, &stats.OffenseTurns, &stats.DefenseTurns)
+		&stats.CombatRating, &stats.OffenseTurns, &stats.DefenseTurns,
+		&stats.CombatRatingCI)
 	if err == nil {
 		m.Stats = &stats
+
+		// Sub-scores against each baseline of the panel
+		blRows, err := h.DB.Query(ctx, `
+			SELECT vbr.baseline_id, b.name, b.model_code, vbr.weight, vbr.combat_rating, vbr.combat_rating_ci
+			FROM variant_baseline_ratings vbr
+			JOIN variants b ON b.id = vbr.baseline_id
+			WHERE vbr.variant_id = $1
+			ORDER BY vbr.weight DESC, b.name`, id)
+		if err == nil {
+			defer blRows.Close()
+			for blRows.Next() {
+				var br models.BaselineRating
+				blRows.Scan(&br.BaselineID, &br.Name, &br.ModelCode, &br.Weight, &br.CombatRating, &br.CombatRatingCI)
+				stats.BaselineRatings = append(stats.BaselineRatings, br)
+			}
+		}
 	}
 
 	// Generate sourcing links from chassis name
@@ -351,6 +427,104 @@ func (h *MechHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Load quirks
+	quirkRows, err := h.DB.Query(ctx, `
+		SELECT quirk, COALESCE(location,''), COALESCE(weapon,'')
+		FROM variant_quirks
+		WHERE variant_id = $1
+		ORDER BY id`, id)
+	if err == nil {
+		defer quirkRows.Close()
+		for quirkRows.Next() {
+			var q models.VariantQuirk
+			quirkRows.Scan(&q.Quirk, &q.Location, &q.Weapon)
+			m.Quirks = append(m.Quirks, describeQuirk(q))
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(m)
 }
+
+// defaultTournamentPool is the calc-cr-v2 -tournament pool that Elo ratings
+// come from when a request doesn't name one with ?pool=.
+const defaultTournamentPool = "all"
+
+func tournamentPool(r *http.Request) string {
+	if p := r.URL.Query().Get("pool"); p != "" {
+		return p
+	}
+	return defaultTournamentPool
+}
+
+// matchupLimit is the ?limit= of best and worst matchups, 5 by default and
+// at most 50.
+func matchupLimit(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || n <= 0 {
+		return 5
+	}
+	return min(n, 50)
+}
+
+// Matchups returns a variant's tournament rating with its best and worst
+// matchups: the opponents it scored most and least against, a draw counting
+// half a win. Ties go to the stronger opponent for best and the weaker one
+// for worst.
+func (h *MechHandler) Matchups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	res := models.MechMatchups{Pool: tournamentPool(r)}
+	err = h.DB.QueryRow(ctx, `
+		SELECT elo, games, wins, losses, draws FROM tournament_ratings
+		WHERE pool = $1 AND variant_id = $2`, res.Pool, id).Scan(
+		&res.Elo, &res.Games, &res.Wins, &res.Losses, &res.Draws)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "no tournament rating", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, side := range []struct {
+		order string
+		into  *[]models.MatchupRecord
+	}{
+		{"win_rate DESC, opp_elo DESC", &res.Best},
+		{"win_rate, opp_elo", &res.Worst},
+	} {
+		rows, err := h.DB.Query(ctx, `
+			SELECT v.id, v.model_code, v.name, COALESCE(tr.elo,0) AS opp_elo, tm.wins, tm.losses, tm.draws,
+			       (tm.wins + 0.5*tm.draws)::float8 / (tm.wins + tm.losses + tm.draws) AS win_rate
+			FROM tournament_matchups tm
+			JOIN variants v ON v.id = tm.opponent_id
+			LEFT JOIN tournament_ratings tr ON tr.pool = tm.pool AND tr.variant_id = tm.opponent_id
+			WHERE tm.pool = $1 AND tm.variant_id = $2 AND tm.wins + tm.losses + tm.draws > 0
+			ORDER BY `+side.order+` LIMIT $3`, res.Pool, id, matchupLimit(r))
+		if err != nil {
+			http.Error(w, "query error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		*side.into = []models.MatchupRecord{}
+		for rows.Next() {
+			var m models.MatchupRecord
+			if err := rows.Scan(&m.ID, &m.ModelCode, &m.Name, &m.Elo, &m.Wins, &m.Losses, &m.Draws, &m.WinRate); err != nil {
+				rows.Close()
+				http.Error(w, "scan error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			*side.into = append(*side.into, m)
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -12,9 +12,50 @@ import (
 
 type MechHandlerSQLite struct {
 	DB *sql.DB
+
+	// Older mech DBs predate the tournament and terrain rating tables
+	hasTournamentRatings bool
+	hasTerrainRatings    bool
+}
+
+// NewMechHandlerSQLite checks once which optional rating tables db has.
+func NewMechHandlerSQLite(db *sql.DB) *MechHandlerSQLite {
+	return &MechHandlerSQLite{
+		DB:                   db,
+		hasTournamentRatings: sqliteHasTable(db, "tournament_ratings"),
+		hasTerrainRatings:    sqliteHasTable(db, "variant_terrain_ratings"),
+	}
+}
+
+// sqliteHasTable reports whether db has the named table.
+func sqliteHasTable(db *sql.DB, name string) bool {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n)
+	return err == nil && n > 0
 }
 
 func (h *MechHandlerSQLite) List(w http.ResponseWriter, r *http.Request) {
+	// Elo comes from the ?pool= tournament
+	eloCol, tournamentJoin := "0", ""
+	var args []any
+	if h.hasTournamentRatings {
+		eloCol = "COALESCE(tr.elo,0)"
+		tournamentJoin = "LEFT JOIN tournament_ratings tr ON tr.variant_id = v.id AND tr.pool = ?"
+		args = append(args, tournamentPool(r))
+	}
+	terrainCols, terrainJoin := "0, 0, 0, 0", ""
+	if h.hasTerrainRatings {
+		terrainCols = "COALESCE(vtr.open_cr,0), COALESCE(vtr.mixed_cr,0), COALESCE(vtr.dense_cr,0), COALESCE(vtr.urban_cr,0)"
+		terrainJoin = `LEFT JOIN (
+			SELECT variant_id,
+			       MAX(CASE WHEN terrain = 'open' THEN combat_rating END) AS open_cr,
+			       MAX(CASE WHEN terrain = 'mixed' THEN combat_rating END) AS mixed_cr,
+			       MAX(CASE WHEN terrain = 'dense' THEN combat_rating END) AS dense_cr,
+			       MAX(CASE WHEN terrain = 'urban' THEN combat_rating END) AS urban_cr
+			FROM variant_terrain_ratings GROUP BY variant_id
+		) vtr ON vtr.variant_id = v.id`
+	}
+
 	query := `
 		SELECT v.id, v.model_code, v.name, c.name, COALESCE(c.alternate_name,''), COALESCE(vs.tonnage, c.tonnage), c.tech_base,
 		       v.battle_value, v.intro_year, COALESCE(v.era,''), COALESCE(v.role,''),
@@ -27,16 +68,18 @@ func (h *MechHandlerSQLite) List(w http.ResponseWriter, r *http.Request) {
 		       COALESCE(vs.heat_sink_count,0), COALESCE(vs.heat_sink_type,''),
 		       COALESCE(vs.run_mp,0),
 		       COALESCE(v.rules_level,0), COALESCE(v.source,''), COALESCE(v.config,''),
-		       COALESCE(vs.combat_rating,0),
+		       COALESCE(vs.combat_rating,0), COALESCE(vs.combat_rating_ci,0),
+		       ` + eloCol + `,
+		       ` + terrainCols + `,
 		       COALESCE(er.rating,'')
 		FROM variants v
 		JOIN chassis c ON c.id = v.chassis_id
 		LEFT JOIN variant_stats vs ON vs.variant_id = v.id
 		LEFT JOIN external_ratings er ON er.variant_id = v.id AND er.source = 'goonhammer'
+		` + tournamentJoin + `
+		` + terrainJoin + `
 		WHERE v.mul_id IS NOT NULL AND v.mul_id > 0 AND v.battle_value > 0`
 
-	args := []any{}
-
 	// Support ?ids=1,2,3 for fetching specific variants by ID
 	if v := r.URL.Query().Get("ids"); v != "" {
 		idStrs := strings.Split(v, ",")
@@ -132,6 +175,46 @@ func (h *MechHandlerSQLite) List(w http.ResponseWriter, r *http.Request) {
 			args = append(args, n)
 		}
 	}
+	// Combat rating under a condition preset, e.g. condition=night&condition_cr_min=6
+	if cond := r.URL.Query().Get("condition"); cond != "" {
+		if v := r.URL.Query().Get("condition_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_condition_ratings vcr WHERE vcr.variant_id = v.id AND vcr.condition = ? AND vcr.combat_rating >= ?)"
+				args = append(args, cond, n)
+			}
+		}
+	}
+	// Combat rating on a terrain class, e.g. terrain=urban&terrain_cr_min=6
+	if terrain := r.URL.Query().Get("terrain"); terrain != "" {
+		if v := r.URL.Query().Get("terrain_cr_min"); v != "" {
+			if !h.hasTerrainRatings {
+				http.Error(w, "terrain ratings are not available", http.StatusBadRequest)
+				return
+			}
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_terrain_ratings vter WHERE vter.variant_id = v.id AND vter.terrain = ? AND vter.combat_rating >= ?)"
+				args = append(args, terrain, n)
+			}
+		}
+	}
+	// Combat rating playing a scenario, e.g. scenario=breakthrough&scenario_cr_min=6
+	if scenario := r.URL.Query().Get("scenario"); scenario != "" {
+		if v := r.URL.Query().Get("scenario_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_scenario_ratings vscr WHERE vscr.variant_id = v.id AND vscr.scenario = ? AND vscr.combat_rating >= ?)"
+				args = append(args, scenario, n)
+			}
+		}
+	}
+	// Combat rating playing an AI strategy, e.g. strategy=brawler&strategy_cr_min=6
+	if strat := r.URL.Query().Get("strategy"); strat != "" {
+		if v := r.URL.Query().Get("strategy_cr_min"); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				query += " AND EXISTS (SELECT 1 FROM variant_strategy_ratings vsr WHERE vsr.variant_id = v.id AND vsr.strategy = ? AND vsr.combat_rating >= ?)"
+				args = append(args, strat, n)
+			}
+		}
+	}
 	if v := r.URL.Query().Get("intro_year_min"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
 			query += " AND v.intro_year >= ?"
@@ -241,7 +324,8 @@ func (h *MechHandlerSQLite) List(w http.ResponseWriter, r *http.Request) {
 			&m.EngineType, &m.EngineRating,
 			&m.HeatSinkCount, &m.HeatSinkType,
 			&m.RunMP, &m.RulesLevel, &m.Source, &m.Config,
-			&m.CombatRating, &m.GoonhammerRating); err != nil {
+			&m.CombatRating, &m.CombatRatingCI, &m.Elo,
+			&m.OpenCR, &m.MixedCR, &m.DenseCR, &m.UrbanCR, &m.GoonhammerRating); err != nil {
 			http.Error(w, "scan error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -287,7 +371,8 @@ func (h *MechHandlerSQLite) GetByID(w http.ResponseWriter, r *http.Request) {
 		       COALESCE(tmm,0), COALESCE(armor_coverage_pct,0), COALESCE(heat_neutral_damage,0),
 		       COALESCE(heat_neutral_range,''), COALESCE(max_damage,0), COALESCE(effective_heat_neutral_damage,0),
 		       COALESCE(has_targeting_computer, 0),
-		       COALESCE(combat_rating, 0), COALESCE(offense_turns, 0), COALESCE(defense_turns, 0)
+		       COALESCE(combat_rating, 0), COALESCE(offense_turns, 0), COALESCE(defense_turns, 0),
+		       COALESCE(combat_rating_ci, 0)
 		FROM variant_stats WHERE variant_id = ?`, id).Scan(
 		&stats.WalkMP, &stats.RunMP, &stats.JumpMP, &stats.ArmorTotal, &stats.ISTotal,
 		&stats.HeatSinkCount, &stats.HeatSinkType, &stats.EngineType, &stats.EngineRating,
@@ -296,9 +381,26 @@ func (h *MechHandlerSQLite) GetByID(w http.ResponseWriter, r *http.Request) {
 		&stats.TMM, &stats.ArmorCoveragePct, &stats.HeatNeutralDamage,
 		&stats.HeatNeutralRange, &stats.MaxDamage, &stats.EffHeatNeutralDamage,
 		&stats.HasTargetingComputer,
-		&stats.CombatRating, &stats.OffenseTurns, &stats.DefenseTurns)
+		&stats.CombatRating, &stats.OffenseTurns, &stats.DefenseTurns,
+		&stats.CombatRatingCI)
 	if err == nil {
 		m.Stats = &stats
+
+		// Sub-scores against each baseline of the panel
+		blRows, err := h.DB.Query(`
+			SELECT vbr.baseline_id, b.name, b.model_code, vbr.weight, vbr.combat_rating, vbr.combat_rating_ci
+			FROM variant_baseline_ratings vbr
+			JOIN variants b ON b.id = vbr.baseline_id
+			WHERE vbr.variant_id = ?
+			ORDER BY vbr.weight DESC, b.name`, id)
+		if err == nil {
+			defer blRows.Close()
+			for blRows.Next() {
+				var br models.BaselineRating
+				blRows.Scan(&br.BaselineID, &br.Name, &br.ModelCode, &br.Weight, &br.CombatRating, &br.CombatRatingCI)
+				stats.BaselineRatings = append(stats.BaselineRatings, br)
+			}
+		}
 	}
 
 	// Generate Sarna link
@@ -372,6 +474,85 @@ func (h *MechHandlerSQLite) GetByID(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Load quirks
+	quirkRows, err := h.DB.Query(`
+		SELECT quirk, COALESCE(location,''), COALESCE(weapon,'')
+		FROM variant_quirks
+		WHERE variant_id = ?
+		ORDER BY id`, id)
+	if err == nil {
+		defer quirkRows.Close()
+		for quirkRows.Next() {
+			var q models.VariantQuirk
+			quirkRows.Scan(&q.Quirk, &q.Location, &q.Weapon)
+			m.Quirks = append(m.Quirks, describeQuirk(q))
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(m)
 }
+
+// Matchups returns a variant's tournament rating with its best and worst
+// matchups; see MechHandler.Matchups.
+func (h *MechHandlerSQLite) Matchups(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.hasTournamentRatings {
+		http.Error(w, "no tournament ratings", http.StatusNotFound)
+		return
+	}
+
+	res := models.MechMatchups{Pool: tournamentPool(r)}
+	err = h.DB.QueryRow(`
+		SELECT elo, games, wins, losses, draws FROM tournament_ratings
+		WHERE pool = ? AND variant_id = ?`, res.Pool, id).Scan(
+		&res.Elo, &res.Games, &res.Wins, &res.Losses, &res.Draws)
+	if err == sql.ErrNoRows {
+		http.Error(w, "no tournament rating", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, side := range []struct {
+		order string
+		into  *[]models.MatchupRecord
+	}{
+		{"win_rate DESC, opp_elo DESC", &res.Best},
+		{"win_rate, opp_elo", &res.Worst},
+	} {
+		rows, err := h.DB.Query(`
+			SELECT v.id, v.model_code, v.name, COALESCE(tr.elo,0) AS opp_elo, tm.wins, tm.losses, tm.draws,
+			       (tm.wins + 0.5*tm.draws) / (tm.wins + tm.losses + tm.draws) AS win_rate
+			FROM tournament_matchups tm
+			JOIN variants v ON v.id = tm.opponent_id
+			LEFT JOIN tournament_ratings tr ON tr.pool = tm.pool AND tr.variant_id = tm.opponent_id
+			WHERE tm.pool = ? AND tm.variant_id = ? AND tm.wins + tm.losses + tm.draws > 0
+			ORDER BY `+side.order+` LIMIT ?`, res.Pool, id, matchupLimit(r))
+		if err != nil {
+			http.Error(w, "query error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		*side.into = []models.MatchupRecord{}
+		for rows.Next() {
+			var m models.MatchupRecord
+			if err := rows.Scan(&m.ID, &m.ModelCode, &m.Name, &m.Elo, &m.Wins, &m.Losses, &m.Draws, &m.WinRate); err != nil {
+				rows.Close()
+				http.Error(w, "scan error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			*side.into = append(*side.into, m)
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
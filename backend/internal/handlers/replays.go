@@ -1,52 +1,134 @@
 package handlers
 
 import (
-	"bytes"
-	"compress/gzip"
 	"database/sql"
-	"io"
+	"encoding/json"
 	"net/http"
 	"strconv"
+
+	"github.com/JustinWhittecar/slic/internal/cbor"
+	"github.com/JustinWhittecar/slic/internal/sim"
 )
 
 type ReplayHandler struct {
 	DB *sql.DB
 }
 
-func (h *ReplayHandler) GetReplay(w http.ResponseWriter, r *http.Request) {
+// loadReplay reads and decodes the stored replay for the {id} in the path,
+// writing the error response if there is none.
+func (h *ReplayHandler) loadReplay(w http.ResponseWriter, r *http.Request) *sim.ReplayData {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "invalid id", http.StatusBadRequest)
-		return
+		return nil
 	}
 
 	var data []byte
 	err = h.DB.QueryRow(`SELECT replay_data FROM variant_replays WHERE variant_id = ?`, id).Scan(&data)
 	if err == sql.ErrNoRows {
 		http.Error(w, "no replay available", http.StatusNotFound)
-		return
+		return nil
 	}
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
-		return
+		return nil
 	}
 
-	// Decompress gzip
-	gz, err := gzip.NewReader(bytes.NewReader(data))
+	replay, err := sim.DecodeReplay(data)
 	if err != nil {
-		http.Error(w, "decompress error", http.StatusInternalServerError)
+		http.Error(w, "decode error", http.StatusInternalServerError)
+		return nil
+	}
+	return replay
+}
+
+// GetReplay handles GET /api/variants/{id}/replay: the whole replay as
+// JSON, whatever version it is stored in.
+func (h *ReplayHandler) GetReplay(w http.ResponseWriter, r *http.Request) {
+	replay := h.loadReplay(w, r)
+	if replay == nil {
 		return
 	}
-	defer gz.Close()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	json.NewEncoder(w).Encode(replay)
+}
 
-	jsonData, err := io.ReadAll(gz)
-	if err != nil {
-		http.Error(w, "decompress error", http.StatusInternalServerError)
+// StreamReplay handles GET /api/variants/{id}/replay/stream: a
+// sim.ReplayHeader, then one sim.ReplayTurnDelta per turn, flushed as each
+// is written so the viewer can start playing before the rest arrives. The
+// stream is newline-delimited JSON, or a CBOR sequence for Accept:
+// application/cbor-seq. from and to pick turns [from, to) by index.
+func (h *ReplayHandler) StreamReplay(w http.ResponseWriter, r *http.Request) {
+	from, to := 0, -1
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil || from < 0 {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil || to < from {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+
+	replay := h.loadReplay(w, r)
+	if replay == nil {
+		return
+	}
+	n := len(replay.Turns)
+	if from > n {
+		http.Error(w, "from is past the last turn", http.StatusRequestedRangeNotSatisfiable)
 		return
 	}
+	if to < 0 || to > n {
+		to = n
+	}
+
+	var enc interface{ Encode(any) error }
+	if r.Header.Get("Accept") == "application/cbor-seq" {
+		w.Header().Set("Content-Type", "application/cbor-seq")
+		enc = cbor.NewEncoder(w)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc = json.NewEncoder(w)
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Vary", "Accept")
+	flusher, _ := w.(http.Flusher)
+	send := func(v any) bool {
+		if err := enc.Encode(v); err != nil {
+			return false // client went away
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
 
+	if !send(replay.Header(from)) {
+		return
+	}
+	for _, d := range replay.Deltas(from, to) {
+		if !send(d) {
+			return
+		}
+	}
+}
+
+// Analysis handles GET /api/variants/{id}/replay/analysis: the replay's
+// damage, heat and hit-rate timelines, crits and decisive turn (see
+// sim.AnalyzeReplay).
+func (h *ReplayHandler) Analysis(w http.ResponseWriter, r *http.Request) {
+	replay := h.loadReplay(w, r)
+	if replay == nil {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "public, max-age=86400")
-	w.Write(jsonData)
+	json.NewEncoder(w).Encode(sim.AnalyzeReplay(replay))
 }
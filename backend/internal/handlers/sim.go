@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+	"github.com/JustinWhittecar/slic/internal/models"
+	"github.com/JustinWhittecar/slic/internal/sim"
+)
+
+const (
+	maxSimJobs      = 2 // concurrent sim requests; more get 503
+	maxDuelSims     = 200
+	defaultDuelSims = 50
+	simTimeout      = 20 * time.Second
+)
+
+// SimHandler runs on-demand simulations against the read-only mech DB.
+type SimHandler struct {
+	DB     *sql.DB
+	UserDB *sql.DB // saved lists; list matchups are disabled without it
+	Boards []*sim.Board
+	MTFs   map[string]*ingestion.MTFData // optional; armor is estimated without it
+	jobs   chan struct{}
+}
+
+func NewSimHandler(db *sql.DB, boards []*sim.Board, mtfs map[string]*ingestion.MTFData) *SimHandler {
+	return &SimHandler{DB: db, Boards: boards, MTFs: mtfs, jobs: make(chan struct{}, maxSimJobs)}
+}
+
+type simSide struct {
+	VariantID int      `json:"variant_id"`
+	Gunnery   *int     `json:"gunnery"`  // default 4
+	Piloting  *int     `json:"piloting"` // default 5
+	SPAs      []string `json:"spas"`     // see sim.ParseSPA
+	AI        string   `json:"ai"`       // see sim.Strategies, default tactician
+}
+
+type duelRequest struct {
+	Attacker simSide `json:"attacker"`
+	Defender simSide `json:"defender"`
+	Seed     *uint64 `json:"seed"`
+	Sims     int     `json:"sims"`
+	// Comma-separated condition presets, e.g. "night,cold"
+	Conditions string `json:"conditions"`
+}
+
+type duelResponse struct {
+	Seed uint64 `json:"seed"`
+	*sim.DuelResult
+}
+
+// Duel handles POST /api/sim/duel.
+func (h *SimHandler) Duel(w http.ResponseWriter, r *http.Request) {
+	var req duelRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10_000)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Sims == 0 {
+		req.Sims = defaultDuelSims
+	}
+	if req.Sims < 1 || req.Sims > maxDuelSims {
+		http.Error(w, "sims must be between 1 and 200", http.StatusBadRequest)
+		return
+	}
+	cond, err := sim.ParseConditions(req.Conditions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := h.acquire()
+	if !ok {
+		http.Error(w, "Simulator busy, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	atk, status, msg := h.buildSide(r.Context(), req.Attacker)
+	if atk == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	def, status, msg := h.buildSide(r.Context(), req.Defender)
+	if def == nil {
+		http.Error(w, msg, status)
+		return
+	}
+
+	seed := rand.Uint64()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), simTimeout)
+	defer cancel()
+	res, err := sim.RunDuels(ctx, sim.DuelConfig{
+		Boards:   h.Boards,
+		Attacker: atk,
+		Defender: def,
+		Seed:     seed,
+		N:        req.Sims,
+
+		Conditions: cond,
+	})
+	if err != nil {
+		writeSimError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duelResponse{Seed: seed, DuelResult: res})
+}
+
+// acquire takes a job slot without blocking.
+func (h *SimHandler) acquire() (func(), bool) {
+	select {
+	case h.jobs <- struct{}{}:
+		return func() { <-h.jobs }, true
+	default:
+		return nil, false
+	}
+}
+
+// buildSide loads one side's variant and applies its pilot skills. On failure
+// it returns a nil mech plus the HTTP status and message to send.
+func (h *SimHandler) buildSide(ctx context.Context, side simSide) (*sim.MechState, int, string) {
+	gunnery, piloting := sim.DefaultGunnery, sim.DefaultPiloting
+	if side.Gunnery != nil {
+		gunnery = *side.Gunnery
+	}
+	if side.Piloting != nil {
+		piloting = *side.Piloting
+	}
+	if gunnery < 0 || gunnery > 8 || piloting < 0 || piloting > 8 {
+		return nil, http.StatusBadRequest, "gunnery and piloting must be between 0 and 8"
+	}
+	spas, err := sim.ParseSPAs(side.SPAs)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+	ai, err := sim.ParseStrategy(side.AI)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+
+	v, err := loadSimVariant(ctx, h.DB, side.VariantID)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, "variant not found"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+
+	m := sim.BuildMechState(v, sim.LookupMTF(h.MTFs, v))
+	m.Gunnery = gunnery
+	m.Piloting = piloting
+	m.SPAs = spas
+	m.AI = ai
+	return m, 0, ""
+}
+
+func writeSimError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Simulation timed out, try fewer sims", http.StatusServiceUnavailable)
+	case errors.Is(err, context.Canceled):
+		// client went away
+	default:
+		http.Error(w, "Simulation failed", http.StatusInternalServerError)
+	}
+}
+
+// loadSimVariant reads the sim inputs for one variant from the SQLite mech DB.
+func loadSimVariant(ctx context.Context, db *sql.DB, id int) (*sim.Variant, error) {
+	v := &sim.Variant{ID: id}
+	var hasTC int
+	err := db.QueryRowContext(ctx, `
+		SELECT v.name, v.model_code, c.tech_base, COALESCE(vs.tonnage, c.tonnage),
+		       vs.walk_mp, vs.run_mp, vs.jump_mp, vs.heat_sink_count, vs.heat_sink_type,
+		       vs.engine_type, COALESCE(vs.structure_type, 'Standard'),
+		       COALESCE(vs.has_targeting_computer, 0), vs.armor_total
+		FROM variants v
+		JOIN chassis c ON c.id = v.chassis_id
+		JOIN variant_stats vs ON vs.variant_id = v.id
+		WHERE v.id = ?`, id).Scan(
+		&v.Name, &v.ModelCode, &v.TechBase, &v.Tonnage,
+		&v.WalkMP, &v.RunMP, &v.JumpMP, &v.HSCount, &v.HSType,
+		&v.EngineType, &v.StructType, &hasTC, &v.ArmorTotal)
+	if err != nil {
+		return nil, err
+	}
+	v.HasTC = hasTC != 0
+
+	// Quirks are optional: older mech DBs have no variant_quirks table
+	if qrows, err := db.QueryContext(ctx,
+		`SELECT quirk, COALESCE(location,''), COALESCE(weapon,'') FROM variant_quirks WHERE variant_id = ? ORDER BY id`, id); err == nil {
+		for qrows.Next() {
+			var q ingestion.WeaponQuirk
+			if err := qrows.Scan(&q.Quirk, &q.Location, &q.Weapon); err != nil {
+				qrows.Close()
+				return nil, err
+			}
+			if q.Weapon == "" {
+				v.Quirks = append(v.Quirks, q.Quirk)
+			} else {
+				v.WeaponQuirks = append(v.WeaponQuirks, q)
+			}
+		}
+		qrows.Close()
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.name, e.type, COALESCE(e.damage, 0), COALESCE(e.heat, 0),
+		       COALESCE(e.min_range, 0), COALESCE(e.short_range, 0), COALESCE(e.medium_range, 0),
+		       COALESCE(e.long_range, 0), COALESCE(e.to_hit_modifier, 0), COALESCE(e.rack_size, 0),
+		       ve.location, ve.quantity
+		FROM variant_equipment ve
+		JOIN equipment e ON e.id = ve.equipment_id
+		WHERE ve.variant_id = ?
+		  AND e.type IN ('energy','ballistic','missile','artillery')
+		ORDER BY ve.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var wp sim.VariantWeapon
+		var dmg float64
+		if err := rows.Scan(&wp.Name, &wp.Type, &dmg, &wp.Heat,
+			&wp.MinRange, &wp.Short, &wp.Medium, &wp.Long,
+			&wp.ToHitMod, &wp.RackSize, &wp.Location, &wp.Quantity); err != nil {
+			return nil, err
+		}
+		wp.Damage = int(dmg)
+		v.Weapons = append(v.Weapons, wp)
+	}
+	return v, rows.Err()
+}
+
+// describeQuirk fills in the display label and whether the simulator models
+// the quirk.
+func describeQuirk(q models.VariantQuirk) models.VariantQuirk {
+	q.Label = q.Quirk
+	if q.Weapon != "" {
+		if wq, ok := sim.LookupWeaponQuirk(q.Quirk); ok {
+			q.Label, q.Simulated = wq.Label, true
+		}
+		return q
+	}
+	if uq, ok := sim.LookupQuirk(q.Quirk); ok {
+		q.Label, q.Simulated = uq.Label, !uq.NoEffect
+	}
+	return q
+}
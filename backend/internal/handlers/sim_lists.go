@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/JustinWhittecar/slic/internal/bvcalc"
+	"github.com/JustinWhittecar/slic/internal/sim"
+)
+
+const (
+	maxBattleSims     = 100
+	defaultBattleSims = 50
+	maxListUnits      = 16
+)
+
+// listRef picks a saved list: by ID (owner, or with its share code) or by
+// share code alone.
+type listRef struct {
+	ListID    int64  `json:"list_id"`
+	ShareCode string `json:"share_code"`
+	AI        string `json:"ai"` // strategy for every unit, see sim.Strategies
+}
+
+type listMatchupRequest struct {
+	A    listRef `json:"a"`
+	B    listRef `json:"b"`
+	Seed *uint64 `json:"seed"`
+	Sims int     `json:"sims"`
+	// Comma-separated condition presets, e.g. "night,cold"
+	Conditions string `json:"conditions"`
+	// Scenario list A plays against list B, see sim.Scenarios; "" for a
+	// plain battle
+	Scenario string `json:"scenario"`
+}
+
+type listUnitReport struct {
+	VariantID int      `json:"variant_id"`
+	Gunnery   int      `json:"gunnery"`
+	Piloting  int      `json:"piloting"`
+	SPAs      []string `json:"spas,omitempty"`
+	sim.UnitStats
+}
+
+type listSideReport struct {
+	ListID int64 `json:"list_id"`
+	sim.ForceStats
+	Units       []listUnitReport `json:"units"`
+	MVP         int              `json:"mvp"`          // index into units
+	WeakestLink int              `json:"weakest_link"` // index into units
+}
+
+type listMatchupResponse struct {
+	Seed      uint64         `json:"seed"`
+	Sims      int            `json:"sims"`
+	Draws     int            `json:"draws"`
+	MeanTurns float64        `json:"mean_turns"`
+	A         listSideReport `json:"a"`
+	B         listSideReport `json:"b"`
+}
+
+// simList is a saved list resolved into a sim force.
+type simList struct {
+	id      int64
+	force   sim.Force
+	entries []UserListEntry
+}
+
+// ListMatchup handles POST /api/sim/lists. It plays two saved lists against
+// each other, with each entry's pilot skills and skill-adjusted BV.
+func (h *SimHandler) ListMatchup(w http.ResponseWriter, r *http.Request) {
+	if h.UserDB == nil {
+		http.Error(w, "Lists are not available", http.StatusServiceUnavailable)
+		return
+	}
+	var req listMatchupRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10_000)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Sims == 0 {
+		req.Sims = defaultBattleSims
+	}
+	if req.Sims < 1 || req.Sims > maxBattleSims {
+		http.Error(w, fmt.Sprintf("sims must be between 1 and %d", maxBattleSims), http.StatusBadRequest)
+		return
+	}
+	cond, err := sim.ParseConditions(req.Conditions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scenario, err := sim.ParseScenario(req.Scenario)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := h.acquire()
+	if !ok {
+		http.Error(w, "Simulator busy, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	a, status, msg := h.loadSimList(r.Context(), req.A)
+	if a == nil {
+		http.Error(w, msg, status)
+		return
+	}
+	b, status, msg := h.loadSimList(r.Context(), req.B)
+	if b == nil {
+		http.Error(w, msg, status)
+		return
+	}
+
+	seed := rand.Uint64()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), simTimeout)
+	defer cancel()
+	res, err := sim.RunBattles(ctx, sim.BattleConfig{
+		Boards: h.Boards,
+		A:      a.force,
+		B:      b.force,
+		Seed:   seed,
+		N:      req.Sims,
+
+		Conditions: cond,
+		Scenario:   scenario,
+	})
+	if err != nil {
+		writeSimError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listMatchupResponse{
+		Seed:      seed,
+		Sims:      res.Sims,
+		Draws:     res.Draws,
+		MeanTurns: res.MeanTurns,
+		A:         sideReport(a, res.A),
+		B:         sideReport(b, res.B),
+	})
+}
+
+func sideReport(l *simList, fs sim.ForceStats) listSideReport {
+	rep := listSideReport{
+		ListID:      l.id,
+		ForceStats:  fs,
+		Units:       make([]listUnitReport, len(fs.Units)),
+		MVP:         fs.MVP(),
+		WeakestLink: fs.WeakestLink(),
+	}
+	for i, u := range fs.Units {
+		e := l.entries[i]
+		rep.Units[i] = listUnitReport{VariantID: e.VariantID, Gunnery: e.Gunnery, Piloting: e.Piloting, SPAs: e.SPAs, UnitStats: u}
+	}
+	return rep
+}
+
+// loadSimList resolves ref to a list the caller may read and builds its
+// force. On failure it returns nil plus the HTTP status and message to send.
+func (h *SimHandler) loadSimList(ctx context.Context, ref listRef) (*simList, int, string) {
+	l := &simList{id: ref.ListID}
+	var ownerID int64
+	var shareCode string
+	var err error
+	switch {
+	case ref.ListID > 0:
+		err = h.UserDB.QueryRowContext(ctx,
+			`SELECT user_id, name, COALESCE(share_code,'') FROM user_lists WHERE id = ?`, ref.ListID,
+		).Scan(&ownerID, &l.force.Name, &shareCode)
+	case ref.ShareCode != "":
+		err = h.UserDB.QueryRowContext(ctx,
+			`SELECT id, user_id, name, share_code FROM user_lists WHERE share_code = ?`, ref.ShareCode,
+		).Scan(&l.id, &ownerID, &l.force.Name, &shareCode)
+	default:
+		return nil, http.StatusBadRequest, "each side needs a list_id or share_code"
+	}
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, "list not found"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+
+	// Same access rule as ListsHandler.Get
+	user := UserFromContext(ctx)
+	if (user == nil || user.ID != ownerID) && (shareCode == "" || shareCode != ref.ShareCode) {
+		return nil, http.StatusForbidden, "Forbidden"
+	}
+
+	l.entries, err = loadListEntries(ctx, h.UserDB, l.id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+	if len(l.entries) == 0 {
+		return nil, http.StatusBadRequest, fmt.Sprintf("list %q has no units", l.force.Name)
+	}
+	if len(l.entries) > maxListUnits {
+		return nil, http.StatusBadRequest, fmt.Sprintf("lists are limited to %d units", maxListUnits)
+	}
+
+	for _, e := range l.entries {
+		m, status, msg := h.buildSide(ctx, simSide{VariantID: e.VariantID, Gunnery: &e.Gunnery, Piloting: &e.Piloting, SPAs: e.SPAs, AI: ref.AI})
+		if m == nil {
+			return nil, status, msg
+		}
+		var bv int
+		if err := h.DB.QueryRowContext(ctx,
+			`SELECT COALESCE(battle_value, 0) FROM variants WHERE id = ?`, e.VariantID).Scan(&bv); err != nil {
+			return nil, http.StatusInternalServerError, "Database error"
+		}
+		l.force.Units = append(l.force.Units, sim.ForceUnit{
+			Mech: m,
+			BV:   bvcalc.SkillAdjustedBV(bv, e.Gunnery, e.Piloting),
+		})
+	}
+	return l, 0, ""
+}
@@ -36,7 +36,8 @@ type MTFData struct {
 	RulesLevel int
 
 	// Quirks
-	Quirks []string
+	Quirks       []string
+	WeaponQuirks []WeaponQuirk
 
 	// Core
 	Mass          int
@@ -83,6 +84,29 @@ type WeaponEntry struct {
 	Location string
 }
 
+// WeaponQuirk is a quirk on one mounted weapon, from a
+// "weaponquirk:accurate:RA:2:Medium Laser" line (quirk, location, slot, weapon).
+type WeaponQuirk struct {
+	Quirk    string
+	Location string
+	Slot     int
+	Weapon   string
+}
+
+func parseWeaponQuirk(val string) (WeaponQuirk, bool) {
+	parts := strings.SplitN(val, ":", 4)
+	if len(parts) != 4 || parts[0] == "" {
+		return WeaponQuirk{}, false
+	}
+	slot, _ := strconv.Atoi(parts[2])
+	return WeaponQuirk{
+		Quirk:    strings.TrimSpace(parts[0]),
+		Location: strings.TrimSpace(parts[1]),
+		Slot:     slot,
+		Weapon:   strings.TrimSpace(parts[3]),
+	}, true
+}
+
 // ParseMTF reads a MegaMek .mtf file and returns structured data.
 func ParseMTF(path string) (*MTFData, error) {
 	f, err := os.Open(path)
@@ -191,6 +215,10 @@ func ParseMTF(path string) (*MTFData, error) {
 				if val != "" {
 					data.Quirks = append(data.Quirks, val)
 				}
+			case "weaponquirk":
+				if wq, ok := parseWeaponQuirk(val); ok {
+					data.WeaponQuirks = append(data.WeaponQuirks, wq)
+				}
 			case "mass":
 				data.Mass, _ = strconv.Atoi(val)
 			case "engine":
@@ -46,6 +46,12 @@ type MechListItem struct {
 	HeatNeutralRange       string  `json:"heat_neutral_range,omitempty"`
 	GameDamage             float64 `json:"game_damage"`
 	CombatRating           float64 `json:"combat_rating"`
+	CombatRatingCI         float64 `json:"combat_rating_ci"`  // 95% half-width
+	Elo                    float64 `json:"elo,omitempty"`     // in the ?pool= tournament
+	OpenCR                 float64 `json:"open_cr,omitempty"` // combat rating by terrain class
+	MixedCR                float64 `json:"mixed_cr,omitempty"`
+	DenseCR                float64 `json:"dense_cr,omitempty"`
+	UrbanCR                float64 `json:"urban_cr,omitempty"`
 	EngineType             string  `json:"engine_type,omitempty"`
 	EngineRating      int     `json:"engine_rating,omitempty"`
 	HeatSinkCount     int     `json:"heat_sink_count,omitempty"`
@@ -113,8 +119,22 @@ type VariantStats struct {
 	EffHeatNeutralDamage     float64 `json:"effective_heat_neutral_damage"`
 	HasTargetingComputer     bool    `json:"has_targeting_computer"`
 	CombatRating             float64 `json:"combat_rating,omitempty"`
+	CombatRatingCI           float64 `json:"combat_rating_ci,omitempty"`
 	OffenseTurns             float64 `json:"offense_turns,omitempty"`
 	DefenseTurns             float64 `json:"defense_turns,omitempty"`
+
+	// The CR against each baseline of the panel it was measured against
+	BaselineRatings []BaselineRating `json:"baseline_ratings,omitempty"`
+}
+
+// BaselineRating is a variant's combat rating against one baseline opponent.
+type BaselineRating struct {
+	BaselineID     int     `json:"baseline_id"`
+	Name           string  `json:"name"`
+	ModelCode      string  `json:"model_code"`
+	Weight         float64 `json:"weight"`
+	CombatRating   float64 `json:"combat_rating"`
+	CombatRatingCI float64 `json:"combat_rating_ci"`
 }
 
 type PhysicalModelInfo struct {
@@ -134,6 +154,40 @@ type ExternalRating struct {
 	Notes  string `json:"notes,omitempty"`
 }
 
+// VariantQuirk is a design quirk; Location and Weapon are set for weapon quirks.
+type VariantQuirk struct {
+	Quirk     string `json:"quirk"`
+	Label     string `json:"label"`
+	Location  string `json:"location,omitempty"`
+	Weapon    string `json:"weapon,omitempty"`
+	Simulated bool   `json:"simulated"` // affects combat rating and sim results
+}
+
+// MatchupRecord is a variant's tournament record against one opponent.
+type MatchupRecord struct {
+	ID        int     `json:"id"`
+	ModelCode string  `json:"model_code"`
+	Name      string  `json:"name"`
+	Elo       float64 `json:"elo"`
+	Wins      int     `json:"wins"`
+	Losses    int     `json:"losses"`
+	Draws     int     `json:"draws"`
+	WinRate   float64 `json:"win_rate"` // a draw counts half
+}
+
+// MechMatchups is a variant's tournament rating with its best and worst
+// matchups.
+type MechMatchups struct {
+	Pool   string          `json:"pool"`
+	Elo    float64         `json:"elo"`
+	Games  int             `json:"games"`
+	Wins   int             `json:"wins"`
+	Losses int             `json:"losses"`
+	Draws  int             `json:"draws"`
+	Best   []MatchupRecord `json:"best"`
+	Worst  []MatchupRecord `json:"worst"`
+}
+
 type MechDetail struct {
 	MechListItem
 	ChassisID       int                `json:"chassis_id"`
@@ -142,4 +196,5 @@ type MechDetail struct {
 	Equipment       []VariantEquipment `json:"equipment,omitempty"`
 	Models          []PhysicalModelInfo `json:"models,omitempty"`
 	ExternalRatings []ExternalRating   `json:"external_ratings,omitempty"`
+	Quirks          []VariantQuirk     `json:"quirks,omitempty"`
 }
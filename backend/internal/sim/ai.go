@@ -0,0 +1,175 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// ─── AI strategies ──────────────────────────────────────────────────────────
+//
+// An AI makes a 'Mech's decisions: where to move, which weapons to fire, and
+// whether to charge or DFA instead. Each MechState carries its own, so every
+// unit in a duel or a battle can play differently; nil plays as the
+// Tactician. Strategies hold no state between calls.
+
+// AI decides how a 'Mech plays.
+type AI interface {
+	// ChooseMove picks one of myOptions (all of me's moves if nil). When
+	// second is true op has already moved to opPos/opFacing; otherwise op
+	// still has opOptions to choose from.
+	ChooseMove(board *Board, me, op *MechState2, second bool, opPos HexCoord, opFacing int,
+		opOptions, myOptions []ReachableHex, rng *rand.Rand) ReachableHex
+	// ChooseFire picks the weapons m fires at t and returns their indexes
+	// and total heat.
+	ChooseFire(m *MechState, board *Board, t *MechState, dist, baseTarget int) ([]int, int)
+	// ChoosePhysical decides, before weapon fire, whether m charges or
+	// DFAs t instead of shooting; it sets m.DeclaredPhysical. weaponEV is
+	// the expected damage of a normal volley.
+	ChoosePhysical(m, t *MechState, dist int, weaponEV float64) bool
+	// ChooseEW switches m's stealth armor and Null-Signature System on or
+	// off for the turn once everyone has moved. foes are the enemies still
+	// in the fight.
+	ChooseEW(m *MechState, board *Board, foes []*MechState)
+}
+
+// Strategies are the named AIs the CLI and the API accept.
+var Strategies = map[string]AI{
+	"tactician": Tactician{},
+	"brawler":   Brawler{},
+	"sniper":    Sniper{},
+}
+
+// StrategyKeys lists the strategies in sorted order.
+func StrategyKeys() []string {
+	keys := make([]string, 0, len(Strategies))
+	for k := range Strategies {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseStrategy looks up a strategy by name. An empty name is the
+// Tactician.
+func ParseStrategy(name string) (AI, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Tactician{}, nil
+	}
+	ai, ok := Strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (have %s)", name, strings.Join(StrategyKeys(), ", "))
+	}
+	return ai, nil
+}
+
+func (m *MechState) ai() AI {
+	if m.AI == nil {
+		return Tactician{}
+	}
+	return m.AI
+}
+
+// Tactician is the default AI: minimax movement over PositionScore,
+// heat-aware EV weapon selection, and a charge or DFA when it beats the
+// volley.
+type Tactician struct{}
+
+func (Tactician) ChooseMove(board *Board, me, op *MechState2, second bool, opPos HexCoord, opFacing int,
+	opOptions, myOptions []ReachableHex, rng *rand.Rand) ReachableHex {
+	return ChooseMovement(board, me, op, second, opPos, opFacing, opOptions, rng, myOptions)
+}
+
+func (Tactician) ChooseFire(m *MechState, board *Board, t *MechState, dist, baseTarget int) ([]int, int) {
+	return selectWeaponsEV(m, board, t, dist, baseTarget)
+}
+
+func (Tactician) ChoosePhysical(m, t *MechState, dist int, weaponEV float64) bool {
+	return m.declarePhysical(t, dist, weaponEV)
+}
+
+func (Tactician) ChooseEW(m *MechState, board *Board, foes []*MechState) {
+	m.engageEW(board, foes)
+}
+
+// Brawler always closes: it takes the reachable hex nearest the opponent,
+// using PositionScore only to break ties, and charges or DFAs whenever that
+// is worth half a volley.
+type Brawler struct{ Tactician }
+
+func (Brawler) ChooseMove(board *Board, me, op *MechState2, second bool, opPos HexCoord, opFacing int,
+	opOptions, myOptions []ReachableHex, rng *rand.Rand) ReachableHex {
+	return pickMove(board, me, op, opPos, opFacing, myOptions, func(h ReachableHex, score float64) float64 {
+		return score - 1000*float64(HexDistance(h.Coord, opPos))
+	})
+}
+
+func (Brawler) ChoosePhysical(m, t *MechState, dist int, weaponEV float64) bool {
+	return m.declarePhysical(t, dist, weaponEV/2)
+}
+
+// Sniper holds its optimal range and cover: PositionScore less 3 per hex
+// off its optimal range and half the opponent's expected damage again. It
+// fires only what keeps its heat under 5 and never charges or DFAs.
+type Sniper struct{ Tactician }
+
+func (Sniper) ChooseMove(board *Board, me, op *MechState2, second bool, opPos HexCoord, opFacing int,
+	opOptions, myOptions []ReachableHex, rng *rand.Rand) ReachableHex {
+	opMove := ReachableHex{Coord: opPos, Facing: opFacing, Mode: ModeWalk}
+	return pickMove(board, me, op, opPos, opFacing, myOptions, func(h ReachableHex, score float64) float64 {
+		dist := HexDistance(h.Coord, opPos)
+		score -= 3 * math.Abs(float64(dist-optimalRange(me)))
+		if los := CheckLOS(board, opPos, h.Coord); los.CanSee {
+			score -= 0.5 * expectedDamage(op, dist, los, opMove, h.Coord, h.Facing)
+		}
+		return score
+	})
+}
+
+func (Sniper) ChooseFire(m *MechState, board *Board, t *MechState, dist, baseTarget int) ([]int, int) {
+	fire, _ := selectWeaponsEV(m, board, t, dist, baseTarget)
+	room := 4 + m.dissipation(board.Conditions) - m.Heat
+	kept, heat := fire[:0], 0
+	for _, i := range fire {
+		h := effectiveWeaponHeat(&m.Weapons[i])
+		if h > 0 && heat+h > room {
+			continue
+		}
+		kept = append(kept, i)
+		heat += h
+	}
+	return kept, heat
+}
+
+func (Sniper) ChoosePhysical(m, t *MechState, dist int, weaponEV float64) bool {
+	m.DeclaredPhysical, m.PhysicalTarget = physNone, nil
+	return false
+}
+
+// pickMove faces each option toward opPos and returns the one that scores
+// best, where score adjusts PositionScore.
+func pickMove(board *Board, me, op *MechState2, opPos HexCoord, opFacing int, options []ReachableHex,
+	score func(h ReachableHex, positionScore float64) float64) ReachableHex {
+	if options == nil {
+		options = collectAllMoveOptions(board, me)
+	}
+	if len(options) == 0 {
+		return ReachableHex{Coord: me.Pos, Facing: me.Facing, Mode: ModeStand}
+	}
+	// options may be the other side's list too, so each is faced on a copy
+	lc := newLOSCache()
+	best, bestScore := options[0], math.Inf(-1)
+	for _, h := range options {
+		if h.Coord == opPos {
+			continue
+		}
+		h.Facing = bearingToFacing(h.Coord, opPos)
+		if s := score(h, PositionScore(board, me, h, op, opPos, opFacing, lc)); s > bestScore {
+			best, bestScore = h, s
+		}
+	}
+	return best
+}
@@ -0,0 +1,96 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// ─── Baseline panels ────────────────────────────────────────────────────────
+//
+// A combat rating measures a mech against a baseline opponent that scores
+// 5.0 by definition. A panel spreads that over several baselines, say one
+// mech per weight class from an era: the mech is rated against each, and its
+// CR is the weighted mean of those sub-scores. Every baseline plays its own
+// mirror match evenly, so each sub-score uses a baseline ratio of 1.0.
+
+// Baseline is one opponent of a panel.
+type Baseline struct {
+	ID     int // variant ID; 0 if it isn't in the database
+	Name   string
+	Mech   *MechState
+	Weight float64
+	Turns  float64 // median turns of its mirror match
+}
+
+// PanelRating is a mech's rating against a panel.
+type PanelRating struct {
+	Rating          // weighted over the panel
+	Sub    []Rating // by baseline
+}
+
+// RatePanel rates m against each baseline of panel with RateMech. A mech
+// that is itself baseline i (selfID == panel[i].ID) gets exactly 5.0 there
+// without playing it. cfg.Baseline is ignored.
+func RatePanel(preBoards *PrecomputedBoards, m *MechState, selfID int, panel []Baseline, cfg RatingConfig, rng *rand.Rand) PanelRating {
+	r := PanelRating{Sub: make([]Rating, len(panel))}
+	weights := make([]float64, len(panel))
+	for i, b := range panel {
+		weights[i] = b.Weight
+		if selfID != 0 && b.ID == selfID {
+			r.Sub[i] = MirrorRating(b.Turns)
+			continue
+		}
+		cfg.Baseline = b.Mech
+		r.Sub[i] = RateMech(preBoards, m, cfg, rng)
+	}
+	r.Rating = CombineRatings(r.Sub, weights)
+	return r
+}
+
+// MirrorRating is a baseline's rating against itself.
+func MirrorRating(turns float64) Rating {
+	exact := func(v float64) Estimate { return Estimate{v, v, v} }
+	return Rating{Offense: exact(turns), Defense: exact(turns), CR: exact(5.0)}
+}
+
+// CombineRatings is the weighted mean of independent ratings. Its intervals
+// are centred on the mean with half-widths added in quadrature, which is
+// what independent normal errors would give; a single rating is returned
+// unchanged.
+func CombineRatings(sub []Rating, weights []float64) Rating {
+	if len(sub) == 1 {
+		return sub[0]
+	}
+	var r Rating
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	combine := func(pick func(Rating) Estimate) Estimate {
+		mean, variance := 0.0, 0.0
+		for i, s := range sub {
+			e := pick(s)
+			mean += weights[i] / total * e.Value
+			variance += math.Pow(weights[i]/total*e.HalfWidth(), 2)
+		}
+		hw := math.Sqrt(variance)
+		return Estimate{mean, mean - hw, mean + hw}
+	}
+	r.Offense = combine(func(s Rating) Estimate { return s.Offense })
+	r.Defense = combine(func(s Rating) Estimate { return s.Defense })
+	r.CR = combine(func(s Rating) Estimate { return s.CR })
+	for _, s := range sub {
+		r.Sims += s.Sims
+	}
+	return r
+}
+
+// WeightedMean is the mean of xs under the panel's weights.
+func WeightedMean(xs []float64, panel []Baseline) float64 {
+	sum, total := 0.0, 0.0
+	for i, b := range panel {
+		sum += b.Weight * xs[i]
+		total += b.Weight
+	}
+	return sum / total
+}
@@ -0,0 +1,242 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ─── Board generator ────────────────────────────────────────────────────────
+//
+// Generated boards stand in for the MegaMek board set on machines without a
+// checkout, and give pools of one kind of terrain. A profile says how much
+// of a board is hills, water, woods, rough and city; the generator grows
+// each as clumps from random seeds, so a board reads like terrain rather
+// than noise. The same profile, size and RNG stream always give the same
+// board.
+
+// BoardProfile is the terrain mix of a generated board. Shares are of the
+// board's hexes.
+type BoardProfile struct {
+	Hills        int     // number of hills
+	MaxElevation int     // height of the tallest hill
+	Water        float64 // share of water; lakes lie in low ground
+	Woods        float64 // share of woods, a third of it heavy
+	Rough        float64 // share of rough
+	Urban        float64 // share of buildings, in blocks between streets
+}
+
+// BoardProfiles are the named profiles calc-cr-v2 can generate pools of.
+var BoardProfiles = map[string]BoardProfile{
+	"open":  {Hills: 1, MaxElevation: 1, Woods: 0.04, Rough: 0.03},
+	"woods": {Hills: 2, MaxElevation: 2, Woods: 0.45, Rough: 0.05},
+	"urban": {Woods: 0.02, Rough: 0.02, Urban: 0.35},
+	"hills": {Hills: 5, MaxElevation: 4, Water: 0.12, Woods: 0.12, Rough: 0.05},
+}
+
+// BoardProfileKeys lists the profiles in sorted order.
+func BoardProfileKeys() []string {
+	keys := make([]string, 0, len(BoardProfiles))
+	for k := range BoardProfiles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseBoardProfile looks up a profile by name.
+func ParseBoardProfile(key string) (BoardProfile, error) {
+	p, ok := BoardProfiles[strings.ToLower(strings.TrimSpace(key))]
+	if !ok {
+		return BoardProfile{}, fmt.Errorf("unknown board profile %q (have %s)", key, strings.Join(BoardProfileKeys(), ", "))
+	}
+	return p, nil
+}
+
+// GenerateBoardPool generates n standard 16×17 boards, taking the profiles
+// in turn. Board i uses RNG stream (seed, i).
+func GenerateBoardPool(profiles []BoardProfile, n int, seed uint64) []*Board {
+	boards := make([]*Board, n)
+	for i := range boards {
+		rng := rand.New(rand.NewPCG(seed, uint64(i)))
+		boards[i] = GenerateBoard(profiles[i%len(profiles)], 16, 17, rng)
+	}
+	return boards
+}
+
+// GenerateBoard generates a w×h board of profile p.
+func GenerateBoard(p BoardProfile, w, h int, rng *rand.Rand) *Board {
+	b := NewBoard(w, h)
+	b.buildGrid()
+	total := float64(w * h)
+
+	// Hills: cones of a random height and radius
+	for i := 0; i < p.Hills; i++ {
+		top := 1 + rng.IntN(max(p.MaxElevation, 1))
+		radius := 2 + rng.IntN(3)
+		center := b.randomHex(rng)
+		for j := range b.Grid {
+			hex := &b.Grid[j]
+			d := HexDistance(center, hex.Coord)
+			if d > radius {
+				continue
+			}
+			hex.Elevation = max(hex.Elevation, top-d*top/(radius+1))
+		}
+	}
+
+	free := func(hex *Hex) bool { return len(hex.Terrain) == 0 }
+
+	// Water in low ground, deep where a hex is surrounded by it
+	if n := int(p.Water * total); n > 0 {
+		b.growClumps(rng, max(n/8, 1), n, func(hex *Hex) bool { return free(hex) && hex.Elevation == 0 },
+			func(hex *Hex) { hex.Terrain = append(hex.Terrain, TerrainFeature{TerrainWater, 1}) })
+		for j := range b.Grid {
+			hex := &b.Grid[j]
+			if hex.WaterDepth() == 0 {
+				continue
+			}
+			deep := true
+			for _, n := range Neighbors(hex.Coord) {
+				if nb := b.Get(n); nb != nil && nb.WaterDepth() == 0 {
+					deep = false
+				}
+			}
+			if deep {
+				hex.Terrain[0].Level = 2
+			}
+		}
+	}
+
+	// City blocks: buildings between streets every fourth column and row
+	if p.Urban > 0 {
+		for j := range b.Grid {
+			hex := &b.Grid[j]
+			if hex.Coord.Col%4 == 0 || hex.Coord.Row%4 == 0 {
+				hex.Elevation = 0
+				hex.Terrain = []TerrainFeature{{TerrainPavement, 1}}
+			}
+		}
+		b.growClumps(rng, max(int(p.Urban*total)/5, 1), int(p.Urban*total), free, func(hex *Hex) {
+			class := 1 + rng.IntN(3)
+			height := 1 + rng.IntN(3)
+			hex.Terrain = append(hex.Terrain,
+				TerrainFeature{TerrainBuilding, class},
+				TerrainFeature{TerrainBldgCF, defaultCF[class]},
+				TerrainFeature{TerrainBldgElev, height})
+		})
+	}
+
+	// Woods in stands, a third of them heavy
+	if n := int(p.Woods * total); n > 0 {
+		b.growClumps(rng, max(n/6, 1), n, free, func(hex *Hex) {
+			level := 1
+			if rng.IntN(3) == 0 {
+				level = 2
+			}
+			hex.Terrain = append(hex.Terrain, TerrainFeature{TerrainWoods, level})
+		})
+	}
+
+	// Rough in scattered hexes
+	for i := int(p.Rough * total); i > 0; i-- {
+		if hex := b.Get(b.randomHex(rng)); free(hex) {
+			hex.Terrain = append(hex.Terrain, TerrainFeature{TerrainRough, 1})
+		}
+	}
+	return b
+}
+
+func (b *Board) randomHex(rng *rand.Rand) HexCoord {
+	return HexCoord{Col: 1 + rng.IntN(b.Width), Row: 1 + rng.IntN(b.Height)}
+}
+
+// growClumps applies set to up to n hexes that ok accepts, grown outwards
+// from the given number of random seed hexes.
+func (b *Board) growClumps(rng *rand.Rand, seeds, n int, ok func(*Hex) bool, set func(*Hex)) {
+	var frontier []HexCoord
+	for i := 0; i < seeds*4 && len(frontier) < seeds; i++ {
+		if c := b.randomHex(rng); ok(b.Get(c)) {
+			frontier = append(frontier, c)
+		}
+	}
+	for n > 0 && len(frontier) > 0 {
+		i := rng.IntN(len(frontier))
+		c := frontier[i]
+		hex := b.Get(c)
+		if !ok(hex) {
+			frontier = append(frontier[:i], frontier[i+1:]...)
+			continue
+		}
+		set(hex)
+		n--
+		for _, nb := range Neighbors(c) {
+			if b.InBounds(nb) && rng.IntN(2) == 0 {
+				frontier = append(frontier, nb)
+			}
+		}
+	}
+}
+
+// ─── Board writer ───────────────────────────────────────────────────────────
+
+// terrainNames are the MegaMek names of the terrain the sim models.
+var terrainNames = map[TerrainType]string{
+	TerrainWoods:    "woods",
+	TerrainWater:    "water",
+	TerrainRough:    "rough",
+	TerrainPavement: "pavement",
+	TerrainRoad:     "road",
+	TerrainBuilding: "building",
+	TerrainSand:     "sand",
+	TerrainSwamp:    "swamp",
+	TerrainMud:      "mud",
+	TerrainRubble:   "rubble",
+	TerrainFire:     "fire",
+	TerrainSmoke:    "smoke",
+	TerrainBldgCF:   "bldg_cf",
+	TerrainBldgElev: "bldg_elev",
+}
+
+// WriteBoard writes b in MegaMek's .board format, which ParseBoard reads
+// back. Woods get the foliage height MegaMek expects with them.
+func (b *Board) WriteBoard(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "size %d %d\n", b.Width, b.Height)
+	for row := 1; row <= b.Height; row++ {
+		for col := 1; col <= b.Width; col++ {
+			hex := b.Get(HexCoord{Col: col, Row: row})
+			var features []string
+			elevation := 0
+			if hex != nil {
+				elevation = hex.Elevation
+				for _, f := range hex.Terrain {
+					features = append(features, fmt.Sprintf("%s:%d", terrainNames[f.Type], f.Level))
+					if f.Type == TerrainWoods {
+						features = append(features, "foliage_elev:2")
+					}
+				}
+			}
+			fmt.Fprintf(bw, "hex %02d%02d %d \"%s\" \"\"\n", col, row, elevation, strings.Join(features, ";"))
+		}
+	}
+	fmt.Fprintln(bw, "end")
+	return bw.Flush()
+}
+
+// SaveBoard writes b to a .board file at path.
+func (b *Board) SaveBoard(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.WriteBoard(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	"math"
@@ -29,6 +29,7 @@ const (
 	catPlasmaCannon
 	catPlasmaRifle
 	catVSP
+	catTAG
 )
 
 func categorizeWeapon(name string) weaponCategory {
@@ -66,6 +67,8 @@ func categorizeWeapon(name string) weaponCategory {
 		return catLRM
 	case strings.Contains(upper, "VSP") || strings.Contains(upper, "VARIABLE SPEED PULSE"):
 		return catVSP
+	case strings.HasSuffix(strings.ReplaceAll(upper, " ", ""), "TAG"):
+		return catTAG
 	default:
 		return catNormal
 	}
@@ -96,6 +99,7 @@ type SimWeapon struct {
 	RackSize   int
 	Type       string // energy, ballistic, missile, artillery
 	AmmoKey    string
+	Loaded     munition // munition of the shot being resolved
 	Destroyed  bool
 	Jammed     bool
 }
@@ -188,7 +192,12 @@ func amsInterceptStreak(rackSize int, defender *MechState) int {
 	return clusterTable[5][colIdx] // roll 7 = row index 5
 }
 
-func artemisBonus(m *MechState) int {
+// artemisBonus is the cluster roll bonus for a shot of mu. Artemis only
+// guides Artemis-capable ammo, and not under enemy ECM.
+func artemisBonus(m *MechState, mu munition) int {
+	if mu != munArtemis || m.UnderECM != ecmNone {
+		return 0
+	}
 	if m.HasArtemisV {
 		return 3
 	}
@@ -254,12 +263,19 @@ func canWeaponFire(w *SimWeapon, mechPos HexCoord, facing int, torsoTwist int, t
 // resolveWeaponFire2D resolves weapon fire with 2D hex grid awareness.
 // Returns total damage dealt (for PSR tracking).
 func resolveWeaponFire2D(w *SimWeapon, target int, isRear bool, attacker *MechState, defender *MechState, rng *rand.Rand) int {
+	attacker.FiredFrom[w.Location] = true
 	switch w.Category {
 	case catArrowIV:
 		return resolveArrowIV(w, target, defender, rng)
 	case catStreakSRM:
+		if attacker.UnderECM == ecmAngel {
+			return resolveSRM(w, target, isRear, attacker, defender, rng)
+		}
 		return resolveStreakSRM(w, target, isRear, attacker, defender, rng)
 	case catStreakLRM:
+		if attacker.UnderECM == ecmAngel {
+			return resolveLRM(w, target, isRear, attacker, defender, rng)
+		}
 		return resolveStreakLRM(w, target, isRear, attacker, defender, rng)
 	case catRocketLauncher:
 		return resolveRocketLauncher(w, target, isRear, defender, rng)
@@ -290,7 +306,10 @@ func resolveWeaponFire2D(w *SimWeapon, target int, isRear bool, attacker *MechSt
 	default:
 		if roll2d6(rng) >= target {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			if w.Loaded == munArmorPiercing {
+				defender.armorPiercing(loc, apCritMod(w), rng)
+			}
 			return w.Damage
 		}
 		return 0
@@ -306,14 +325,14 @@ func resolveArrowIV(w *SimWeapon, target int, defender *MechState, rng *rand.Ran
 				d = dmg
 			}
 			loc := rollHitLocation(false, rng)
-			defender.applyDamage(loc, d, false, rng)
+			defender.weaponHit(loc, d, false, rng)
 			dmgDealt += d
 		}
 	} else {
 		if roll1d6(rng) == 1 {
 			for dmg := 10; dmg > 0; dmg -= 5 {
 				loc := rollHitLocation(false, rng)
-				defender.applyDamage(loc, 5, false, rng)
+				defender.weaponHit(loc, 5, false, rng)
 				dmgDealt += 5
 			}
 		}
@@ -327,7 +346,7 @@ func resolveStreakSRM(w *SimWeapon, target int, isRear bool, attacker *MechState
 		hits := amsInterceptStreak(w.RackSize, defender)
 		for m := 0; m < hits; m++ {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += 2
 		}
 	}
@@ -346,7 +365,7 @@ func resolveUltraAC(w *SimWeapon, target int, isRear bool, defender *MechState,
 		hits := clusterHits(2, rng) // cluster table column "2" → 1 or 2 hits
 		for h := 0; h < hits; h++ {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += w.Damage
 		}
 	}
@@ -433,7 +452,7 @@ func resolveRotaryAC(w *SimWeapon, target int, isRear bool, defender *MechState,
 		hits := clusterHits(shots, rng)
 		for h := 0; h < hits; h++ {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += w.Damage
 		}
 	}
@@ -441,12 +460,21 @@ func resolveRotaryAC(w *SimWeapon, target int, isRear bool, defender *MechState,
 }
 
 func resolveLBX(w *SimWeapon, target int, isRear bool, defender *MechState, rng *rand.Rand) int {
+	if w.Loaded != munCluster {
+		// Slug: one hit for full damage, like a standard AC
+		if roll2d6(rng) >= target {
+			loc := rollHitLocation(isRear, rng)
+			defender.weaponHit(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			return w.Damage
+		}
+		return 0
+	}
 	dmgDealt := 0
 	if roll2d6(rng) >= target {
 		hits := clusterHits(w.RackSize, rng)
 		for h := 0; h < hits; h++ {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, 1, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, 1, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt++
 		}
 	}
@@ -457,7 +485,7 @@ func resolveLRM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 	dmgDealt := 0
 	if roll2d6(rng) >= target {
 		// AMS applies -4 to cluster roll (BMM p.118)
-		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker)+amsClusterMod(defender), rng)
+		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker, w.Loaded)+amsClusterMod(defender), rng)
 		if hits <= 0 {
 			return 0
 		}
@@ -467,7 +495,7 @@ func resolveLRM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 				grp = hits
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			hits -= grp
 		}
@@ -478,10 +506,17 @@ func resolveLRM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 func resolveSRM(w *SimWeapon, target int, isRear bool, attacker *MechState, defender *MechState, rng *rand.Rand) int {
 	dmgDealt := 0
 	if roll2d6(rng) >= target {
-		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker)+amsClusterMod(defender), rng)
+		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker, w.Loaded)+amsClusterMod(defender), rng)
+		if w.Loaded == munInferno {
+			defender.HeatPenalty += 2 * hits
+			return 0
+		}
 		for h := 0; h < hits; h++ {
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			if w.Loaded == munTandem {
+				defender.armorPiercing(loc, -2, rng)
+			}
 			dmgDealt += 2
 		}
 	}
@@ -504,7 +539,7 @@ func resolveMRM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 				grp = totalDmg
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			totalDmg -= grp
 		}
@@ -530,7 +565,7 @@ func resolveHAG(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 				grp = hits
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			hits -= grp
 		}
@@ -558,7 +593,7 @@ func resolveATM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 
 	dmgDealt := 0
 	if roll2d6(rng) >= target {
-		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker)+amsClusterMod(defender), rng)
+		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker, w.Loaded)+amsClusterMod(defender), rng)
 		// ATM is C5: total damage = hits * dmgPerMissile, apply in 5-point groups
 		totalDmg := hits * dmgPerMissile
 		for totalDmg > 0 {
@@ -567,7 +602,7 @@ func resolveATM(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 				grp = totalDmg
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			totalDmg -= grp
 		}
@@ -591,12 +626,12 @@ func resolveMML(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 
 	dmgDealt := 0
 	if roll2d6(rng) >= target {
-		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker)+amsClusterMod(defender), rng)
+		hits := clusterHitsWithBonus(w.RackSize, artemisBonus(attacker, w.Loaded)+amsClusterMod(defender), rng)
 		if useSRMMode {
 			// SRM mode: 2 damage per missile, individual hit locations
 			for h := 0; h < hits; h++ {
 				loc := rollHitLocation(isRear, rng)
-				defender.applyDamage(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+				defender.weaponHit(loc, 2, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 				dmgDealt += 2
 			}
 		} else {
@@ -607,7 +642,7 @@ func resolveMML(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 					grp = hits
 				}
 				loc := rollHitLocation(isRear, rng)
-				defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+				defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 				dmgDealt += grp
 				hits -= grp
 			}
@@ -627,7 +662,7 @@ func resolveStreakLRM(w *SimWeapon, target int, isRear bool, attacker *MechState
 				grp = hits
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			hits -= grp
 		}
@@ -648,7 +683,7 @@ func resolveRocketLauncher(w *SimWeapon, target int, isRear bool, defender *Mech
 				grp = hits
 			}
 			loc := rollHitLocation(isRear, rng)
-			defender.applyDamage(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+			defender.weaponHit(loc, grp, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 			dmgDealt += grp
 			hits -= grp
 		}
@@ -669,7 +704,7 @@ func resolvePlasmaRifle(w *SimWeapon, target int, isRear bool, defender *MechSta
 	// Plasma Rifle: 10 damage + heat to target on hit.
 	if roll2d6(rng) >= target {
 		loc := rollHitLocation(isRear, rng)
-		defender.applyDamage(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+		defender.weaponHit(loc, w.Damage, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 		heatApplied := roll2d6(rng)
 		defender.HeatPenalty += heatApplied
 		return w.Damage
@@ -725,7 +760,7 @@ func resolveVSP(w *SimWeapon, target int, isRear bool, attacker *MechState, defe
 
 	if roll2d6(rng) >= adjustedTarget {
 		loc := rollHitLocation(isRear, rng)
-		defender.applyDamage(loc, dmg, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
+		defender.weaponHit(loc, dmg, isRear && (loc == LocCT || loc == LocLT || loc == LocRT), rng)
 		return dmg
 	}
 	return 0
@@ -749,6 +784,14 @@ func effectiveWeaponHeat(w *SimWeapon) int {
 // selectWeaponsEV selects which weapons to fire based on EV-based heat management.
 // Returns indices into mech.Weapons and total weapon heat.
 func selectWeaponsEV(mech *MechState, board *Board, defender *MechState, dist int, baseTarget int) ([]int, int) {
+	return selectWeapons(mech, board, defender, dist, baseTarget, func(w *SimWeapon) (int, bool) {
+		return mech.attackMod(w, defender, dist), true
+	})
+}
+
+// selectWeapons is selectWeaponsEV with the per-weapon to-hit modifier
+// supplied by the caller; weapons it reports false for don't fire.
+func selectWeapons(mech *MechState, board *Board, defender *MechState, dist int, baseTarget int, mod func(w *SimWeapon) (int, bool)) ([]int, int) {
 	type weaponFire struct {
 		idx    int
 		expDmg float64
@@ -762,16 +805,20 @@ func selectWeaponsEV(mech *MechState, board *Board, defender *MechState, dist in
 		if w.Destroyed || w.Jammed {
 			continue
 		}
-		if w.AmmoKey != "" && mech.Ammo[w.AmmoKey] <= 0 {
+		if !mech.hasAmmo(w) {
 			continue
 		}
 
 		// Check if weapon can fire at target (arc constraint)
-		if !canWeaponFire(w, mech.Pos, mech.Facing, mech.TorsoTwist, defender.Pos) {
+		if mech.submerged(w.Location) || !canWeaponFire(w, mech.Pos, mech.Facing, mech.TorsoTwist, defender.Pos) {
 			continue
 		}
 
-		ed := weaponExpectedDamage(w, dist, baseTarget)
+		md, ok := mod(w)
+		if !ok {
+			continue
+		}
+		ed := mech.expectedDamage(w, defender, dist, baseTarget+md)
 		if ed <= 0 {
 			continue
 		}
@@ -793,7 +840,7 @@ func selectWeaponsEV(mech *MechState, board *Board, defender *MechState, dist in
 
 	var firingWeapons []int
 	weaponHeatTotal := 0
-	heatThisMod := heatToHitMod(mech.Heat)
+	heatThisMod := heatToHitMod(mech.Heat, board.Conditions)
 
 	for _, c := range candidates {
 		if c.heat == 0 {
@@ -802,12 +849,12 @@ func selectWeaponsEV(mech *MechState, board *Board, defender *MechState, dist in
 		}
 
 		newWeaponHeat := weaponHeatTotal + c.heat
-		projectedHeat := mech.Heat + newWeaponHeat - mech.Dissipation
+		projectedHeat := mech.Heat + newWeaponHeat - mech.dissipation(board.Conditions)
 		if projectedHeat < 0 {
 			projectedHeat = 0
 		}
 
-		oldProjectedHeat := mech.Heat + weaponHeatTotal - mech.Dissipation
+		oldProjectedHeat := mech.Heat + weaponHeatTotal - mech.dissipation(board.Conditions)
 		if oldProjectedHeat < 0 {
 			oldProjectedHeat = 0
 		}
@@ -816,19 +863,22 @@ func selectWeaponsEV(mech *MechState, board *Board, defender *MechState, dist in
 		newCost := heatCostEV(projectedHeat, avgTurnDmg, ammoExpDmg, mech.WalkMP, avgTurnDmg)
 		marginalCost := newCost - oldCost
 
-		oldToHitMod := heatToHitMod(oldProjectedHeat)
-		newToHitMod := heatToHitMod(projectedHeat)
+		oldToHitMod := heatToHitMod(oldProjectedHeat, board.Conditions)
+		newToHitMod := heatToHitMod(projectedHeat, board.Conditions)
 		toHitPenaltyCost := 0.0
 		if newToHitMod > oldToHitMod {
 			for _, fi := range firingWeapons {
 				fw := &mech.Weapons[fi]
-				oldDmg := weaponExpectedDamage(fw, dist, baseTarget+oldToHitMod-heatThisMod)
-				newDmg := weaponExpectedDamage(fw, dist, baseTarget+newToHitMod-heatThisMod)
+				spaMod, _ := mod(fw)
+				oldDmg := weaponExpectedDamage(fw, dist, baseTarget+oldToHitMod-heatThisMod+spaMod)
+				newDmg := weaponExpectedDamage(fw, dist, baseTarget+newToHitMod-heatThisMod+spaMod)
 				toHitPenaltyCost += oldDmg - newDmg
 			}
 		}
 
-		actualDmg := weaponExpectedDamage(&mech.Weapons[c.idx], dist, baseTarget+newToHitMod-heatThisMod)
+		cw := &mech.Weapons[c.idx]
+		cwMod, _ := mod(cw)
+		actualDmg := weaponExpectedDamage(cw, dist, baseTarget+newToHitMod-heatThisMod+cwMod)
 		marginalEV := actualDmg - marginalCost - toHitPenaltyCost
 
 		if marginalEV > 0 {
@@ -1028,13 +1078,13 @@ func calcExpectedDamage(m *MechState, dist int, baseTarget int, defTMM int) floa
 	total := 0.0
 	for i := range m.Weapons {
 		w := &m.Weapons[i]
-		if w.Destroyed || w.Jammed {
+		if w.Destroyed || w.Jammed || m.submerged(w.Location) {
 			continue
 		}
-		if w.AmmoKey != "" && m.Ammo[w.AmmoKey] <= 0 {
+		if !m.hasAmmo(w) {
 			continue
 		}
-		ed := weaponExpectedDamage(w, dist, baseTarget+defTMM)
+		ed := weaponExpectedDamage(w, dist, baseTarget+defTMM+m.weaponMod(w, dist))
 		total += ed
 	}
 	return total
@@ -0,0 +1,196 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ─── Environmental conditions ───────────────────────────────────────────────
+//
+// Tactical Operations light, weather, gravity, atmosphere and temperature.
+// The zero Conditions is a clear, standard day, so boards and configs that
+// never set them play exactly as before. Conditions ride on the Board, which
+// every phase of the sim already has in hand.
+
+type Light int
+
+const (
+	LightDay Light = iota
+	LightDusk
+	LightFullMoon
+	LightNight // moonless
+)
+
+type Fog int
+
+const (
+	FogNone  Fog = iota
+	FogLight     // limits sight range only, which the sim doesn't model
+	FogHeavy
+)
+
+type Rain int
+
+const (
+	RainNone Rain = iota
+	RainModerate
+	RainHeavy
+	RainDownpour
+)
+
+type Atmosphere int
+
+const (
+	AtmoStandard Atmosphere = iota
+	AtmoThin
+	AtmoVacuum
+)
+
+// Conditions are the environmental conditions for a game.
+type Conditions struct {
+	Light       Light
+	Fog         Fog
+	Rain        Rain
+	Gravity     float64 // in G; 0 means 1G
+	Atmosphere  Atmosphere
+	Temperature int // °C; -30 to 50 has no effect
+}
+
+// ConditionPresets are the named conditions the CLI rates every mech under
+// and the API accepts.
+var ConditionPresets = map[string]Conditions{
+	"dusk":         {Light: LightDusk},
+	"full_moon":    {Light: LightFullMoon},
+	"night":        {Light: LightNight},
+	"heavy_fog":    {Fog: FogHeavy},
+	"rain":         {Rain: RainModerate},
+	"downpour":     {Rain: RainDownpour},
+	"high_gravity": {Gravity: 1.5},
+	"low_gravity":  {Gravity: 0.5},
+	"vacuum":       {Atmosphere: AtmoVacuum},
+	"cold":         {Temperature: -50},
+	"hot":          {Temperature: 70},
+}
+
+// ConditionKeys lists the presets in sorted order.
+func ConditionKeys() []string {
+	keys := make([]string, 0, len(ConditionPresets))
+	for k := range ConditionPresets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseConditions combines comma-separated presets ("night,cold"). Later
+// presets override the fields they set. An empty spec is a standard day.
+func ParseConditions(spec string) (Conditions, error) {
+	var c Conditions
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		p, ok := ConditionPresets[key]
+		if !ok {
+			return Conditions{}, fmt.Errorf("unknown condition %q (have %s)", key, strings.Join(ConditionKeys(), ", "))
+		}
+		if p.Light != LightDay {
+			c.Light = p.Light
+		}
+		if p.Fog != FogNone {
+			c.Fog = p.Fog
+		}
+		if p.Rain != RainNone {
+			c.Rain = p.Rain
+		}
+		if p.Gravity != 0 {
+			c.Gravity = p.Gravity
+		}
+		if p.Atmosphere != AtmoStandard {
+			c.Atmosphere = p.Atmosphere
+		}
+		if p.Temperature != 0 {
+			c.Temperature = p.Temperature
+		}
+	}
+	if c.Atmosphere == AtmoVacuum && (c.Rain != RainNone || c.Fog != FogNone) {
+		return Conditions{}, fmt.Errorf("no weather in vacuum")
+	}
+	return c, nil
+}
+
+// toHitMod is the weapon attack modifier for light and weather.
+func (c Conditions) toHitMod() int {
+	mod := int(c.Light) // dusk +1, full moon +2, moonless night +3
+	if c.Fog == FogHeavy {
+		mod++
+	}
+	switch c.Rain {
+	case RainModerate, RainHeavy:
+		mod++
+	case RainDownpour:
+		mod += 2
+	}
+	return mod
+}
+
+// heat is the extra heat per turn from the temperature: +1 per 10°C (or
+// part) over 50°C, -1 per 10°C under -30°C.
+func (c Conditions) heat() int {
+	switch {
+	case c.Temperature > 50:
+		return (c.Temperature - 41) / 10
+	case c.Temperature < -30:
+		return -((-30 - c.Temperature + 9) / 10)
+	}
+	return 0
+}
+
+// moveCost is the extra MP to enter a hex on the ground: a downpour turns
+// every hex to mud.
+func (c Conditions) moveCost() int {
+	if c.Rain == RainDownpour {
+		return 1
+	}
+	return 0
+}
+
+// scaleMP applies gravity to walking and jumping MP: MP / G, rounded down.
+// Running MP is recomputed from the new walking MP.
+func (c Conditions) scaleMP(walk, run, jump int) (int, int, int) {
+	if c.Gravity == 0 || c.Gravity == 1 {
+		return walk, run, jump
+	}
+	w := int(math.Floor(float64(walk) / c.Gravity))
+	j := int(math.Floor(float64(jump) / c.Gravity))
+	r := run
+	if walk > 0 {
+		r = int(math.Ceil(float64(w) * 1.5))
+	}
+	return w, r, j
+}
+
+// WithConditions returns a copy of the board played under c.
+func (b *Board) WithConditions(c Conditions) *Board {
+	cp := *b
+	cp.Conditions = c
+	return &cp
+}
+
+// breachCheck rolls for a hull breach when a location in vacuum takes
+// structure damage: on 10+ the location is open to vacuum and everything in
+// it stops working.
+func (m *MechState) breachCheck(loc int, rng randSource) {
+	if !m.Vacuum || m.Breached[loc] || roll2d6(rng) < 10 {
+		return
+	}
+	m.Breached[loc] = true
+	for i := range m.Weapons {
+		if m.Weapons[i].Location == loc {
+			m.Weapons[i].Destroyed = true
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// ─── Confidence intervals ───────────────────────────────────────────────────
+//
+// A combat rating is a function of two sample medians, so its uncertainty is
+// estimated by bootstrap: resample the offense and defense turn counts with
+// replacement, recompute both medians and the CR, and take the middle 95% of
+// the results. Turn counts are whole numbers, so a plain median hardly moves
+// between resamples; the bootstrap works on the grouped (interpolated) median
+// instead and shifts its spread onto the plain one. RateMech keeps adding
+// rounds of sims until the CR interval is narrow enough, the sample cap is
+// reached or the time budget runs out.
+
+const (
+	// BootstrapResamples is the number of bootstrap resamples per interval.
+	BootstrapResamples = 1000
+	// ConfidenceLevel is the coverage of every interval.
+	ConfidenceLevel = 0.95
+)
+
+// Estimate is a point estimate with its confidence interval.
+type Estimate struct {
+	Value float64
+	Low   float64
+	High  float64
+}
+
+// HalfWidth is the ± of the interval.
+func (e Estimate) HalfWidth() float64 {
+	return (e.High - e.Low) / 2
+}
+
+// RatingConfig controls how long RateMech samples.
+type RatingConfig struct {
+	Baseline      *MechState
+	BaselineRatio float64
+	// TargetHalfWidth stops sampling once the CR interval is at most ± this;
+	// 0 runs the first round only.
+	TargetHalfWidth float64
+	// MaxSims caps the sims per side; 0 is no cap.
+	MaxSims int
+	// Budget caps the wall time spent after the first round; 0 is no cap.
+	Budget time.Duration
+}
+
+// Rating is a mech's combat rating with confidence intervals.
+type Rating struct {
+	Offense Estimate // median turns to kill the baseline
+	Defense Estimate // median turns to be killed by it
+	CR      Estimate
+	Sims    int // per side
+}
+
+// RateMech rates m against cfg.Baseline. The first round is the fixed
+// NumSimsPerBoard sims on each board pair that RunSimsBatch2DPre runs, so
+// its point estimates match; each further round runs as many again.
+func RateMech(preBoards *PrecomputedBoards, m *MechState, cfg RatingConfig, rng *rand.Rand) Rating {
+	var off, def []int
+	round := func() {
+		for _, b := range preBoards.Boards {
+			for s := 0; s < NumSimsPerBoard; s++ {
+				off = append(off, SimulateCombat2D(b, m, cfg.Baseline, rng))
+			}
+		}
+		for _, b := range preBoards.Boards {
+			for s := 0; s < NumSimsPerBoard; s++ {
+				def = append(def, SimulateCombat2D(b, cfg.Baseline, m, rng))
+			}
+		}
+	}
+
+	round()
+	r := bootstrapRating(off, def, cfg.BaselineRatio)
+	start := time.Now()
+	step := len(preBoards.Boards) * NumSimsPerBoard
+	for step > 0 && r.CR.HalfWidth() > cfg.TargetHalfWidth {
+		if cfg.MaxSims > 0 && len(off)+step > cfg.MaxSims {
+			break
+		}
+		if cfg.Budget > 0 && time.Since(start) > cfg.Budget {
+			break
+		}
+		round()
+		r = bootstrapRating(off, def, cfg.BaselineRatio)
+	}
+	return r
+}
+
+// bootstrapRating computes the medians and CR of the offense and defense
+// turn counts with percentile bootstrap intervals. The resampling is seeded
+// by the sample sizes so the same samples always give the same intervals.
+// Each resample moves a median by as much as it moves the grouped median.
+func bootstrapRating(off, def []int, baselineRatio float64) Rating {
+	r := Rating{Sims: len(off)}
+	if len(off) == 0 || len(def) == 0 {
+		return r
+	}
+	offMed, defMed := sampleMedian(off), sampleMedian(def)
+	r.Offense.Value, r.Defense.Value = offMed, defMed
+	r.CR.Value = CombatRating(offMed, defMed, baselineRatio)
+
+	rng := rand.New(rand.NewPCG(uint64(len(off)), uint64(len(def))))
+	counts := make([]int, MaxTurns+2)
+	offGrouped, defGrouped := groupedMedian(off, counts, nil), groupedMedian(def, counts, nil)
+	offs := make([]float64, BootstrapResamples)
+	defs := make([]float64, BootstrapResamples)
+	crs := make([]float64, BootstrapResamples)
+	for i := range crs {
+		offs[i] = offMed + groupedMedian(off, counts, rng) - offGrouped
+		defs[i] = defMed + groupedMedian(def, counts, rng) - defGrouped
+		crs[i] = CombatRating(offs[i], defs[i], baselineRatio)
+	}
+	r.Offense.Low, r.Offense.High = percentileInterval(offs)
+	r.Defense.Low, r.Defense.High = percentileInterval(defs)
+	r.CR.Low, r.CR.High = percentileInterval(crs)
+	return r
+}
+
+// sampleMedian is the median of unsorted turn counts.
+func sampleMedian(turns []int) float64 {
+	sorted := append([]int(nil), turns...)
+	sort.Ints(sorted)
+	return medianTurns(sorted)
+}
+
+// groupedMedian is the median of turns treating each count t as spread
+// evenly over [t-0.5, t+0.5), so it moves smoothly as the counts shift. With
+// an rng it first draws len(turns) counts with replacement. counts is
+// scratch space of at least MaxTurns+2 entries.
+func groupedMedian(turns []int, counts []int, rng *rand.Rand) float64 {
+	clear(counts)
+	n := len(turns)
+	for i := 0; i < n; i++ {
+		j := i
+		if rng != nil {
+			j = rng.IntN(n)
+		}
+		counts[min(max(turns[j], 0), len(counts)-1)]++
+	}
+	half, below := float64(n)/2, 0
+	for t, c := range counts {
+		if c > 0 && float64(below+c) >= half {
+			return float64(t) - 0.5 + (half-float64(below))/float64(c)
+		}
+		below += c
+	}
+	return float64(MaxTurns)
+}
+
+// percentileInterval sorts xs and returns its ConfidenceLevel percentile
+// interval.
+func percentileInterval(xs []float64) (float64, float64) {
+	sort.Float64s(xs)
+	tail := (1 - ConfidenceLevel) / 2
+	lo := int(tail * float64(len(xs)))
+	hi := min(int((1-tail)*float64(len(xs))), len(xs)-1)
+	return xs[lo], xs[hi]
+}
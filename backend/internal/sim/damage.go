@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	"math"
@@ -86,6 +86,15 @@ type MechState struct {
 	IsComposite   bool
 	TechBase      string
 
+	// Pilot
+	Gunnery  int
+	Piloting int
+	SPAs     []*SPA
+	AI       AI // nil plays as the Tactician
+
+	// Design quirks
+	Quirks []*Quirk
+
 	// 2D position
 	Pos    HexCoord
 	Facing int // 0-5
@@ -107,7 +116,8 @@ type MechState struct {
 	Weapons []SimWeapon
 
 	// Ammo pools
-	Ammo map[string]int
+	Ammo      map[string]int
+	Munitions map[string][]munition // alternate munitions carried, by AmmoKey
 
 	// Dynamic state
 	Heat           int
@@ -117,6 +127,8 @@ type MechState struct {
 	CockpitHit     bool
 	ArmActuatorHit [NumLoc]int
 	LegActuatorHit int
+	LimbCrits      [NumLoc]limbCrits // which actuators are hit, for physical attacks
+	MeleeDestroyed [NumLoc]bool
 
 	// IS exposure tracking
 	ISExposed [NumLoc]bool
@@ -133,6 +145,13 @@ type MechState struct {
 	HasArtemisV          bool
 	HasApollo            bool
 	AMSUsedThisTurn      bool
+	SemiGuided           bool // carries semi-guided LRM ammo
+	EW                   Electronics
+	MASC                 booster
+	Supercharger         booster
+	TSM                  bool
+	PartialWing          bool
+	HomingArrowIV        bool // carries homing Arrow IV ammo
 
 	// Heat state
 	HeatPenalty       int // heat applied by enemy plasma weapons
@@ -150,7 +169,19 @@ type MechState struct {
 	// Movement tracking (set each turn)
 	LastMoveMode MoveMode
 	LastHexMoved int
-	TorsoTwist   int // -1, 0, +1
+	TorsoTwist   int       // -1, 0, +1
+	WaterDepth   int       // depth of the water the mech stands in
+	Building     *Building // building the mech is inside, if any
+	Vacuum       bool      // fighting in vacuum: hull breach checks
+	Breached     [NumLoc]bool
+
+	// Per-turn attack tracking, cleared when the mech moves
+	FiredFrom        [NumLoc]bool // locations that fired a weapon this turn
+	DeclaredPhysical physicalKind // charge or DFA declared instead of weapon fire
+	PhysicalTarget   *MechState   // target of the declared charge or DFA
+	Tagged           bool         // designated by an enemy TAG this turn
+	UnderECM         ecmKind      // enemy ECM on the current line of fire
+	C3Dist           int          // C3 network's range to the current target, 0 if none
 }
 
 func (m *MechState) effectiveWalkMP() int {
@@ -159,6 +190,9 @@ func (m *MechState) effectiveWalkMP() int {
 		return 0
 	}
 	mp := m.WalkMP - m.LegActuatorHit - heatMPReduction(m.Heat)
+	if m.tsmActive() {
+		mp += 2
+	}
 	if mp < 0 {
 		mp = 0
 	}
@@ -338,6 +372,7 @@ func (m *MechState) applyDamage(loc int, dmg int, isRear bool, rng *rand.Rand) {
 	if m.IS[loc] > effectiveDmg {
 		m.IS[loc] -= effectiveDmg
 		m.ISExposed[loc] = true
+		m.breachCheck(loc, rng)
 		// [fix #2] Roll crits every time IS takes damage (BMM p.45)
 		m.rollCrits(loc, rng)
 		return
@@ -474,17 +509,27 @@ func (m *MechState) applyCrit(loc int, rng *rand.Rand) {
 		strings.Contains(slot, "lower arm") || strings.Contains(slot, "hand"):
 		if loc == LocLA || loc == LocRA {
 			m.ArmActuatorHit[loc]++
+			m.LimbCrits[loc].hit(slot)
 		}
 	case strings.Contains(slot, "hip"):
 		if !m.HipHit[loc] {
 			m.HipHit[loc] = true
+			m.LimbCrits[loc].Shoulder = true
 			m.LegActuatorHit += m.effectiveWalkMP()
 			m.NeedsPSRFromCrit = true
 		}
 	case strings.Contains(slot, "upper leg") || strings.Contains(slot, "lower leg") || strings.Contains(slot, "foot"):
 		m.LegActuatorHit++
 		m.LegFootHits[loc]++
+		m.LimbCrits[loc].hit(slot)
 		m.NeedsPSRFromCrit = true
+	case meleeWeaponOf(slot) != meleeNone:
+		m.MeleeDestroyed[loc] = true
+	case m.EW.crit(slot):
+	case strings.Contains(slot, "masc"):
+		m.MASC.Destroyed = true
+	case strings.Contains(slot, "supercharger"):
+		m.Supercharger.Destroyed = true
 	case strings.Contains(slot, "ammo"):
 		m.ammoExplosion(loc, slots[idx], rng)
 	default:
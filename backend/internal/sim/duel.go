@@ -0,0 +1,179 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// ─── Mutual-fire duel batch ─────────────────────────────────────────────────
+
+// DuelConfig describes a batch of two-sided duels (both mechs fire), as
+// played by SimulateDuelReplay.
+type DuelConfig struct {
+	Boards   []*Board
+	Attacker *MechState
+	Defender *MechState
+	Seed     uint64
+	N        int // number of duels, defaults to NumSimsPerBoard
+
+	Conditions Conditions
+}
+
+// DamageStats summarises damage dealt across a batch of duels.
+type DamageStats struct {
+	Mean   float64 `json:"mean"`
+	Min    int     `json:"min"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	Max    int     `json:"max"`
+}
+
+// DuelResult is the outcome of RunDuels.
+type DuelResult struct {
+	Sims            int         `json:"sims"`
+	AttackerWins    int         `json:"attacker_wins"`
+	DefenderWins    int         `json:"defender_wins"`
+	Draws           int         `json:"draws"`
+	AttackerWinRate float64     `json:"attacker_win_rate"`
+	DefenderWinRate float64     `json:"defender_win_rate"`
+	MeanTurnsToKill float64     `json:"mean_turns_to_kill"` // decisive duels only
+	AttackerDamage  DamageStats `json:"attacker_damage"`    // dealt by the attacker
+	DefenderDamage  DamageStats `json:"defender_damage"`    // dealt by the defender
+	Sample          *ReplayData `json:"sample_replay"`      // the median-length duel
+}
+
+// RunDuels plays cfg.N duels. Duel i uses its own RNG stream (Seed, i), so a
+// single duel can be replayed on its own; the median-length one is returned
+// as Sample.
+func RunDuels(ctx context.Context, cfg DuelConfig) (*DuelResult, error) {
+	if len(cfg.Boards) == 0 {
+		return nil, errors.New("sim: no boards")
+	}
+	if cfg.Attacker == nil || cfg.Defender == nil {
+		return nil, errors.New("sim: attacker and defender are required")
+	}
+	n := cfg.N
+	if n <= 0 {
+		n = NumSimsPerBoard
+	}
+
+	atkStart := structureTotal(cfg.Attacker)
+	defStart := structureTotal(cfg.Defender)
+
+	type duel struct {
+		idx   int
+		turns int
+	}
+	duels := make([]duel, 0, n)
+	atkDmg := make([]int, 0, n)
+	defDmg := make([]int, 0, n)
+	res := &DuelResult{Sims: n}
+	decisiveTurns := 0
+
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		replay := playDuel(cfg, i)
+		turns := len(replay.Turns)
+		duels = append(duels, duel{i, turns})
+
+		switch duelWinner(replay.Result) {
+		case 1:
+			res.AttackerWins++
+			decisiveTurns += turns
+		case -1:
+			res.DefenderWins++
+			decisiveTurns += turns
+		default:
+			res.Draws++
+		}
+
+		if turns > 0 {
+			last := replay.Turns[turns-1]
+			atkDmg = append(atkDmg, defStart-snapshotStructure(last.Defender))
+			defDmg = append(defDmg, atkStart-snapshotStructure(last.Attacker))
+		} else {
+			atkDmg = append(atkDmg, 0)
+			defDmg = append(defDmg, 0)
+		}
+	}
+
+	res.AttackerWinRate = float64(res.AttackerWins) / float64(n)
+	res.DefenderWinRate = float64(res.DefenderWins) / float64(n)
+	if decisive := res.AttackerWins + res.DefenderWins; decisive > 0 {
+		res.MeanTurnsToKill = float64(decisiveTurns) / float64(decisive)
+	}
+	res.AttackerDamage = damageStats(atkDmg)
+	res.DefenderDamage = damageStats(defDmg)
+
+	sort.SliceStable(duels, func(i, j int) bool { return duels[i].turns < duels[j].turns })
+	res.Sample = playDuel(cfg, duels[n/2].idx)
+	return res, nil
+}
+
+func playDuel(cfg DuelConfig, i int) *ReplayData {
+	rng := rand.New(rand.NewPCG(cfg.Seed, uint64(i)))
+	b1 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+	b2 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+	return SimulateDuelReplay(CombineBoards(b1, b2).WithConditions(cfg.Conditions), cfg.Attacker, cfg.Defender, rng)
+}
+
+// duelWinner maps a ReplayData.Result to +1 (attacker won), -1 (defender won)
+// or 0 (mutual destruction or timeout).
+func duelWinner(result string) int {
+	switch {
+	case strings.HasPrefix(result, "defender_destroyed"), result == "defender_forced_withdrawal":
+		return 1
+	case result == "attacker_destroyed", result == "attacker_forced_withdrawal":
+		return -1
+	}
+	return 0
+}
+
+func structureTotal(m *MechState) int {
+	total := 0
+	for loc := 0; loc < NumLoc; loc++ {
+		total += max(m.Armor[loc], 0) + max(m.IS[loc], 0)
+	}
+	for _, a := range m.RearArmor {
+		total += max(a, 0)
+	}
+	return total
+}
+
+func snapshotStructure(s ReplayMechSnapshot) int {
+	total := 0
+	for loc := 0; loc < NumLoc; loc++ {
+		total += max(s.Armor[loc], 0) + max(s.IS[loc], 0)
+	}
+	for _, a := range s.RearArmor {
+		total += max(a, 0)
+	}
+	return total
+}
+
+func damageStats(vals []int) DamageStats {
+	if len(vals) == 0 {
+		return DamageStats{}
+	}
+	sorted := append([]int(nil), vals...)
+	sort.Ints(sorted)
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	n := len(sorted)
+	return DamageStats{
+		Mean:   float64(sum) / float64(n),
+		Min:    sorted[0],
+		P25:    sorted[n/4],
+		Median: sorted[n/2],
+		P75:    sorted[3*n/4],
+		Max:    sorted[n-1],
+	}
+}
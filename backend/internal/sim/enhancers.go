@@ -0,0 +1,126 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Movement enhancers ─────────────────────────────────────────────────────
+//
+// MASC and superchargers let a 'Mech run at twice its walking MP (2.5x with
+// both) at the risk of failure. Each use rolls 2d6 against a target number
+// that climbs with every consecutive turn of use and steps back down each
+// turn the system rests. The AI only engages a system when its failure
+// chance is the 1-in-36 of a rested system. TSM adds 2 walking MP and
+// doubles punch, kick and melee weapon damage at 9+ heat.
+
+// boostTN is the failure target number by consecutive turns of use.
+var boostTN = [...]int{3, 5, 7, 11, 13}
+
+// booster is a MASC or supercharger.
+type booster struct {
+	Has       bool
+	Destroyed bool
+	Level     int // index into boostTN
+}
+
+func (b *booster) ready() bool {
+	return b.Has && !b.Destroyed && b.Level == 0
+}
+
+// roll checks the system after a turn of use and reports whether it failed.
+func (b *booster) roll(rng *rand.Rand) bool {
+	failed := roll2d6(rng) < boostTN[b.Level]
+	b.Level = min(b.Level+1, len(boostTN)-1)
+	if failed {
+		b.Destroyed = true
+	}
+	return failed
+}
+
+func (b *booster) rest() {
+	if b.Level > 0 {
+		b.Level--
+	}
+}
+
+// addEnhancer records movement enhancers in a crit slot.
+func (m *MechState) addEnhancer(slot string) {
+	s := strings.ToLower(strings.ReplaceAll(slot, " ", ""))
+	switch {
+	case strings.Contains(s, "masc"):
+		m.MASC.Has = true
+	case strings.Contains(s, "supercharger"):
+		m.Supercharger.Has = true
+	case strings.Contains(s, "industrial"):
+	case strings.Contains(s, "tsm") || strings.Contains(s, "triplestrength"):
+		m.TSM = true
+	case strings.Contains(s, "partialwing"):
+		m.PartialWing = true
+	}
+}
+
+// tsmActive reports whether TSM is working at m's current heat.
+func (m *MechState) tsmActive() bool {
+	return m.TSM && m.Heat >= 9
+}
+
+// boostRunMP is m's running MP with MASC and/or supercharger engaged, 0 if
+// neither is ready.
+func (m *MechState) boostRunMP() int {
+	w := m.effectiveWalkMP()
+	masc, sc := m.MASC.ready(), m.Supercharger.ready()
+	switch {
+	case masc && sc:
+		return int(math.Ceil(float64(w) * 2.5))
+	case masc || sc:
+		return w * 2
+	}
+	return 0
+}
+
+// boost rolls for the systems used in a boosted move, or rests them, and
+// returns what happened, for replays. A failed MASC takes a hip actuator in
+// each leg; a failed supercharger takes an engine hit.
+func (m *MechState) boost(used bool, rng *rand.Rand) []string {
+	if !used {
+		m.MASC.rest()
+		m.Supercharger.rest()
+		return nil
+	}
+	var notes []string
+	if m.MASC.ready() {
+		if m.MASC.roll(rng) {
+			for _, loc := range []int{LocLL, LocRL} {
+				if !m.HipHit[loc] {
+					m.HipHit[loc] = true
+					m.LimbCrits[loc].Shoulder = true
+					m.LegActuatorHit += m.effectiveWalkMP()
+				}
+			}
+			m.NeedsPSRFromCrit = true
+			notes = append(notes, "MASC fails, both hips destroyed")
+		} else {
+			notes = append(notes, "Runs on MASC")
+		}
+	}
+	if m.Supercharger.ready() {
+		if m.Supercharger.roll(rng) {
+			m.EngineHits++
+			notes = append(notes, "Supercharger fails, engine hit")
+		} else {
+			notes = append(notes, "Runs on supercharger")
+		}
+	}
+	return notes
+}
+
+// partialWingJump is the jump MP a partial wing adds: 2 up to 55 tons, 1
+// above.
+func partialWingJump(tonnage int) int {
+	if tonnage <= 55 {
+		return 2
+	}
+	return 1
+}
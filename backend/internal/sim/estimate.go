@@ -0,0 +1,89 @@
+package sim
+
+// ─── Layout estimate (no MTF) ───────────────────────────────────────────────
+//
+// The server's SQLite DB only carries armor_total and the weapon list, not the
+// per-location armor or crit table. When no MTF is available we spread armor
+// over the locations in proportion to their maximums and build a standard crit
+// layout so that engine, gyro and ammo crits still happen.
+
+// estimateLayout fills armor, slots and ammo for a mech built without an MTF.
+func estimateLayout(m *MechState, armorTotal int) {
+	distributeArmor(m, armorTotal)
+
+	m.Slots[LocHD] = []string{"Life Support", "Sensors", "Cockpit", "Sensors", "Life Support"}
+	m.Slots[LocCT] = []string{"Engine", "Engine", "Engine", "Gyro", "Gyro", "Gyro", "Gyro", "Engine", "Engine", "Engine"}
+	sideEngine := 0
+	if m.IsClanXL {
+		sideEngine = 2
+	} else if m.IsXL {
+		sideEngine = 3
+	}
+	for _, loc := range []int{LocLT, LocRT} {
+		for i := 0; i < sideEngine; i++ {
+			m.Slots[loc] = append(m.Slots[loc], "Engine")
+		}
+	}
+	for _, loc := range []int{LocLA, LocRA} {
+		m.Slots[loc] = []string{"Shoulder", "Upper Arm", "Lower Arm", "Hand"}
+	}
+	for _, loc := range []int{LocLL, LocRL} {
+		m.Slots[loc] = []string{"Hip", "Upper Leg", "Lower Leg", "Foot"}
+	}
+
+	for _, w := range m.Weapons {
+		m.Slots[w.Location] = append(m.Slots[w.Location], w.Name)
+		if w.AmmoKey == "" {
+			continue
+		}
+		// One ton per launcher, stored in the nearest torso
+		slot := w.Name + " Ammo"
+		loc := w.Location
+		switch loc {
+		case LocLA:
+			loc = LocLT
+		case LocRA:
+			loc = LocRT
+		case LocHD:
+			loc = LocCT
+		}
+		m.Slots[loc] = append(m.Slots[loc], slot)
+		m.Ammo[w.AmmoKey] += guessAmmoShots(slot)
+	}
+}
+
+// distributeArmor spreads total armor points in proportion to each location's
+// maximum (2× IS, head 9), with a quarter of each torso's share on the rear.
+func distributeArmor(m *MechState, total int) {
+	var maxArmor [NumLoc]int
+	maxTotal := 0
+	for loc := 0; loc < NumLoc; loc++ {
+		maxArmor[loc] = m.MaxIS[loc] * 2
+		if loc == LocHD {
+			maxArmor[loc] = 9
+		}
+		maxTotal += maxArmor[loc]
+	}
+	if total <= 0 || maxTotal == 0 {
+		return
+	}
+	if total > maxTotal {
+		total = maxTotal
+	}
+
+	assigned := 0
+	for loc := 0; loc < NumLoc; loc++ {
+		pts := maxArmor[loc] * total / maxTotal
+		assigned += pts
+		switch loc {
+		case LocCT, LocLT, LocRT:
+			rear := pts / 4
+			m.RearArmor[loc-LocCT] = rear
+			m.Armor[loc] = pts - rear
+		default:
+			m.Armor[loc] = pts
+		}
+	}
+	// Rounding remainder goes on the front CT
+	m.Armor[LocCT] += total - assigned
+}
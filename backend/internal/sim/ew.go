@@ -0,0 +1,306 @@
+package sim
+
+import "strings"
+
+// ─── Electronic warfare ─────────────────────────────────────────────────────
+//
+// ECM, active probes, C3, stealth armor and the Null-Signature System. What
+// ECM does depends on where everyone stands, so it is worked out per attack:
+// electronics sets the shooter's UnderECM and C3Dist just before it fires.
+// Under enemy ECM, Artemis and C3 stop working; under Angel ECM, Streaks
+// fire like standard missiles. A friendly ECM or an active probe in range
+// counters an enemy ECM (only Angel ECM or a Bloodhound counters Angel).
+
+type ecmKind int
+
+const (
+	ecmNone     ecmKind = iota
+	ecmGuardian         // Guardian, Clan ECM, Watchdog
+	ecmAngel
+)
+
+type c3Role int
+
+const (
+	c3None c3Role = iota
+	c3Slave
+	c3Master
+	c3Improved
+)
+
+// Electronics is a 'Mech's electronic warfare equipment.
+type Electronics struct {
+	ECM        ecmKind
+	ECMRange   int
+	ProbeRange int
+	Bloodhound bool
+	C3         c3Role
+	Stealth    bool // stealth armor; runs off the ECM
+	NullSig    bool
+
+	StealthEngaged bool // switched on for this turn by the AI
+	NullSigEngaged bool
+
+	ECMDestroyed     bool
+	ProbeDestroyed   bool
+	C3Destroyed      bool
+	NullSigDestroyed bool
+}
+
+type ewPart int
+
+const (
+	ewNone ewPart = iota
+	ewECM
+	ewProbe
+	ewC3
+	ewNullSig
+)
+
+// ewPartOf classifies a crit slot as electronic warfare equipment.
+func ewPartOf(slot string) ewPart {
+	s := strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(slot))
+	switch {
+	case strings.Contains(s, "ammo"):
+		return ewNone
+	case strings.Contains(s, "ecm") || strings.Contains(s, "watchdog"):
+		return ewECM
+	case strings.Contains(s, "activeprobe") || strings.Contains(s, "beagle") || strings.Contains(s, "bloodhound"):
+		return ewProbe
+	case strings.Contains(s, "c3"):
+		return ewC3
+	case strings.Contains(s, "nullsignature"):
+		return ewNullSig
+	}
+	return ewNone
+}
+
+// add records the equipment in one crit slot. Multi-slot items are seen once
+// per slot, so add only ever upgrades.
+func (e *Electronics) add(slot string) {
+	s := strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(slot))
+	switch ewPartOf(slot) {
+	case ewECM:
+		switch {
+		case strings.Contains(s, "angel"):
+			e.ECM, e.ECMRange = ecmAngel, 6
+		case strings.Contains(s, "watchdog"):
+			if e.ECM == ecmNone {
+				e.ECM, e.ECMRange = ecmGuardian, 3
+			}
+			e.ProbeRange = max(e.ProbeRange, 4)
+		case e.ECM == ecmNone:
+			e.ECM, e.ECMRange = ecmGuardian, 6
+		}
+	case ewProbe:
+		switch {
+		case strings.Contains(s, "bloodhound"):
+			e.ProbeRange, e.Bloodhound = 8, true
+		case strings.Contains(s, "light"):
+			e.ProbeRange = max(e.ProbeRange, 3)
+		case strings.Contains(s, "beagle"):
+			e.ProbeRange = max(e.ProbeRange, 4)
+		default: // Clan active probe
+			e.ProbeRange = max(e.ProbeRange, 5)
+		}
+	case ewC3:
+		switch {
+		case strings.Contains(s, "c3i") || strings.Contains(s, "improved"):
+			e.C3 = c3Improved
+		case strings.Contains(s, "master"):
+			e.C3 = c3Master
+		case e.C3 == c3None:
+			e.C3 = c3Slave
+		}
+	case ewNullSig:
+		e.NullSig = true
+	}
+}
+
+// crit destroys the equipment in a crit slot; false if it isn't EW gear.
+func (e *Electronics) crit(slot string) bool {
+	switch ewPartOf(slot) {
+	case ewECM:
+		e.ECMDestroyed = true
+	case ewProbe:
+		e.ProbeDestroyed = true
+	case ewC3:
+		e.C3Destroyed = true
+	case ewNullSig:
+		e.NullSigDestroyed = true
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *MechState) ewWorking() bool {
+	return !m.IsShutdown && !m.isDestroyed()
+}
+
+// ecmRange is the radius of m's ECM bubble, 0 if it has none. ECM running
+// stealth armor makes no bubble.
+func (m *MechState) ecmRange() int {
+	if m.EW.ECM == ecmNone || m.EW.ECMDestroyed || m.stealthOn() || !m.ewWorking() {
+		return 0
+	}
+	return m.EW.ECMRange
+}
+
+func (m *MechState) probeRange() int {
+	if m.EW.ProbeDestroyed || !m.ewWorking() {
+		return 0
+	}
+	return m.EW.ProbeRange
+}
+
+func (m *MechState) c3() c3Role {
+	if m.EW.C3Destroyed || !m.ewWorking() {
+		return c3None
+	}
+	return m.EW.C3
+}
+
+func (m *MechState) stealthOn() bool {
+	return m.EW.Stealth && m.EW.StealthEngaged && m.EW.ECM != ecmNone && !m.EW.ECMDestroyed && !m.IsShutdown
+}
+
+func (m *MechState) nullSigOn() bool {
+	return m.EW.NullSig && m.EW.NullSigEngaged && !m.EW.NullSigDestroyed && !m.IsShutdown
+}
+
+// engageEW runs stealth armor and the Null-Signature System only while one
+// of foes can see m from medium or long range of a working weapon, where
+// they add to its target numbers. Up close or out of sight they would only
+// cost heat.
+func (m *MechState) engageEW(board *Board, foes []*MechState) {
+	engage := false
+	for _, f := range foes {
+		if f.isDestroyed() || f.IsShutdown || !CheckLOS(board, f.Pos, m.Pos).CanSee {
+			continue
+		}
+		dist := HexDistance(f.Pos, m.Pos)
+		for i := range f.Weapons {
+			if w := &f.Weapons[i]; !w.Destroyed && rangeModifier(w, dist) >= 2 {
+				engage = true
+			}
+		}
+	}
+	m.EW.StealthEngaged = engage && m.EW.Stealth
+	m.EW.NullSigEngaged = engage && m.EW.NullSig
+}
+
+// ewHeat is the heat stealth armor and the Null-Signature System make each
+// turn they run: 10 each.
+func (m *MechState) ewHeat() int {
+	heat := 0
+	if m.stealthOn() {
+		heat += 10
+	}
+	if m.nullSigOn() {
+		heat += 10
+	}
+	return heat
+}
+
+// stealthMod is the to-hit modifier for attacking m at range bracket rm
+// (0 short, 2 medium, 4 long). Stealth armor is +0/+1/+2, Null-Signature
+// +1/+1/+2.
+func (m *MechState) stealthMod(rm int) int {
+	if rm < 0 {
+		return 0
+	}
+	b := min(rm/2, 2)
+	mod := 0
+	if m.stealthOn() {
+		mod = [3]int{0, 1, 2}[b]
+	}
+	if m.nullSigOn() {
+		mod = max(mod, [3]int{1, 1, 2}[b])
+	}
+	return mod
+}
+
+// countered reports whether one of friends counters src's ECM.
+func countered(src *MechState, friends []*MechState) bool {
+	for _, f := range friends {
+		d := HexDistance(f.Pos, src.Pos)
+		if r := f.ecmRange(); r > 0 && d <= r && (src.EW.ECM != ecmAngel || f.EW.ECM == ecmAngel) {
+			return true
+		}
+		if r := f.probeRange(); r > 0 && d <= r && (src.EW.ECM != ecmAngel || f.EW.Bloodhound) {
+			return true
+		}
+	}
+	return false
+}
+
+// ecmAt is the strongest enemy ECM covering h that friends don't counter.
+func ecmAt(h HexCoord, foes, friends []*MechState) ecmKind {
+	k := ecmNone
+	for _, f := range foes {
+		if r := f.ecmRange(); r > 0 && HexDistance(f.Pos, h) <= r && f.EW.ECM > k && !countered(f, friends) {
+			k = f.EW.ECM
+		}
+	}
+	return k
+}
+
+// c3Linked reports whether a and b share a C3 network: both improved C3,
+// or both standard C3 with a working master on the side.
+func c3Linked(a, b *MechState, side []*MechState) bool {
+	ra, rb := a.c3(), b.c3()
+	switch {
+	case ra == c3None || rb == c3None:
+		return false
+	case ra == c3Improved || rb == c3Improved:
+		return ra == rb
+	}
+	for _, m := range side {
+		if m.c3() == c3Master {
+			return true
+		}
+	}
+	return false
+}
+
+// electronics works out the electronic warfare for m's attack on t: enemy
+// ECM anywhere along the line of fire, and the shortest distance to t from
+// a C3 partner that can see it. friends are m's teammates and foes t's.
+func (m *MechState) electronics(board *Board, t *MechState, friends, foes []*MechState) {
+	m.UnderECM, m.C3Dist = ecmNone, 0
+	own := append([]*MechState{m}, friends...)
+	hostile := append([]*MechState{t}, foes...)
+
+	jamming := false
+	for _, f := range hostile {
+		if f.ecmRange() > 0 {
+			jamming = true
+			break
+		}
+	}
+	if jamming {
+		line := append(HexLine(m.Pos, t.Pos), m.Pos, t.Pos)
+		for _, h := range line {
+			if k := ecmAt(h, hostile, own); k > m.UnderECM {
+				m.UnderECM = k
+			}
+		}
+	}
+
+	if m.c3() == c3None || len(friends) == 0 || jamming && ecmAt(m.Pos, hostile, own) != ecmNone {
+		return
+	}
+	for _, f := range friends {
+		if f == m || !c3Linked(m, f, own) || jamming && ecmAt(f.Pos, hostile, own) != ecmNone {
+			continue
+		}
+		d := HexDistance(f.Pos, t.Pos)
+		if d == 0 || m.C3Dist > 0 && d >= m.C3Dist {
+			continue
+		}
+		if CheckLOS(board, f.Pos, t.Pos).CanSee {
+			m.C3Dist = d
+		}
+	}
+}
@@ -0,0 +1,678 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+)
+
+// ─── Forces (N-vs-M battles) ────────────────────────────────────────────────
+
+// DefaultBreakFraction ends a battle once a force has lost half its BV.
+const DefaultBreakFraction = 0.5
+
+// ForceUnit is one unit of a Force. BV weights it in the victory condition;
+// if every unit of a force has BV 0, units count equally.
+type ForceUnit struct {
+	Mech *MechState
+	BV   int
+}
+
+// Force is one side of a battle.
+type Force struct {
+	Name  string
+	Units []ForceUnit
+}
+
+// BattleConfig describes a batch of force-on-force battles.
+type BattleConfig struct {
+	Boards        []*Board
+	A, B          Force
+	Seed          uint64
+	N             int     // number of battles, defaults to NumSimsPerBoard
+	BreakFraction float64 // share of BV destroyed or withdrawn that loses the battle, defaults to DefaultBreakFraction
+	Conditions    Conditions
+	Scenario      *Scenario // nil for a plain battle
+}
+
+// BattleOutcome is the result of one battle. Per-unit slices follow the
+// order of Force.Units; index 0 is force A, 1 is force B.
+type BattleOutcome struct {
+	Turns       int
+	Winner      int    // 1 = A, -1 = B, 0 = draw
+	Points      [2]int // scenario objective points
+	SurvivingBV [2]int
+	Damage      [2][]int // damage dealt by each unit
+	Kills       [2][]int
+	Survived    [2][]bool
+}
+
+// UnitStats aggregates one unit over a batch of battles.
+type UnitStats struct {
+	Name            string  `json:"name"`
+	BV              int     `json:"bv"`
+	SurvivalRate    float64 `json:"survival_rate"`
+	MeanDamageDealt float64 `json:"mean_damage_dealt"`
+	MeanKills       float64 `json:"mean_kills"`
+}
+
+// ForceStats aggregates one force over a batch of battles.
+type ForceStats struct {
+	Name            string      `json:"name"`
+	StartBV         int         `json:"start_bv"`
+	Wins            int         `json:"wins"`
+	WinRate         float64     `json:"win_rate"`
+	MeanSurvivingBV float64     `json:"mean_surviving_bv"`
+	MeanPoints      float64     `json:"mean_points"` // scenario objective points
+	Units           []UnitStats `json:"units"`
+}
+
+// BattleResult is the outcome of RunBattles.
+type BattleResult struct {
+	Sims      int        `json:"sims"`
+	Draws     int        `json:"draws"`
+	MeanTurns float64    `json:"mean_turns"`
+	A         ForceStats `json:"a"`
+	B         ForceStats `json:"b"`
+}
+
+// MVP returns the index of the unit that dealt the most damage per battle,
+// breaking ties on kills, or -1 for an empty force.
+func (f *ForceStats) MVP() int {
+	best := -1
+	for i, u := range f.Units {
+		if best < 0 || u.MeanDamageDealt > f.Units[best].MeanDamageDealt ||
+			(u.MeanDamageDealt == f.Units[best].MeanDamageDealt && u.MeanKills > f.Units[best].MeanKills) {
+			best = i
+		}
+	}
+	return best
+}
+
+// WeakestLink returns the index of the unit with the least damage dealt per
+// point of BV, or -1 for an empty force. Units without a BV compare on raw
+// damage.
+func (f *ForceStats) WeakestLink() int {
+	worst, worstVal := -1, 0.0
+	for i, u := range f.Units {
+		val := u.MeanDamageDealt
+		if u.BV > 0 {
+			val /= float64(u.BV)
+		}
+		if worst < 0 || val < worstVal {
+			worst, worstVal = i, val
+		}
+	}
+	return worst
+}
+
+// RunBattles plays cfg.N battles, battle i on its own RNG stream (Seed, i).
+func RunBattles(ctx context.Context, cfg BattleConfig) (*BattleResult, error) {
+	if len(cfg.Boards) == 0 {
+		return nil, errors.New("sim: no boards")
+	}
+	if len(cfg.A.Units) == 0 || len(cfg.B.Units) == 0 {
+		return nil, errors.New("sim: both forces need at least one unit")
+	}
+	n := cfg.N
+	if n <= 0 {
+		n = NumSimsPerBoard
+	}
+	breakFrac := cfg.BreakFraction
+	if breakFrac <= 0 || breakFrac > 1 {
+		breakFrac = DefaultBreakFraction
+	}
+
+	res := &BattleResult{Sims: n}
+	forces := [2]Force{cfg.A, cfg.B}
+	stats := [2]*ForceStats{&res.A, &res.B}
+	for s, f := range forces {
+		stats[s].Name = f.Name
+		stats[s].Units = make([]UnitStats, len(f.Units))
+		for i, u := range f.Units {
+			stats[s].StartBV += u.BV
+			stats[s].Units[i] = UnitStats{Name: u.Mech.DebugName, BV: u.BV}
+		}
+	}
+
+	totalTurns := 0
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rng := rand.New(rand.NewPCG(cfg.Seed, uint64(i)))
+		b1 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+		b2 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+		out := SimulateScenario(CombineBoards(b1, b2).WithConditions(cfg.Conditions), cfg.A, cfg.B, breakFrac, cfg.Scenario, rng)
+
+		totalTurns += out.Turns
+		switch out.Winner {
+		case 1:
+			res.A.Wins++
+		case -1:
+			res.B.Wins++
+		default:
+			res.Draws++
+		}
+		for s := 0; s < 2; s++ {
+			stats[s].MeanSurvivingBV += float64(out.SurvivingBV[s])
+			stats[s].MeanPoints += float64(out.Points[s])
+			for u := range stats[s].Units {
+				us := &stats[s].Units[u]
+				us.MeanDamageDealt += float64(out.Damage[s][u])
+				us.MeanKills += float64(out.Kills[s][u])
+				if out.Survived[s][u] {
+					us.SurvivalRate++
+				}
+			}
+		}
+	}
+
+	fn := float64(n)
+	res.MeanTurns = float64(totalTurns) / fn
+	for s := 0; s < 2; s++ {
+		stats[s].WinRate = float64(stats[s].Wins) / fn
+		stats[s].MeanSurvivingBV /= fn
+		stats[s].MeanPoints /= fn
+		for u := range stats[s].Units {
+			us := &stats[s].Units[u]
+			us.MeanDamageDealt /= fn
+			us.MeanKills /= fn
+			us.SurvivalRate /= fn
+		}
+	}
+	return res, nil
+}
+
+// battleUnit is a live unit inside SimulateBattle.
+type battleUnit struct {
+	m       *MechState
+	side    int
+	idx     int
+	weight  int // BV, or 1 when the force has no BV
+	bv      int
+	out     bool
+	moved   bool
+	choice  ReachableHex
+	lastHit *battleUnit
+	damage  int
+	kills   int
+
+	hidden   bool // unseen by the enemy (scenario deployment)
+	exited   bool // left the board by the enemy's edge (scenario objective)
+	priority bool // the enemy's first target (escorted unit)
+}
+
+// SimulateBattle runs one battle between forces a and b on board. Force A
+// deploys on the north edge, B on the south edge. Each turn both sides roll
+// initiative; the loser moves first and units alternate by unit count (BMM
+// p.17). Fire is simultaneous: units destroyed during the weapon phase still
+// shoot. The battle ends when a force has lost breakFrac of its BV.
+func SimulateBattle(board *Board, a, b Force, breakFrac float64, rng *rand.Rand) BattleOutcome {
+	return SimulateScenario(board, a, b, breakFrac, nil, rng)
+}
+
+// SimulateScenario runs one battle as SimulateBattle does, played as sc
+// (nil for a plain battle).
+func SimulateScenario(board *Board, a, b Force, breakFrac float64, sc *Scenario, rng *rand.Rand) BattleOutcome {
+	board = board.forSim()
+	st := newScenarioState(board, sc)
+	var units [2][]*battleUnit
+	var startWeight [2]int
+	for s, f := range [2]Force{a, b} {
+		hasBV := false
+		for _, u := range f.Units {
+			if u.BV > 0 {
+				hasBV = true
+			}
+		}
+		for i, u := range f.Units {
+			bu := &battleUnit{m: cloneMech(u.Mech), side: s, idx: i, bv: u.BV, weight: 1}
+			if hasBV {
+				bu.weight = u.BV
+			}
+			startWeight[s] += bu.weight
+			units[s] = append(units[s], bu)
+		}
+	}
+	st.deploy(board, units, rng)
+
+	all := append(append([]*battleUnit{}, units[0]...), units[1]...)
+	out := BattleOutcome{Turns: st.turns()}
+
+	for turn := 1; turn <= st.turns(); turn++ {
+		if w, done := st.over(units, startWeight, breakFrac); done {
+			out.Turns = turn - 1
+			out.Winner = w
+			break
+		}
+
+		// Start of turn: unjam RACs, restart shut-down units, stand up
+		for _, u := range all {
+			if u.out {
+				continue
+			}
+			m := u.m
+			for i := range m.Weapons {
+				if m.Weapons[i].Jammed && m.Weapons[i].Category == catRotaryAC {
+					m.Weapons[i].Jammed = false
+				}
+			}
+			if m.IsShutdown {
+				tn := heatShutdownTN(m.Heat)
+				if tn < 13 && roll2d6(rng) >= tn {
+					m.IsShutdown = false
+					// Involuntary shutdown PSR: piloting + 3 modifier (BMM p.52)
+					if !m.Prone && roll2d6(rng) < m.Piloting+3+m.psrMod() {
+						m.applyFall(rng)
+					}
+				}
+			}
+			if m.Prone && !m.IsShutdown {
+				m.Heat++
+				if m.rollPSRForStanding(rng) {
+					m.Prone = false
+				} else {
+					m.applyFall(rng)
+				}
+			}
+			u.moved = false
+			u.choice = ReachableHex{Coord: m.Pos, Facing: m.Facing, Mode: ModeStand}
+		}
+		markOut(all)
+
+		// Initiative: 2d6 per side, loser moves first
+		bonusA, bonusB := sideInitiativeBonus(units[0]), sideInitiativeBonus(units[1])
+		initA, initB := roll2d6(rng)+bonusA, roll2d6(rng)+bonusB
+		for initA == initB {
+			initA, initB = roll2d6(rng)+bonusA, roll2d6(rng)+bonusB
+		}
+		first := 0
+		if initB < initA {
+			first = 1
+		}
+
+		// Movement, alternating by unit count
+		var pending [2][]*battleUnit
+		for s := 0; s < 2; s++ {
+			for _, u := range units[s] {
+				if !u.out {
+					pending[s] = append(pending[s], u)
+				}
+			}
+		}
+		for len(pending[0])+len(pending[1]) > 0 {
+			for _, s := range [2]int{first, 1 - first} {
+				k := len(pending[s])
+				if other := len(pending[1-s]); other > 0 {
+					k = max(1, len(pending[s])/other)
+				}
+				for ; k > 0 && len(pending[s]) > 0; k-- {
+					moveUnit(board, st, pending[s][0], all, units[1-s], rng)
+					pending[s] = pending[s][1:]
+				}
+			}
+		}
+		st.afterMove(units)
+		for s := 0; s < 2; s++ {
+			foes := teammates(units[1-s], nil)
+			for _, u := range units[s] {
+				if !u.out {
+					u.m.ai().ChooseEW(u.m, board, foes)
+				}
+			}
+		}
+
+		// Weapon attacks, in initiative order, simultaneous
+		phaseDmg := make(map[*battleUnit]int)
+		canFire := make(map[*battleUnit]bool, len(all))
+		for _, u := range all {
+			canFire[u] = !u.out && !u.m.IsShutdown
+		}
+		for _, s := range [2]int{first, 1 - first} {
+			focus := focusTarget(board, units[s], units[1-s])
+			for _, u := range units[s] {
+				if !canFire[u] {
+					continue
+				}
+				t := chooseFireTarget(board, u, units[s], units[1-s], focus)
+				if t == nil {
+					continue
+				}
+				u.hidden = false
+				u.m.TorsoTwist = BestTorsoTwist(u.m.Pos, u.m.Facing, t.m.Pos)
+				u.m.AMSUsedThisTurn = false
+				before := structureTotal(t.m)
+				wasOut := t.m.isDestroyed()
+				friends := teammates(units[s], u)
+				u.m.electronics(board, t.m, friends, teammates(units[1-s], nil))
+				_, _, _ = fireWeaponsReplay(u.m, t.m, board, u.choice, t.choice, friends, "", nil, nil, rng)
+				dealt := before - structureTotal(t.m)
+				if dealt > 0 {
+					u.damage += dealt
+					phaseDmg[t] += dealt
+					t.lastHit = u
+				}
+				if !wasOut && t.m.isDestroyed() {
+					u.kills++
+					t.out = true
+				}
+			}
+		}
+
+		// PSRs: 20+ damage in the phase, then crit PSRs
+		for _, u := range all {
+			if u.m.isDestroyed() {
+				continue
+			}
+			if phaseDmg[u] >= 20 && !u.m.Prone && !u.m.rollPSR(1, rng) {
+				u.m.applyFall(rng)
+			}
+			if u.m.NeedsPSRFromCrit {
+				u.m.NeedsPSRFromCrit = false
+				if !u.m.rollPSR(0, rng) {
+					u.m.applyFall(rng)
+				}
+			}
+		}
+
+		// Physical attacks against an adjacent enemy
+		for _, s := range [2]int{first, 1 - first} {
+			for _, u := range units[s] {
+				if u.m.isDestroyed() || u.m.IsShutdown || u.m.Prone {
+					continue
+				}
+				for _, t := range units[1-s] {
+					if t.m.isDestroyed() || HexDistance(u.m.Pos, t.m.Pos) != 1 {
+						continue
+					}
+					if u.m.PhysicalTarget != nil && u.m.PhysicalTarget != t.m {
+						continue
+					}
+					before := structureTotal(t.m)
+					resolvePhysical(u.m, t.m, rng)
+					if dealt := before - structureTotal(t.m); dealt > 0 {
+						u.damage += dealt
+						t.lastHit = u
+					}
+					if t.m.isDestroyed() {
+						u.kills++
+					}
+					break
+				}
+			}
+		}
+
+		// Heat phase
+		for _, u := range all {
+			if !u.m.isDestroyed() {
+				heatPhase(u.m, board.Conditions, rng)
+			}
+		}
+
+		// Units that left the fight this turn credit whoever hit them last
+		for _, u := range all {
+			if u.out || !unitOut(u.m) {
+				continue
+			}
+			u.out = true
+			if u.lastHit != nil && !u.m.isDestroyed() {
+				u.lastHit.kills++
+			}
+		}
+		st.endTurn(units)
+	}
+
+	if out.Turns == st.turns() {
+		out.Winner = st.final(units, startWeight, breakFrac)
+	}
+	out.Points = st.score()
+	for s := 0; s < 2; s++ {
+		out.Damage[s] = make([]int, len(units[s]))
+		out.Kills[s] = make([]int, len(units[s]))
+		out.Survived[s] = make([]bool, len(units[s]))
+		for i, u := range units[s] {
+			out.Damage[s][i] = u.damage
+			out.Kills[s][i] = u.kills
+			if !u.out || u.exited {
+				out.Survived[s][i] = true
+				out.SurvivingBV[s] += u.bv
+			}
+		}
+	}
+	return out
+}
+
+// unitOut reports whether a mech has left the fight: destroyed, forced to
+// withdraw, or combat-ineffective (gyro gone or both legs gone).
+func unitOut(m *MechState) bool {
+	return m.isForcedWithdrawal() || m.GyroHits >= 2 || (m.IS[LocLL] <= 0 && m.IS[LocRL] <= 0)
+}
+
+func markOut(all []*battleUnit) {
+	for _, u := range all {
+		if !u.out && unitOut(u.m) {
+			u.out = true
+		}
+	}
+}
+
+// battleOver checks the break condition. A force that has lost breakFrac of
+// its weight loses; if both break at once it is a draw.
+func battleOver(units [2][]*battleUnit, start [2]int, breakFrac float64) (int, bool) {
+	var broken [2]bool
+	for s := 0; s < 2; s++ {
+		lost := 0
+		for _, u := range units[s] {
+			if u.out && !u.exited {
+				lost += u.weight
+			}
+		}
+		broken[s] = float64(lost) >= breakFrac*float64(start[s])
+	}
+	switch {
+	case broken[0] && broken[1]:
+		return 0, true
+	case broken[1]:
+		return 1, true
+	case broken[0]:
+		return -1, true
+	}
+	return 0, false
+}
+
+// sideInitiativeBonus is the side's best quirk initiative bonus (command
+// 'Mech) among units still in the fight.
+func sideInitiativeBonus(side []*battleUnit) int {
+	var live []*MechState
+	for _, u := range side {
+		if !u.out {
+			live = append(live, u.m)
+		}
+	}
+	return initiativeBonus(live...)
+}
+
+// deployForce spreads a force evenly along one row.
+func deployForce(board *Board, units []*battleUnit, row, facing int) {
+	for i, u := range units {
+		u.m.Pos = HexCoord{Col: 1 + (i+1)*(board.Width-1)/(len(units)+1), Row: row}
+		u.m.Facing = facing
+	}
+}
+
+// lightState builds the tactical AI's view of a unit.
+func lightState(m *MechState) *MechState2 {
+	m2 := &MechState2{
+		Pos: m.Pos, Facing: m.Facing, JumpMP: m.JumpMP,
+		Tonnage: m.Tonnage, GunnerySkill: m.Gunnery, PilotingSkill: m.Piloting, SPAs: m.SPAs, Quirks: m.Quirks,
+		Heat: m.Heat, OptimalRange: m.OptimalRange, Prone: m.Prone,
+	}
+	if !m.Prone && !m.IsShutdown {
+		m2.WalkMP = m.effectiveWalkMP()
+		m2.RunMP = m.effectiveRunMP()
+		m2.BoostRunMP = m.boostRunMP()
+	} else {
+		m2.JumpMP = 0
+	}
+	for _, w := range m.Weapons {
+		if !w.Destroyed && !w.Jammed {
+			m2.Weapons = append(m2.Weapons, SimWeapon2{
+				Name: w.Name, Damage: w.Damage, Heat: w.Heat,
+				MinRange: w.MinRange, ShortRange: w.ShortRange,
+				MedRange: w.MedRange, LongRange: w.LongRange,
+				Location: w.Location, ToHitMod: w.ToHitMod,
+			})
+		}
+	}
+	return m2
+}
+
+// moveUnit picks a movement target (the nearest live enemy it can see) and
+// moves u against it with the duel AI, avoiding hexes other units occupy. A
+// unit with a scenario objective only considers the moves that make the
+// most progress towards it; a hidden one stays put.
+func moveUnit(board *Board, st *scenarioState, u *battleUnit, all, enemies []*battleUnit, rng *rand.Rand) {
+	u.moved = true
+	if u.m.IsShutdown || u.hidden {
+		return
+	}
+	var op *battleUnit
+	best := 1 << 30
+	for _, e := range enemies {
+		if e.out || e.hidden {
+			continue
+		}
+		if d := HexDistance(u.m.Pos, e.m.Pos); d < best {
+			best, op = d, e
+		}
+	}
+	goal := st.goal(u, op != nil)
+	if op == nil && goal == nil {
+		return
+	}
+
+	me2 := lightState(u.m)
+	opts := collectAllMoveOptions(board, me2)
+	free := opts[:0:0]
+	for _, o := range opts {
+		if o.Coord == u.m.Pos || !occupied(all, u, o.Coord) {
+			free = append(free, o)
+		}
+	}
+	if goal != nil {
+		free = toward(free, goal)
+	}
+
+	var choice ReachableHex
+	switch {
+	case op == nil:
+		// Nobody to fight: the cheapest of the moves towards the goal
+		choice = free[0]
+		for _, o := range free[1:] {
+			if o.MoveHeat < choice.MoveHeat {
+				choice = o
+			}
+		}
+	case op.moved:
+		choice = u.m.ai().ChooseMove(board, me2, lightState(op.m), true, op.m.Pos, op.m.Facing, nil, free, rng)
+	default:
+		op2 := lightState(op.m)
+		opOpts := collectAllMoveOptions(board, op2)
+		choice = u.m.ai().ChooseMove(board, me2, op2, false, op.m.Pos, op.m.Facing, opOpts, free, rng)
+	}
+	u.choice = choice
+	u.m.Pos = choice.Coord
+	u.m.Facing = choice.Facing
+	u.m.LastMoveMode = choice.Mode
+	u.m.LastHexMoved = choice.HexesMoved
+	u.m.clearTurnAttacks()
+	u.m.Heat += choice.MoveHeat
+	u.m.enterHex(board, rng)
+	u.m.boost(choice.Boosted, rng)
+}
+
+func occupied(all []*battleUnit, self *battleUnit, c HexCoord) bool {
+	for _, o := range all {
+		if o != self && !o.m.isDestroyed() && o.m.Pos == c {
+			return true
+		}
+	}
+	return false
+}
+
+// teammates returns the rest of u's side still in the fight, as spotters.
+func teammates(side []*battleUnit, u *battleUnit) []*MechState {
+	var ms []*MechState
+	for _, o := range side {
+		if o != u && !o.out {
+			ms = append(ms, o.m)
+		}
+	}
+	return ms
+}
+
+// killValue is the expected damage u can put on t this turn as a share of
+// t's remaining armor and structure; 0 if t cannot be engaged. Targets out
+// of LOS count only what u can fire indirectly.
+func killValue(board *Board, u, t *battleUnit, side []*battleUnit) float64 {
+	if t.out || t.hidden {
+		return 0
+	}
+	dist := HexDistance(u.m.Pos, t.m.Pos)
+	if dist == 0 {
+		return 0
+	}
+	tmm := tmmFromHexesMoved(t.choice.HexesMoved, t.choice.Mode)
+	var ed float64
+	if los := CheckLOS(board, u.m.Pos, t.m.Pos); los.CanSee {
+		base := u.m.Gunnery + heatToHitMod(u.m.Heat, board.Conditions) + los.WoodsMod + los.TargetCover + los.ElevationMod
+		ed = calcExpectedDamage(u.m, dist, base, tmm)
+	} else {
+		ed = indirectExpectedDamage(board, u.m, t.m, u.choice.Mode, tmm, teammates(side, u))
+	}
+	remaining := structureTotal(t.m)
+	if remaining <= 0 {
+		return 0
+	}
+	return ed / float64(remaining)
+}
+
+// focusTarget is the enemy the whole side can take apart fastest, counting
+// an escorted unit double.
+func focusTarget(board *Board, side, enemies []*battleUnit) *battleUnit {
+	var best *battleUnit
+	bestV := 0.0
+	for _, e := range enemies {
+		v := 0.0
+		for _, u := range side {
+			if !u.out && !u.m.IsShutdown {
+				v += killValue(board, u, e, side)
+			}
+		}
+		if e.priority {
+			v *= 2
+		}
+		if v > bestV {
+			best, bestV = e, v
+		}
+	}
+	return best
+}
+
+// chooseFireTarget prefers the side's focus target, falling back to the
+// enemy this unit alone does the most relative damage to.
+func chooseFireTarget(board *Board, u *battleUnit, side, enemies []*battleUnit, focus *battleUnit) *battleUnit {
+	var best *battleUnit
+	bestV := 0.0
+	for _, e := range enemies {
+		v := killValue(board, u, e, side)
+		if e == focus {
+			v *= 1.5
+		}
+		if v > bestV {
+			best, bestV = e, v
+		}
+	}
+	return best
+}
@@ -1,6 +1,10 @@
-package main
+package sim
 
-import "math"
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+)
 
 // ─── Dice helpers ───────────────────────────────────────────────────────────
 
@@ -79,18 +83,19 @@ func heatMPReduction(heat int) int {
 	}
 }
 
-func heatToHitMod(heat int) int {
+func heatToHitMod(heat int, c Conditions) int {
+	mod := c.toHitMod() // light and weather
 	switch {
 	case heat >= 24:
-		return 4
+		return mod + 4
 	case heat >= 17:
-		return 3
+		return mod + 3
 	case heat >= 13:
-		return 2
+		return mod + 2
 	case heat >= 8:
-		return 1
+		return mod + 1
 	default:
-		return 0
+		return mod
 	}
 }
 
@@ -172,3 +177,47 @@ func heatCostEV(heat int, avgTurnDmg float64, ammoExpDmg float64, walkMP int, cu
 
 	return cost
 }
+
+// ─── End phase ──────────────────────────────────────────────────────────────
+
+// heatPhase applies the end-of-turn heat step: external heat (plasma), then
+// dissipation, then the shutdown and ammo explosion rolls for the new level.
+func heatPhase(m *MechState, c Conditions, rng *rand.Rand) (shutdown, ammoExp bool) {
+	m.Heat += m.HeatPenalty
+	m.HeatPenalty = 0
+	m.Heat -= m.dissipation(c)
+	if m.Heat < 0 {
+		m.Heat = 0
+	}
+
+	shutdownP := heatShutdownProb(m.Heat)
+	if shutdownP >= 1.0 || (shutdownP > 0 && rng.Float64() < shutdownP) {
+		m.IsShutdown = true
+		shutdown = true
+	}
+	ammoExpP := heatAmmoExpProb(m.Heat)
+	if ammoExpP > 0 && rng.Float64() < ammoExpP {
+		ammoExp = true
+		type ammoBin struct {
+			key string
+			loc int
+		}
+		var bins []ammoBin
+		for loc := 0; loc < NumLoc; loc++ {
+			for _, slot := range m.Slots[loc] {
+				sLower := strings.ToLower(slot)
+				if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
+					key := parseAmmoSlotKey(slot)
+					if m.Ammo[key] > 0 {
+						bins = append(bins, ammoBin{key, loc})
+					}
+				}
+			}
+		}
+		if len(bins) > 0 {
+			bin := bins[rng.IntN(len(bins))]
+			m.ammoExplosion(bin.loc, bin.key, rng)
+		}
+	}
+	return shutdown, ammoExp
+}
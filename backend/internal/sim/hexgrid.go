@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	"bufio"
@@ -221,15 +221,20 @@ func bearingToFacingOLD(from, to HexCoord) int {
 type TerrainType int
 
 const (
-	TerrainWoods    TerrainType = iota // level 1=light, 2=heavy
-	TerrainWater                       // level = depth
-	TerrainRough                       // level 1 or 2
+	TerrainWoods TerrainType = iota // level 1=light, 2=heavy
+	TerrainWater                    // level = depth
+	TerrainRough                    // level 1 or 2
 	TerrainPavement
 	TerrainRoad
-	TerrainBuilding // level = CF class (1-4)
+	TerrainBuilding // level = class (1 light - 4 hardened)
 	TerrainSand
 	TerrainSwamp
 	TerrainMud
+	TerrainRubble
+	TerrainFire     // level 1=fire, 2=inferno
+	TerrainSmoke    // level 1=light, 2=heavy
+	TerrainBldgCF   // level = building construction factor
+	TerrainBldgElev // level = building height in levels
 )
 
 type TerrainFeature struct {
@@ -258,6 +263,10 @@ type Board struct {
 	Width, Height int
 	Hexes         map[HexCoord]*Hex // kept for parsing compatibility
 	Grid          []Hex             // flat 2D grid: (col-1)*Height + (row-1)
+
+	Conditions Conditions // light, weather and so on; zero is a clear day
+
+	buildings map[HexCoord]*Building // standing buildings, on a forSim copy only
 }
 
 func NewBoard(w, h int) *Board {
@@ -419,6 +428,16 @@ func parseTerrainFeature(s string) *TerrainFeature {
 		return &TerrainFeature{Type: TerrainSwamp, Level: level}
 	case name == "mud":
 		return &TerrainFeature{Type: TerrainMud, Level: level}
+	case name == "rubble":
+		return &TerrainFeature{Type: TerrainRubble, Level: level}
+	case name == "fire":
+		return &TerrainFeature{Type: TerrainFire, Level: level}
+	case name == "smoke":
+		return &TerrainFeature{Type: TerrainSmoke, Level: level}
+	case name == "bldg_cf":
+		return &TerrainFeature{Type: TerrainBldgCF, Level: level}
+	case name == "bldg_elev":
+		return &TerrainFeature{Type: TerrainBldgElev, Level: level}
 	default:
 		// ground_fluff, foliage_elev, bridge, etc. — cosmetic, skip
 		return nil
@@ -0,0 +1,296 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Indirect fire, spotting and TAG ────────────────────────────────────────
+//
+// LRMs and MMLs can fire at a target the shooter can't see as long as a
+// friendly unit can (TW p.111). Arrow IV doesn't need a spotter: without LOS
+// it fires as artillery. TAG designates a target for semi-guided LRMs and
+// homing Arrow IV. Ammo is pooled per weapon, so a 'Mech that carries any
+// semi-guided or homing ammo uses it whenever its target is tagged.
+
+// tagWeapon returns the TAG in a crit slot as a weapon: TAG 5/9/15, light
+// TAG 3/6/9. TAG makes no heat and does no damage.
+func tagWeapon(slot string, loc int) (SimWeapon, bool) {
+	s := strings.ToLower(strings.ReplaceAll(slot, " ", ""))
+	s = strings.TrimSuffix(s, "(omnipod)")
+	if !strings.HasSuffix(s, "tag") {
+		return SimWeapon{}, false
+	}
+	if strings.Contains(s, "light") {
+		return SimWeapon{Name: "Light TAG", Category: catTAG, Location: loc, ShortRange: 3, MedRange: 6, LongRange: 9}, true
+	}
+	return SimWeapon{Name: "TAG", Category: catTAG, Location: loc, ShortRange: 5, MedRange: 9, LongRange: 15}, true
+}
+
+// guided reports whether m carries ammo that homes on a TAG designation.
+func (m *MechState) guided() bool {
+	return m.SemiGuided || m.HomingArrowIV
+}
+
+// homing reports whether w fires homing rounds at t.
+func (m *MechState) homing(w *SimWeapon, t *MechState) bool {
+	return w.Category == catArrowIV && m.HomingArrowIV && t.Tagged
+}
+
+// homingTN is what homing Arrow IV needs against a tagged target, whatever
+// the other modifiers: they all went into the TAG roll.
+const homingTN = 4
+
+// finalTN is the target number m fires w at against t, tn having every
+// modifier added: homingTN for homing Arrow IV.
+func (m *MechState) finalTN(w *SimWeapon, t *MechState, tn int) int {
+	if m.homing(w, t) {
+		return homingTN
+	}
+	return tn
+}
+
+// expectedDamage is weaponExpectedDamage for m firing w at t, tn being the
+// target number before range and w's own modifier.
+func (m *MechState) expectedDamage(w *SimWeapon, t *MechState, dist, tn int) float64 {
+	if m.homing(w, t) {
+		tn = homingTN - w.ToHitMod // weaponExpectedDamage adds it back
+	}
+	return weaponExpectedDamage(w, dist, tn)
+}
+
+// attackMod is the per-weapon modifier on top of the base to-hit number:
+// SPAs and quirks, the target's stealth, C3 range sharing, and guided ammo
+// against a tagged target. Semi-guided LRMs ignore the target's movement
+// modifier. Homing Arrow IV takes none, see finalTN.
+func (m *MechState) attackMod(w *SimWeapon, t *MechState, dist int) int {
+	if m.homing(w, t) {
+		return 0
+	}
+	rm := rangeModifier(w, dist)
+	mod := m.weaponMod(w, dist) + t.stealthMod(rm)
+	if w.Category == catLRM && m.SemiGuided && t.Tagged {
+		mod -= tmmFromHexesMoved(t.LastHexMoved, t.LastMoveMode)
+	}
+	// C3: use the range from the network member closest to the target
+	if m.C3Dist > 0 && m.C3Dist < dist && rm >= 0 {
+		if c3rm := rangeModifier(w, m.C3Dist); c3rm >= 0 {
+			mod += c3rm - rm
+		}
+	}
+	return mod
+}
+
+// attacked reports whether m has made an attack this turn.
+func (m *MechState) attacked() bool {
+	if m.DeclaredPhysical != physNone {
+		return true
+	}
+	for _, fired := range m.FiredFrom {
+		if fired {
+			return true
+		}
+	}
+	return false
+}
+
+// designate fires m's TAG at t and returns the target number, or 0 if m has
+// no TAG that can reach. A hit tags t for the rest of the turn.
+func (m *MechState) designate(t *MechState, dist, baseTarget int, rng *rand.Rand) (int, bool) {
+	for i := range m.Weapons {
+		w := &m.Weapons[i]
+		if w.Category != catTAG || w.Destroyed || m.submerged(w.Location) || !canWeaponFire(w, m.Pos, m.Facing, m.TorsoTwist, t.Pos) {
+			continue
+		}
+		rm := rangeModifier(w, dist)
+		if rm < 0 {
+			continue
+		}
+		m.FiredFrom[w.Location] = true
+		tn := baseTarget + w.ToHitMod + m.ArmActuatorHit[w.Location] + rm
+		if roll2d6(rng) >= tn {
+			t.Tagged = true
+		}
+		return tn, t.Tagged
+	}
+	return 0, false
+}
+
+// spot finds the friendly unit that can spot t with the smallest modifier:
+// +1 for indirect fire, the spotter's movement, +1 if the spotter has
+// attacked this turn, and the terrain between spotter and target.
+func spot(board *Board, t *MechState, spotters []*MechState) (spotter *MechState, los LOSResult, mod int) {
+	for _, s := range spotters {
+		if s.isDestroyed() || s.IsShutdown || s.Pos == t.Pos {
+			continue
+		}
+		l := CheckLOS(board, s.Pos, t.Pos)
+		if !l.CanSee {
+			continue
+		}
+		md := 1 + attackerMoveMod(s.LastMoveMode, s.SPAs) + l.WoodsMod + l.TargetCover + l.ElevationMod
+		if s.attacked() {
+			md++
+		}
+		if spotter == nil || md < mod {
+			spotter, los, mod = s, l, md
+		}
+	}
+	return spotter, los, mod
+}
+
+// indirectMods returns the base to-hit number for an indirect attack by
+// shooter on target, and each weapon's modifier on top of it (false if the
+// weapon can't fire). Spotted LRMs and MMLs take the spotter's modifiers;
+// semi-guided LRMs at a tagged target skip the indirect and spotter movement
+// modifiers but not the terrain. Unguided Arrow IV fires as artillery: +7,
+// with no movement or terrain modifiers.
+func indirectMods(board *Board, shooter, target *MechState, shooterMode MoveMode, targetTMM int, spotters []*MechState) (int, func(w *SimWeapon) (int, bool)) {
+	dist := HexDistance(shooter.Pos, target.Pos)
+	base := shooter.Gunnery + shooter.SensorHits*2 + heatToHitMod(shooter.Heat, board.Conditions)
+	artillery := base + 7
+	base += attackerMoveMod(shooterMode, shooter.SPAs) + targetTMM
+	if shooter.Prone {
+		base += 2
+	}
+	if target.Prone {
+		base++
+	}
+	spotter, los, spotMod := spot(board, target, spotters)
+	return base, func(w *SimWeapon) (int, bool) {
+		switch {
+		case w.Category == catArrowIV:
+			if shooter.homing(w, target) {
+				return 0, true // see finalTN
+			}
+			return artillery - base, true
+		case w.Category != catLRM && w.Category != catMML:
+			return 0, false
+		case spotter == nil:
+			return 0, false
+		case w.Category == catLRM && shooter.SemiGuided && target.Tagged:
+			return shooter.attackMod(w, target, dist) + los.WoodsMod + los.TargetCover + los.ElevationMod, true
+		}
+		return shooter.attackMod(w, target, dist) + spotMod, true
+	}
+}
+
+// indirectExpectedDamage is calcExpectedDamage for a target m can't see.
+func indirectExpectedDamage(board *Board, m, t *MechState, mode MoveMode, tmm int, spotters []*MechState) float64 {
+	dist := HexDistance(m.Pos, t.Pos)
+	base, mod := indirectMods(board, m, t, mode, tmm, spotters)
+	total := 0.0
+	for i := range m.Weapons {
+		w := &m.Weapons[i]
+		if w.Destroyed || w.Jammed || m.submerged(w.Location) {
+			continue
+		}
+		if !m.hasAmmo(w) {
+			continue
+		}
+		if md, ok := mod(w); ok {
+			total += m.expectedDamage(w, t, dist, base+md)
+		}
+	}
+	return total
+}
+
+// fireIndirect is fireWeaponsReplay for a target the shooter can't see.
+func fireIndirect(shooter, target *MechState, board *Board, shooterChoice, targetChoice ReachableHex, spotters []*MechState, actorName string, events []ReplayEvent, fires *[]ReplayWeaponFire, rng *rand.Rand) ([]ReplayEvent, int, bool) {
+	// BMM p.49: 2+ sensor hits = weapon fire impossible
+	if shooter.SensorHits >= 2 || shooter.DeclaredPhysical != physNone {
+		return events, 0, false
+	}
+	dist := HexDistance(shooter.Pos, target.Pos)
+	targetEffFacing := ((target.Facing+target.TorsoTwist)%6 + 6) % 6
+	isRear := DetermineArc(target.Pos, targetEffFacing, shooter.Pos) == ArcRear
+	base, mod := indirectMods(board, shooter, target, shooterChoice.Mode, tmmFromHexesMoved(targetChoice.HexesMoved, targetChoice.Mode), spotters)
+	firingWeapons, weaponHeatTotal := selectWeapons(shooter, board, target, dist, base, mod)
+	if len(firingWeapons) == 0 {
+		return events, 0, false
+	}
+	shooter.Heat += weaponHeatTotal
+
+	spotter, _, _ := spot(board, target, spotters)
+	detail := "no spotter"
+	if spotter != nil {
+		detail = "spotter: " + spotter.DebugName
+	}
+	events = append(events, ReplayEvent{
+		Type: "info", Actor: actorName,
+		Message: "No LOS, indirect fire at range " + itoa(dist) + " base TN " + itoa(base),
+		Detail:  detail,
+	})
+
+	totalDmg := 0
+	for _, wi := range firingWeapons {
+		w := &shooter.Weapons[wi]
+		md, _ := mod(w)
+		tn := base + w.ToHitMod + shooter.ArmActuatorHit[w.Location] + md
+		rm := rangeModifier(w, dist)
+		if rm < 0 {
+			continue
+		}
+		tn += rm
+		if w.MinRange > 0 && dist <= w.MinRange {
+			tn += w.MinRange - dist + 1
+		}
+		if !shooter.load(w, target, tn) {
+			continue
+		}
+		tn += shooter.munitionMod(w, w.Loaded, target)
+		tn = shooter.finalTN(w, target, tn)
+
+		var before shotState
+		if fires != nil {
+			before = stateOf(target)
+		}
+		dmg := resolveWeaponFire2D(w, tn, isRear, shooter, target, rng)
+		totalDmg += dmg
+		if fires != nil {
+			*fires = append(*fires, recordShot(w, actorName, tn, dmg, before, target))
+		}
+
+		hitStr := "MISS"
+		if dmg > 0 {
+			hitStr = itoa(dmg) + " dmg"
+		}
+		events = append(events, ReplayEvent{
+			Type: "fire", Actor: actorName,
+			Message: w.shotName() + " (indirect, TN " + itoa(tn) + "): " + hitStr,
+		})
+
+		if target.isDestroyed() {
+			targetName := "defender"
+			if actorName == "defender" {
+				targetName = "attacker"
+			}
+			events = append(events, ReplayEvent{Type: "destroyed", Actor: targetName, Message: "DESTROYED"})
+			return events, totalDmg, true
+		}
+	}
+	return events, totalDmg, false
+}
+
+// anyGuided reports whether any of ms carries guided ammo.
+func anyGuided(ms []*MechState) bool {
+	for _, m := range ms {
+		if m.guided() && !m.isDestroyed() {
+			return true
+		}
+	}
+	return false
+}
+
+// tagEvent fires the shooter's TAG at t and logs the result.
+func tagEvent(events []ReplayEvent, actorName string, shooter, t *MechState, dist, baseTarget int, rng *rand.Rand) []ReplayEvent {
+	tn, hit := shooter.designate(t, dist, baseTarget, rng)
+	if tn == 0 {
+		return events
+	}
+	msg := "TAG (TN " + itoa(tn) + "): MISS"
+	if hit {
+		msg = "TAG (TN " + itoa(tn) + "): target designated"
+	}
+	return append(events, ReplayEvent{Type: "fire", Actor: actorName, Message: msg})
+}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	"math"
@@ -11,8 +11,8 @@ import (
 // LOSResult contains the result of a LOS check.
 type LOSResult struct {
 	CanSee       bool
-	WoodsMod     int  // +1 per light woods, +2 per heavy woods in LOS
-	TargetCover  int  // +1 if target is in woods (partial cover)
+	WoodsMod     int  // +1 per light woods/smoke, +2 per heavy woods/smoke in LOS
+	TargetCover  int  // target's hex: woods/smoke level, +1 for depth 1 water (partial cover)
 	ElevationMod int  // -1 if attacker higher, +1 if lower (for to-hit)
 }
 
@@ -58,6 +58,12 @@ func CheckLOS(board *Board, from, to HexCoord) LOSResult {
 	fromLevel := fromHex.Elevation + 1
 	toLevel := toHex.Elevation + 1
 
+	// Nothing sees into or out of deep water: the 'Mech is fully submerged
+	if fromHex.WaterDepth() >= 2 || toHex.WaterDepth() >= 2 {
+		result.CanSee = false
+		return result
+	}
+
 	// Elevation modifier
 	if fromHex.Elevation > toHex.Elevation {
 		result.ElevationMod = -1 // attacker higher = bonus
@@ -69,6 +75,13 @@ func CheckLOS(board *Board, from, to HexCoord) LOSResult {
 	if hasWoods, level := toHex.HasTerrain(TerrainWoods); hasWoods {
 		result.TargetCover = level // +1 light, +2 heavy
 	}
+	if hasSmoke, level := toHex.HasTerrain(TerrainSmoke); hasSmoke {
+		result.TargetCover += level
+	}
+	// Depth 1 water covers the legs (partial cover)
+	if toHex.WaterDepth() == 1 {
+		result.TargetCover++
+	}
 
 	// Walk hex line between from and to (no allocation)
 	dist := HexDistance(from, to)
@@ -94,9 +107,7 @@ func CheckLOS(board *Board, from, to HexCoord) LOSResult {
 		if hasWoods, _ := hex.HasTerrain(TerrainWoods); hasWoods {
 			interLevel += 2 // woods add 2 levels of height
 		}
-		if hasBuilding, level := hex.HasTerrain(TerrainBuilding); hasBuilding {
-			interLevel += level // building levels
-		}
+		interLevel += hex.BuildingHeight()
 
 		// Simple LOS blocking: if intervening terrain is taller than both endpoints
 		if interLevel > fromLevel && interLevel > toLevel {
@@ -115,16 +126,13 @@ func CheckLOS(board *Board, from, to HexCoord) LOSResult {
 			}
 		}
 
-		// Heavy building blocks LOS
-		if hasBuilding, level := hex.HasTerrain(TerrainBuilding); hasBuilding && level >= 3 {
-			if hex.Elevation >= minInt(fromHex.Elevation, toHex.Elevation) {
-				result.CanSee = false
-				return result
-			}
+		// Smoke counts like woods: light 1, heavy 2
+		if hasSmoke, level := hex.HasTerrain(TerrainSmoke); hasSmoke {
+			woodsCount += level
 		}
 	}
 
-	// 2+ woods levels in LOS = blocked
+	// 2+ woods/smoke levels in LOS = blocked
 	if woodsCount >= 2 {
 		result.CanSee = false
 		return result
@@ -1,11 +1,11 @@
-package main
+package sim
 
 import (
-	"context"
+	"io/fs"
+	"path/filepath"
 	"strings"
 
 	"github.com/JustinWhittecar/slic/internal/ingestion"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // ─── Ammo tables ────────────────────────────────────────────────────────────
@@ -210,26 +210,35 @@ func canonicalAmmoType(s string) string {
 	return strings.TrimSpace(s)
 }
 
+// parseAmmoSlotKey returns the ammo pool an ammo slot feeds: the weapon's
+// key for standard ammo, a munition pool otherwise.
 func parseAmmoSlotKey(slotName string) string {
-	return canonicalAmmoType(slotName)
+	return munitionKey(canonicalAmmoType(slotName), munitionOf(slotName))
 }
 
 func guessAmmoShots(slotName string) int {
+	shots := 10
 	key := canonicalAmmoType(slotName)
 	if v, ok := canonicalAmmoPerTon[key]; ok {
-		return v
-	}
-	for k, v := range canonicalAmmoPerTon {
-		if strings.Contains(key, k) || strings.Contains(k, key) {
-			return v
+		shots = v
+	} else {
+		for k, v := range canonicalAmmoPerTon {
+			if strings.Contains(key, k) || strings.Contains(k, key) {
+				shots = v
+				break
+			}
 		}
 	}
-	return 10
+	if mu := munitionOf(slotName); mu == munPrecision || mu == munArmorPiercing {
+		shots /= 2
+	}
+	return shots
 }
 
-// ─── DB types ───────────────────────────────────────────────────────────────
+// ─── Variant input ──────────────────────────────────────────────────────────
 
-type DBWeapon struct {
+// VariantWeapon is one weapon row as stored in variant_equipment/equipment.
+type VariantWeapon struct {
 	Name     string
 	Type     string
 	Damage   int
@@ -244,7 +253,9 @@ type DBWeapon struct {
 	Quantity int
 }
 
-type DBVariant struct {
+// Variant is the flat DB view of a mech variant that BuildMechState consumes.
+// Callers load it from whichever store they own (Postgres, SQLite).
+type Variant struct {
 	ID         int
 	Name       string
 	ModelCode  string
@@ -258,7 +269,12 @@ type DBVariant struct {
 	EngineType string
 	StructType string
 	HasTC      bool
-	Weapons    []DBWeapon
+	ArmorTotal int // only used when no MTF is available
+	Weapons    []VariantWeapon
+
+	// Design quirks; taken from the MTF when both are empty
+	Quirks       []string
+	WeaponQuirks []ingestion.WeaponQuirk
 }
 
 func locNameToIndex(name string) int {
@@ -290,15 +306,24 @@ func weaponToAmmoKey(name string) string {
 
 // ─── Build mech state from DB ───────────────────────────────────────────────
 
-func buildMechState(v *DBVariant, mtf *ingestion.MTFData) *MechState {
+// BuildMechState builds a sim-ready template from a variant and its MTF.
+// mtf supplies armor and crit slots; without it they are estimated from
+// v.ArmorTotal and a standard layout.
+func BuildMechState(v *Variant, mtf *ingestion.MTFData) *MechState {
+	name := v.Name
+	if !strings.HasSuffix(name, v.ModelCode) {
+		name += " " + v.ModelCode
+	}
 	m := &MechState{
-		DebugName:     v.Name + " " + v.ModelCode,
+		DebugName:     name,
 		Tonnage:       v.Tonnage,
 		WalkMP:        v.WalkMP,
 		RunMP:         v.RunMP,
 		JumpMP:        v.JumpMP,
 		HeatSinkCount: v.HSCount,
 		TechBase:      v.TechBase,
+		Gunnery:       DefaultGunnery,
+		Piloting:      DefaultPiloting,
 		Ammo:          make(map[string]int),
 	}
 
@@ -366,6 +391,9 @@ func buildMechState(v *DBVariant, mtf *ingestion.MTFData) *MechState {
 			}
 		}
 
+		m.EW.Stealth = strings.Contains(strings.ToLower(mtf.ArmorType), "stealth")
+		m.addEnhancer(mtf.Myomer)
+
 		for locStr, slots := range mtf.LocationEquipment {
 			li := locNameToIndex(locStr)
 			if li < 0 {
@@ -388,6 +416,7 @@ func buildMechState(v *DBVariant, mtf *ingestion.MTFData) *MechState {
 					} else {
 						shots := guessAmmoShots(slot)
 						m.Ammo[key] += shots
+						m.addMunition(canonicalAmmoType(slot), munitionOf(slot))
 					}
 				}
 				if strings.Contains(sLower, "targeting computer") || strings.Contains(sLower, "istargeting computer") || strings.Contains(sLower, "cltargeting computer") {
@@ -407,10 +436,28 @@ func buildMechState(v *DBVariant, mtf *ingestion.MTFData) *MechState {
 				if strings.Contains(sLower, "apollo") {
 					m.HasApollo = true
 				}
+				if strings.Contains(sLower, "ammo") && strings.Contains(strings.ReplaceAll(sLower, "-", ""), "semiguided") {
+					m.SemiGuided = true
+				}
+				if strings.Contains(sLower, "ammo") && strings.Contains(sLower, "arrow") && strings.Contains(sLower, "homing") {
+					m.HomingArrowIV = true
+				}
+				m.EW.add(slot)
+				m.addEnhancer(slot)
+				if w, ok := tagWeapon(slot, li); ok {
+					m.Weapons = append(m.Weapons, w)
+				}
 			}
 		}
 	}
 
+	if mtf == nil && v.HasTC {
+		m.HasTargetingComputer = true
+	}
+	if m.PartialWing {
+		m.JumpMP += partialWingJump(m.Tonnage)
+	}
+
 	for _, w := range v.Weapons {
 		cat := categorizeWeapon(w.Name)
 		li := locNameToIndex(w.Location)
@@ -456,12 +503,54 @@ func buildMechState(v *DBVariant, mtf *ingestion.MTFData) *MechState {
 		}
 	}
 
+	quirks, weaponQuirks := v.Quirks, v.WeaponQuirks
+	if mtf != nil && len(quirks) == 0 && len(weaponQuirks) == 0 {
+		quirks, weaponQuirks = mtf.Quirks, mtf.WeaponQuirks
+	}
+	applyQuirks(m, quirks, weaponQuirks)
+
+	if mtf == nil {
+		estimateLayout(m, v.ArmorTotal)
+	}
+
+	m.OptimalRange = CalcOptimalRange(m)
 	return m
 }
 
+// LoadMTFs parses every .mtf file under dir, keyed by MTFData.FullName.
+// Unparseable files are skipped.
+func LoadMTFs(dir string) (map[string]*ingestion.MTFData, error) {
+	mtfs := make(map[string]*ingestion.MTFData)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".mtf") {
+			return nil
+		}
+		data, err := ingestion.ParseMTF(path)
+		if err != nil {
+			return nil
+		}
+		mtfs[data.FullName()] = data
+		return nil
+	})
+	return mtfs, err
+}
+
+// LookupMTF finds v's MTF file, which is keyed by name with or without the
+// model code.
+func LookupMTF(mtfs map[string]*ingestion.MTFData, v *Variant) *ingestion.MTFData {
+	if mtf := mtfs[v.Name]; mtf != nil {
+		return mtf
+	}
+	return mtfs[strings.TrimSuffix(v.Name, " "+v.ModelCode)+" "+v.ModelCode]
+}
+
 // ─── HBK-4P hardcoded baseline ──────────────────────────────────────────────
 
-func buildHBK4P() *MechState {
+// BuildHBK4P returns the HBK-4P reference mech that anchors CR 5.0.
+func BuildHBK4P() *MechState {
 	m := &MechState{
 		DebugName:     "Hunchback HBK-4P",
 		Tonnage:       50,
@@ -471,6 +560,8 @@ func buildHBK4P() *MechState {
 		HeatSinkCount: 23,
 		Dissipation:   23,
 		TechBase:      "Inner Sphere",
+		Gunnery:       DefaultGunnery,
+		Piloting:      DefaultPiloting,
 		Ammo:          make(map[string]int),
 	}
 
@@ -511,80 +602,6 @@ func buildHBK4P() *MechState {
 		Type: "energy",
 	})
 
+	m.OptimalRange = CalcOptimalRange(m)
 	return m
 }
-
-// ─── Load variants from DB ──────────────────────────────────────────────────
-
-func loadVariantsFromDB(ctx context.Context, pool *pgxpool.Pool, filter string) ([]DBVariant, error) {
-	query := `
-		SELECT v.id, v.name, v.model_code, COALESCE(c.tech_base, 'Inner Sphere'),
-			   c.tonnage, vs.walk_mp, vs.run_mp, vs.jump_mp,
-			   vs.heat_sink_count, vs.heat_sink_type, vs.engine_type,
-			   COALESCE(vs.structure_type, 'Standard'), COALESCE(vs.has_targeting_computer, false)
-		FROM variants v
-		JOIN chassis c ON c.id = v.chassis_id
-		JOIN variant_stats vs ON vs.variant_id = v.id`
-
-	rows, err := pool.Query(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var variants []DBVariant
-	for rows.Next() {
-		var v DBVariant
-		err := rows.Scan(&v.ID, &v.Name, &v.ModelCode, &v.TechBase,
-			&v.Tonnage, &v.WalkMP, &v.RunMP, &v.JumpMP,
-			&v.HSCount, &v.HSType, &v.EngineType,
-			&v.StructType, &v.HasTC)
-		if err != nil {
-			continue
-		}
-		variants = append(variants, v)
-	}
-
-	if filter != "" {
-		filters := strings.Split(filter, ",")
-		var filtered []DBVariant
-		for _, v := range variants {
-			for _, f := range filters {
-				f = strings.TrimSpace(f)
-				if strings.Contains(v.ModelCode, f) || strings.Contains(v.Name+" "+v.ModelCode, f) {
-					filtered = append(filtered, v)
-					break
-				}
-			}
-		}
-		variants = filtered
-	}
-
-	return variants, nil
-}
-
-func loadWeaponsForVariants(ctx context.Context, pool *pgxpool.Pool, variants []DBVariant) {
-	for i := range variants {
-		v := &variants[i]
-		wRows, err := pool.Query(ctx, `
-			SELECT e.name, COALESCE(e.type,''), COALESCE(e.damage,0), COALESCE(e.heat,0),
-				   COALESCE(e.min_range,0), COALESCE(e.short_range,0), COALESCE(e.medium_range,0),
-				   COALESCE(e.long_range,0), COALESCE(e.to_hit_modifier,0), COALESCE(e.rack_size,0),
-				   ve.location, ve.quantity
-			FROM variant_equipment ve
-			JOIN equipment e ON e.id = ve.equipment_id
-			WHERE ve.variant_id = $1
-			  AND e.type IN ('energy','ballistic','missile','artillery')`, v.ID)
-		if err != nil {
-			continue
-		}
-		for wRows.Next() {
-			var w DBWeapon
-			wRows.Scan(&w.Name, &w.Type, &w.Damage, &w.Heat,
-				&w.MinRange, &w.Short, &w.Medium, &w.Long,
-				&w.ToHitMod, &w.RackSize, &w.Location, &w.Quantity)
-			v.Weapons = append(v.Weapons, w)
-		}
-		wRows.Close()
-	}
-}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import "sync"
 
@@ -22,6 +22,7 @@ type ReachableHex struct {
 	MPSpent int
 	HexesMoved int   // for TMM calculation
 	MoveHeat   int   // heat generated by this movement
+	Boosted    bool  // running on MASC and/or supercharger
 }
 
 // MovementCost returns the MP cost to enter a hex from an adjacent hex.
@@ -61,6 +62,10 @@ func MovementCost(board *Board, from, to HexCoord, mode MoveMode) int {
 		cost += 1
 	}
 
+	if hasRubble, _ := hex.HasTerrain(TerrainRubble); hasRubble {
+		cost += 1
+	}
+
 	// Road reduces cost (but minimum 1)
 	if hasRoad, _ := hex.HasTerrain(TerrainRoad); hasRoad {
 		if cost > 1 {
@@ -68,6 +73,15 @@ func MovementCost(board *Board, from, to HexCoord, mode MoveMode) int {
 		}
 	}
 
+	// Buildings: light +1 through hardened +4
+	if hasBuilding, class := hex.HasTerrain(TerrainBuilding); hasBuilding {
+		cost += class
+	}
+
+	if mode != ModeJump {
+		cost += board.Conditions.moveCost()
+	}
+
 	// Elevation change > 2 levels is impassable for ground movement
 	if mode != ModeJump && absInt(elevDiff) > 2 {
 		return -1
@@ -132,6 +146,7 @@ func ReachableHexes(board *Board, start HexCoord, facing int,
 // bfsPool provides reusable allocations for BFS ground movement.
 type bfsPool struct {
 	visited map[HexCoord]int
+	order   []HexCoord // first-visit order, so results don't depend on map iteration
 	queue   []bfsState
 }
 
@@ -148,6 +163,7 @@ var bfsPoolSync = sync.Pool{
 	New: func() any {
 		return &bfsPool{
 			visited: make(map[HexCoord]int, 128),
+			order:   make([]HexCoord, 0, 128),
 			queue:   make([]bfsState, 0, 128),
 		}
 	},
@@ -163,6 +179,7 @@ func reachableGround(board *Board, start HexCoord, startFacing int,
 		delete(pool.visited, k)
 	}
 	pool.visited[start] = 0
+	pool.order = append(pool.order[:0], start)
 	pool.queue = pool.queue[:0]
 	pool.queue = append(pool.queue, bfsState{coord: start, mpLeft: totalMP, hexes: 0})
 
@@ -191,9 +208,13 @@ func reachableGround(board *Board, start HexCoord, startFacing int,
 			}
 
 			mpSpent := totalMP - remaining
-			if prev, ok := visited[n]; ok && mpSpent >= prev {
+			prev, seen := visited[n]
+			if seen && mpSpent >= prev {
 				continue
 			}
+			if !seen {
+				pool.order = append(pool.order, n)
+			}
 			visited[n] = mpSpent
 
 			newHexes := cur.hexes + 1
@@ -202,7 +223,8 @@ func reachableGround(board *Board, start HexCoord, startFacing int,
 	}
 
 	// Generate results for all reachable hexes with optimal facing toward center
-	for coord, mpSpent := range visited {
+	for _, coord := range pool.order {
+		mpSpent := visited[coord]
 		if coord == start && mpSpent == 0 {
 			continue // skip standing still (handled by ModeStand)
 		}
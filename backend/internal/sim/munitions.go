@@ -0,0 +1,254 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Alternate munitions ────────────────────────────────────────────────────
+//
+// Each ammo bin holds one munition. Standard ammo is pooled under the
+// weapon's AmmoKey; every other munition gets its own pool, keyed
+// "<AmmoKey>:<munition>", so bins explode and run dry separately. A weapon
+// loads the munition with the best expected damage for each shot:
+//
+//   - Artemis-capable: the only ammo Artemis IV/V works with
+//   - LB-X cluster: cluster table, 1 damage per pellet, -1 to-hit; standard
+//     LB-X ammo is the slug, fired like an autocannon
+//   - Precision AC: cancels up to 2 points of target movement modifier
+//   - Armor-piercing AC: rolls for criticals wherever it hits, at -4/-3/-2/-1
+//     for an AC/2/5/10/20
+//   - Tandem-charge SRM: each missile rolls for criticals at -2
+//   - Inferno SRM: no damage; 2 heat per missile
+//   - Swarm LRM, flak AC: fire like standard ammo against a single 'Mech
+//
+// Precision and armor-piercing ammo carry half the shots per ton.
+
+type munition int
+
+const (
+	munStandard munition = iota
+	munArtemis
+	munCluster
+	munPrecision
+	munArmorPiercing
+	munTandem
+	munInferno
+	munSwarm
+	munFlak
+)
+
+var munitionNames = [...]string{"standard", "artemis", "cluster", "precision", "armor-piercing", "tandem-charge", "inferno", "swarm", "flak"}
+
+func (mu munition) String() string { return munitionNames[mu] }
+
+// critWorth is what one critical hit is worth in points of damage when
+// choosing a munition.
+const critWorth = 5.0
+
+// munitionOf parses the munition of an ammo slot.
+func munitionOf(slot string) munition {
+	s := strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(slot))
+	switch {
+	case strings.Contains(s, "artemis"):
+		return munArtemis
+	case strings.Contains(s, "cluster"):
+		return munCluster
+	case strings.Contains(s, "precision"):
+		return munPrecision
+	case strings.Contains(s, "armorpiercing"):
+		return munArmorPiercing
+	case strings.Contains(s, "tandem"):
+		return munTandem
+	case strings.Contains(s, "inferno"):
+		return munInferno
+	case strings.Contains(s, "swarm"):
+		return munSwarm
+	case strings.Contains(s, "flak"):
+		return munFlak
+	}
+	return munStandard
+}
+
+// munitionKey is the ammo pool for munition mu of weapons using key.
+func munitionKey(key string, mu munition) string {
+	if mu == munStandard {
+		return key
+	}
+	return key + ":" + mu.String()
+}
+
+// addMunition records that m carries munition mu for weapons using key.
+func (m *MechState) addMunition(key string, mu munition) {
+	if mu == munStandard {
+		return
+	}
+	for _, have := range m.Munitions[key] {
+		if have == mu {
+			return
+		}
+	}
+	if m.Munitions == nil {
+		m.Munitions = map[string][]munition{}
+	}
+	m.Munitions[key] = append(m.Munitions[key], mu)
+}
+
+// hasAmmo reports whether w has a shot left of any munition.
+func (m *MechState) hasAmmo(w *SimWeapon) bool {
+	if w.AmmoKey == "" || m.Ammo[w.AmmoKey] > 0 {
+		return true
+	}
+	for _, mu := range m.Munitions[w.AmmoKey] {
+		if m.Ammo[munitionKey(w.AmmoKey, mu)] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// load picks the munition for w's shot at t on target number tn, sets
+// w.Loaded and spends the round. False if w is out of ammo.
+func (m *MechState) load(w *SimWeapon, t *MechState, tn int) bool {
+	w.Loaded = munStandard
+	if w.AmmoKey == "" {
+		return true
+	}
+	best, bestEV := munition(-1), 0.0
+	for i := -1; i < len(m.Munitions[w.AmmoKey]); i++ {
+		mu := munStandard
+		if i >= 0 {
+			mu = m.Munitions[w.AmmoKey][i]
+		}
+		if m.Ammo[munitionKey(w.AmmoKey, mu)] <= 0 {
+			continue
+		}
+		if ev := m.munitionDamage(w, mu, t, tn); best < 0 || ev > bestEV {
+			best, bestEV = mu, ev
+		}
+	}
+	if best < 0 {
+		return false
+	}
+	w.Loaded = best
+	m.Ammo[munitionKey(w.AmmoKey, best)]--
+	return true
+}
+
+// munitionMod is the to-hit modifier for firing mu from w at t: the LB-X
+// slug doesn't get the cluster -1 built into the weapon's ToHitMod,
+// precision rounds cancel up to 2 points of movement modifier, and Artemis
+// V is -1 with its ammo.
+func (m *MechState) munitionMod(w *SimWeapon, mu munition, t *MechState) int {
+	switch {
+	case w.Category == catLBX && mu != munCluster:
+		return 1
+	case mu == munPrecision:
+		return -min(2, max(0, tmmFromHexesMoved(t.LastHexMoved, t.LastMoveMode)))
+	case mu == munArtemis && m.HasArtemisV && m.UnderECM == ecmNone:
+		return -1
+	}
+	return 0
+}
+
+// munitionDamage is the expected damage of one shot of mu from w at t on
+// target number tn, counting criticals and heat as damage.
+func (m *MechState) munitionDamage(w *SimWeapon, mu munition, t *MechState, tn int) float64 {
+	p := hitProb(tn + m.munitionMod(w, mu, t))
+	switch w.Category {
+	case catLBX:
+		if mu == munCluster {
+			return clusterExpected(w.RackSize, 0) * p
+		}
+		return float64(w.Damage) * p
+	case catLRM:
+		return clusterExpected(w.RackSize, artemisBonus(m, mu)) * p
+	case catSRM, catMML:
+		hits := clusterExpected(w.RackSize, artemisBonus(m, mu))
+		switch mu {
+		case munInferno:
+			return hits * 2 * heatWorth(t) * p
+		case munTandem:
+			return hits * (2 + critWorth*expectedCrits(-2)) * p
+		}
+		return hits * 2 * p
+	}
+	if mu == munArmorPiercing {
+		return (float64(w.Damage) + critWorth*expectedCrits(apCritMod(w))) * p
+	}
+	return float64(w.Damage) * p
+}
+
+// clusterExpected is the average cluster table result for rackSize with
+// bonus on the roll.
+func clusterExpected(rackSize, bonus int) float64 {
+	colIdx := 0
+	for i, rs := range clusterRackSizes {
+		if rs <= rackSize {
+			colIdx = i
+		}
+	}
+	weights := [11]int{1, 2, 3, 4, 5, 6, 5, 4, 3, 2, 1}
+	total := 0.0
+	for row := 0; row < 11; row++ {
+		r := min(max(row+bonus, 0), 10)
+		total += float64(clusterTable[r][colIdx] * weights[row])
+	}
+	return total / 36
+}
+
+// expectedCrits is the average number of criticals from one roll on the
+// determining criticals table at mod.
+func expectedCrits(mod int) float64 {
+	return hitProb(8-mod) + hitProb(10-mod) + hitProb(12-mod)
+}
+
+// heatWorth is what a point of heat on t is worth in damage: more once t is
+// already running hot.
+func heatWorth(t *MechState) float64 {
+	if t.Heat >= 5 {
+		return 1
+	}
+	return 0.5
+}
+
+// apCritMod is the critical roll modifier for an armor-piercing round.
+func apCritMod(w *SimWeapon) int {
+	switch {
+	case w.Damage <= 2:
+		return -4
+	case w.Damage <= 5:
+		return -3
+	case w.Damage <= 10:
+		return -2
+	}
+	return -1
+}
+
+// armorPiercing rolls for criticals in loc after an armor-piercing hit,
+// whether or not the armor held.
+func (m *MechState) armorPiercing(loc, mod int, rng *rand.Rand) {
+	if loc < 0 || loc >= NumLoc || m.IS[loc] <= 0 {
+		return
+	}
+	n := 0
+	switch r := roll2d6(rng) + mod; {
+	case r >= 12 && (loc == LocCT || loc == LocLT || loc == LocRT):
+		n = 3
+	case r >= 10:
+		n = 2
+	case r >= 8:
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		m.applyCrit(loc, rng)
+	}
+}
+
+// shotName is w's name for replays, with the munition it fired.
+func (w *SimWeapon) shotName() string {
+	if w.Loaded == munStandard {
+		return w.Name
+	}
+	return w.Name + " [" + w.Loaded.String() + "]"
+}
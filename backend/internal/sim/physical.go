@@ -0,0 +1,427 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Physical attacks ───────────────────────────────────────────────────────
+//
+// Total Warfare physical attacks: punches, kicks, hatchets, swords, maces,
+// claws, charges and death from above. A 'Mech makes one kick, or one
+// punch/melee weapon attack per arm. Charges and DFAs are declared in the
+// movement phase and cost the turn's weapon fire; the sim declares them at
+// the start of the weapon phase (declarePhysical) when they beat firing.
+
+type physicalKind int
+
+const (
+	physNone physicalKind = iota
+	physPunch
+	physKick
+	physCharge
+	physDFA
+)
+
+type meleeWeapon int
+
+const (
+	meleeNone meleeWeapon = iota
+	meleeHatchet
+	meleeSword
+	meleeMace
+	meleeClaws
+)
+
+var physicalNames = [...]string{physCharge: "Charge", physDFA: "Death from Above"}
+
+var meleeNames = [...]string{"Punch", "Hatchet", "Sword", "Mace", "Claws"}
+
+// limbCrits records which actuators of an arm or leg have been hit. Shoulder
+// doubles as hip and Hand as foot.
+type limbCrits struct {
+	Shoulder, Upper, Lower, Hand bool
+}
+
+func (l *limbCrits) hit(slot string) {
+	switch {
+	case strings.Contains(slot, "shoulder"):
+		l.Shoulder = true
+	case strings.Contains(slot, "upper"):
+		l.Upper = true
+	case strings.Contains(slot, "lower"):
+		l.Lower = true
+	case strings.Contains(slot, "hand"), strings.Contains(slot, "foot"):
+		l.Hand = true
+	}
+}
+
+// meleeWeaponOf identifies a melee weapon crit slot (lower-cased).
+func meleeWeaponOf(slot string) meleeWeapon {
+	switch {
+	case strings.Contains(slot, "hatchet"):
+		return meleeHatchet
+	case strings.Contains(slot, "sword"):
+		return meleeSword
+	case strings.Contains(slot, "mace"):
+		return meleeMace
+	case strings.Contains(slot, "claw"):
+		return meleeClaws
+	}
+	return meleeNone
+}
+
+// PhysicalResult is one physical attack roll, for replays.
+type PhysicalResult struct {
+	Name   string
+	TN     int
+	Hit    bool
+	Damage int
+}
+
+// punchTable is the 1d6 punch location table (TW p.147).
+var punchTable = [6]int{LocLA, LocLT, LocCT, LocRT, LocRA, LocHD}
+
+func ceilDiv(a, b int) int { return (a + b - 1) / b }
+
+// hasActuator reports whether an arm was built with the named actuator.
+// Mechs without crit slots are assumed to have full arms.
+func (m *MechState) hasActuator(loc int, name string) bool {
+	if len(m.Slots[loc]) == 0 {
+		return true
+	}
+	for _, s := range m.Slots[loc] {
+		if strings.Contains(strings.ToLower(s), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MechState) noArms() bool {
+	for _, q := range m.Quirks {
+		if q.NoArms {
+			return true
+		}
+	}
+	return false
+}
+
+// armAttack is the best punch or melee weapon attack one arm can make.
+type armAttack struct {
+	name   string
+	loc    int
+	tn     int
+	damage int
+}
+
+// armAttackFor returns the attack loc can make this turn, if any (TW p.145,
+// TO for maces and claws). baseTN already holds the piloting skill, movement
+// and SPA modifiers.
+func (m *MechState) armAttackFor(loc, baseTN int) (armAttack, bool) {
+	lc := m.LimbCrits[loc]
+	if m.IS[loc] <= 0 || lc.Shoulder || m.FiredFrom[loc] || m.noArms() {
+		return armAttack{}, false
+	}
+	hasLower := m.hasActuator(loc, "lower arm") && !lc.Lower
+	hasHand := m.hasActuator(loc, "hand") && !lc.Hand
+
+	weapon := meleeNone
+	if !m.MeleeDestroyed[loc] {
+		for _, s := range m.Slots[loc] {
+			if mw := meleeWeaponOf(strings.ToLower(s)); mw != meleeNone {
+				weapon = mw
+				break
+			}
+		}
+	}
+
+	tons := m.Tonnage
+	var a armAttack
+	switch {
+	case weapon == meleeClaws && hasLower:
+		a = armAttack{tn: baseTN + 1, damage: ceilDiv(tons, 7)}
+	case weapon != meleeNone && weapon != meleeClaws && hasLower && hasHand:
+		switch weapon {
+		case meleeHatchet:
+			a = armAttack{tn: baseTN - 1, damage: ceilDiv(tons, 5)}
+		case meleeSword:
+			a = armAttack{tn: baseTN - 2, damage: ceilDiv(tons, 10) + 1}
+		case meleeMace:
+			a = armAttack{tn: baseTN + 1, damage: ceilDiv(tons, 4)}
+		}
+	default:
+		// Punch: missing or destroyed actuators add to the roll and halve damage
+		weapon = meleeNone
+		a = armAttack{tn: baseTN, damage: ceilDiv(tons, 10)}
+		if !hasLower {
+			a.tn += 2
+			a.damage /= 2
+		}
+		if !hasHand {
+			a.tn++
+		}
+		for _, q := range m.Quirks {
+			a.damage += q.PunchDamage[loc]
+		}
+	}
+	if lc.Upper {
+		a.tn += 2
+		a.damage /= 2
+	}
+	if m.tsmActive() {
+		a.damage *= 2
+	}
+	a.name = meleeNames[weapon]
+	a.loc = loc
+	return a, a.damage > 0
+}
+
+// kickAttack returns the best kick (TW p.147), if either leg can kick.
+func (m *MechState) kickAttack(baseTN int) (armAttack, bool) {
+	best, ok := armAttack{}, false
+	for _, loc := range []int{LocLL, LocRL} {
+		other := LocLL + LocRL - loc
+		lc := m.LimbCrits[loc]
+		if m.IS[loc] <= 0 || m.IS[other] <= 0 || lc.Shoulder || m.FiredFrom[loc] {
+			continue
+		}
+		a := armAttack{name: "Kick", loc: loc, tn: baseTN - 2, damage: m.Tonnage / 5}
+		for _, hit := range []bool{lc.Upper, lc.Lower} {
+			if hit {
+				a.tn += 2
+				a.damage /= 2
+			}
+		}
+		if lc.Hand {
+			a.tn++
+		}
+		if m.tsmActive() {
+			a.damage *= 2
+		}
+		if !ok || hitProb(a.tn)*float64(a.damage) > hitProb(best.tn)*float64(best.damage) {
+			best, ok = a, true
+		}
+	}
+	return best, ok
+}
+
+// dfaTN is the to-hit for a death from above: piloting skill plus the
+// target's movement (TW p.150).
+func (m *MechState) dfaTN(t *MechState) int {
+	spaMod, _, _ := m.spaPhysical(t)
+	return m.Piloting + tmmFromHexesMoved(t.LastHexMoved, t.LastMoveMode) + spaMod
+}
+
+// chargeTN is the DFA to-hit adjusted by the difference in piloting skills
+// (TW p.148).
+func (m *MechState) chargeTN(t *MechState) int {
+	return m.dfaTN(t) + m.Piloting - t.Piloting
+}
+
+func (m *MechState) canCharge(t *MechState) bool {
+	_, _, ok := m.spaPhysical(t)
+	return ok && !m.Prone && !t.Prone && m.LastHexMoved > 0 &&
+		(m.LastMoveMode == ModeWalk || m.LastMoveMode == ModeRun)
+}
+
+func (m *MechState) canDFA(t *MechState) bool {
+	_, _, ok := m.spaPhysical(t)
+	return ok && !m.Prone && m.LastMoveMode == ModeJump && m.IS[LocLL] > 0 && m.IS[LocRL] > 0
+}
+
+func (m *MechState) chargeDamage() int { return ceilDiv(m.Tonnage*max(m.LastHexMoved, 1), 10) }
+func (m *MechState) dfaDamage() int    { return ceilDiv(m.Tonnage, 10) * 3 }
+
+// declarePhysical decides, before weapon fire, whether m charges or DFAs t
+// instead of shooting. weaponEV is the expected damage of a normal volley.
+// Damage m would take counts against the attack at half weight.
+func (m *MechState) declarePhysical(t *MechState, dist int, weaponEV float64) bool {
+	m.DeclaredPhysical, m.PhysicalTarget = physNone, nil
+	if dist != 1 {
+		return false
+	}
+	bestEV := weaponEV
+	if m.canCharge(t) {
+		p := hitProb(m.chargeTN(t))
+		ev := p * (float64(m.chargeDamage()) - 0.5*float64(ceilDiv(t.Tonnage, 10)))
+		if ev > bestEV {
+			bestEV, m.DeclaredPhysical = ev, physCharge
+		}
+	}
+	if m.canDFA(t) {
+		p := hitProb(m.dfaTN(t))
+		ev := p*(float64(m.dfaDamage())-0.5*float64(ceilDiv(m.Tonnage, 5))) -
+			(1-p)*0.5*float64(ceilDiv(m.Tonnage, 10))
+		if ev > bestEV {
+			m.DeclaredPhysical = physDFA
+		}
+	}
+	if m.DeclaredPhysical == physNone {
+		return false
+	}
+	m.PhysicalTarget = t
+	return true
+}
+
+// resolvePhysical makes attacker's physical attacks on an adjacent defender
+// and returns the rolls made.
+func resolvePhysical(attacker, defender *MechState, rng *rand.Rand) []PhysicalResult {
+	// Can only do physical attacks at range 1
+	dist := HexDistance(attacker.Pos, defender.Pos)
+	if dist != 1 || attacker.Prone || attacker.IsShutdown {
+		return nil
+	}
+	spaMod, spaDmg, ok := attacker.spaPhysical(defender)
+	if !ok {
+		return nil
+	}
+
+	defArc := DetermineArc(defender.Pos, ((defender.Facing+defender.TorsoTwist)%6+6)%6, attacker.Pos)
+	isRear := defArc == ArcRear
+
+	if attacker.DeclaredPhysical != physNone {
+		// The charge or DFA replaced weapon fire; it is only ever against
+		// the declared target
+		if attacker.PhysicalTarget != defender {
+			return nil
+		}
+	}
+	switch attacker.DeclaredPhysical {
+	case physCharge:
+		return []PhysicalResult{resolveCharge(attacker, defender, isRear, spaDmg, rng)}
+	case physDFA:
+		return []PhysicalResult{resolveDFA(attacker, defender, isRear, spaDmg, rng)}
+	}
+
+	// Punches/melee weapons or a kick, whichever does more: piloting skill
+	// plus attacker and target movement (BMM: physical attacks include TMM)
+	baseTN := attacker.Piloting + spaMod +
+		attackerMoveMod(attacker.LastMoveMode, attacker.SPAs) +
+		tmmFromHexesMoved(defender.LastHexMoved, defender.LastMoveMode)
+
+	var arms []armAttack
+	armsEV := 0.0
+	for _, loc := range []int{LocLA, LocRA} {
+		if a, ok := attacker.armAttackFor(loc, baseTN); ok {
+			arms = append(arms, a)
+			armsEV += hitProb(max(a.tn, 2)) * float64(a.damage+spaDmg)
+		}
+	}
+	kick, canKick := attacker.kickAttack(baseTN)
+	if canKick && hitProb(max(kick.tn, 2))*float64(kick.damage+spaDmg) > armsEV {
+		return []PhysicalResult{resolveKick(attacker, defender, kick, isRear, spaDmg, rng)}
+	}
+
+	var results []PhysicalResult
+	for _, a := range arms {
+		tn := max(a.tn, 2)
+		res := PhysicalResult{Name: a.name, TN: tn}
+		if roll2d6(rng) >= tn {
+			res.Hit = true
+			res.Damage = a.damage + spaDmg
+			defender.applyDamage(punchTable[rng.IntN(6)], res.Damage, isRear, rng)
+		}
+		results = append(results, res)
+		if defender.isDestroyed() {
+			break
+		}
+	}
+	return results
+}
+
+func resolveKick(attacker, defender *MechState, kick armAttack, isRear bool, spaDmg int, rng *rand.Rand) PhysicalResult {
+	tn := max(kick.tn, 2)
+	res := PhysicalResult{Name: "Kick", TN: tn}
+	if roll2d6(rng) < tn {
+		// Missed kick: attacker checks to stay standing
+		if !attacker.rollPSR(0, rng) {
+			attacker.applyFall(rng)
+		}
+		return res
+	}
+	res.Hit = true
+	res.Damage = kick.damage + spaDmg
+	loc := LocRL
+	if roll1d6(rng) >= 4 {
+		loc = LocLL
+	}
+	defender.applyDamage(loc, res.Damage, isRear, rng)
+
+	// Kicked mech needs PSR
+	if !defender.Prone && !defender.rollPSR(0, rng) {
+		defender.applyFall(rng)
+	}
+	return res
+}
+
+// applyClusters deals dmg in 5-point groups using the standard hit table.
+func applyClusters(m *MechState, dmg int, isRear bool, rng *rand.Rand) {
+	for dmg > 0 {
+		grp := min(dmg, 5)
+		m.applyDamage(rollHitLocation(isRear, rng), grp, isRear, rng)
+		dmg -= grp
+	}
+}
+
+// resolveCharge: target takes tonnage/10 per hex moved, the attacker takes
+// the target's tonnage/10, and both make a +2 PSR (TW p.148).
+func resolveCharge(attacker, defender *MechState, isRear bool, spaDmg int, rng *rand.Rand) PhysicalResult {
+	tn := max(attacker.chargeTN(defender), 2)
+	res := PhysicalResult{Name: physicalNames[physCharge], TN: tn}
+	if roll2d6(rng) < tn {
+		return res
+	}
+	res.Hit = true
+	res.Damage = attacker.chargeDamage() + spaDmg
+	applyClusters(defender, res.Damage, isRear, rng)
+	applyClusters(attacker, ceilDiv(defender.Tonnage, 10), false, rng)
+	for _, m := range []*MechState{defender, attacker} {
+		if !m.isDestroyed() && !m.rollPSR(2, rng) {
+			m.applyFall(rng)
+		}
+	}
+	return res
+}
+
+// resolveDFA: target takes 3 × tonnage/10 on the punch table and makes a +2
+// PSR; the attacker takes tonnage/5 to the legs and makes a +4 PSR. A miss
+// drops the attacker (TW p.150).
+func resolveDFA(attacker, defender *MechState, isRear bool, spaDmg int, rng *rand.Rand) PhysicalResult {
+	tn := max(attacker.dfaTN(defender), 2)
+	res := PhysicalResult{Name: physicalNames[physDFA], TN: tn}
+	if roll2d6(rng) < tn {
+		attacker.applyFall(rng)
+		return res
+	}
+	res.Hit = true
+	res.Damage = attacker.dfaDamage() + spaDmg
+	for dmg := res.Damage; dmg > 0; dmg -= 5 {
+		loc := punchTable[rng.IntN(6)]
+		if defender.Prone {
+			loc = rollHitLocation(true, rng)
+		}
+		defender.applyDamage(loc, min(dmg, 5), isRear, rng)
+	}
+	for dmg := ceilDiv(attacker.Tonnage, 5); dmg > 0; dmg -= 5 {
+		loc := LocRL
+		if roll1d6(rng) >= 4 {
+			loc = LocLL
+		}
+		attacker.applyDamage(loc, min(dmg, 5), false, rng)
+	}
+	if !defender.isDestroyed() && !defender.Prone && !defender.rollPSR(2, rng) {
+		defender.applyFall(rng)
+	}
+	if !attacker.isDestroyed() && !attacker.rollPSR(4, rng) {
+		attacker.applyFall(rng)
+	}
+	return res
+}
+
+// clearTurnAttacks resets the per-turn attack state after the mech moves.
+func (m *MechState) clearTurnAttacks() {
+	m.FiredFrom = [NumLoc]bool{}
+	m.DeclaredPhysical, m.PhysicalTarget = physNone, nil
+	m.Tagged = false
+}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import "math/rand/v2"
 
@@ -30,7 +30,7 @@ func (m *MechState) rollPSR(extraMod int, rng *rand.Rand) bool {
 	if m.GyroHits >= 2 {
 		return false
 	}
-	target := pilotingSkill + m.psrPreexistingMod() + extraMod
+	target := m.Piloting + m.psrPreexistingMod() + m.psrMod() + extraMod
 	return roll2d6(rng) >= target
 }
 
@@ -41,7 +41,7 @@ func (m *MechState) rollPSRForStanding(rng *rand.Rand) bool {
 	if m.GyroHits >= 2 {
 		return false
 	}
-	target := pilotingSkill + m.psrPreexistingMod()
+	target := m.Piloting + m.psrPreexistingMod() + m.psrMod()
 	return roll2d6(rng) >= target
 }
 
@@ -62,7 +62,7 @@ func (m *MechState) applyFall(rng *rand.Rand) {
 		remaining -= grp
 	}
 
-	target := pilotingSkill + m.psrPreexistingMod()
+	target := m.Piloting + m.psrPreexistingMod() + m.psrMod()
 	if m.PilotUnconscious || roll2d6(rng) < target {
 		m.PilotDamage++
 		if m.PilotDamage >= 6 {
@@ -0,0 +1,174 @@
+package sim
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+)
+
+// ─── Design quirks ──────────────────────────────────────────────────────────
+//
+// Quirk keys are MegaMek's (the quirk: and weaponquirk: lines of an MTF).
+// Like SPAs, every modifier is added to a target number. Quirks do not
+// change BV.
+
+// Quirk is the sim effect of one unit quirk.
+type Quirk struct {
+	Key   string
+	Label string
+
+	RangeMod    [3]int      // to-hit at short, medium, long (improved/poor targeting)
+	AttackMod   int         // to-hit for every weapon attack
+	PSRMod      int         // every piloting skill roll
+	Initiative  int         // side initiative while the unit is in play
+	PunchDamage [NumLoc]int // extra punch damage by arm
+	NoArms      bool        // cannot punch
+	NoEffect    bool        // recognised, but nothing it affects is simulated
+}
+
+// WeaponQuirk is the sim effect of one weapon quirk.
+type WeaponQuirk struct {
+	Key   string
+	Label string
+	ToHit int
+	Heat  int
+}
+
+var quirkRegistry = map[string]*Quirk{
+	"imp_target_short":  {Label: "Improved Targeting (Short)", RangeMod: [3]int{-1, 0, 0}},
+	"imp_target_med":    {Label: "Improved Targeting (Medium)", RangeMod: [3]int{0, -1, 0}},
+	"imp_target_long":   {Label: "Improved Targeting (Long)", RangeMod: [3]int{0, 0, -1}},
+	"poor_target_short": {Label: "Poor Targeting (Short)", RangeMod: [3]int{1, 0, 0}},
+	"poor_target_med":   {Label: "Poor Targeting (Medium)", RangeMod: [3]int{0, 1, 0}},
+	"poor_target_long":  {Label: "Poor Targeting (Long)", RangeMod: [3]int{0, 0, 1}},
+	"sensor_ghosts":     {Label: "Sensor Ghosts", AttackMod: 1},
+	"battle_fists_la":   {Label: "Battle Fists (LA)", PunchDamage: [NumLoc]int{LocLA: 1}},
+	"battle_fists_ra":   {Label: "Battle Fists (RA)", PunchDamage: [NumLoc]int{LocRA: 1}},
+	"command_mech":      {Label: "Command 'Mech", Initiative: 1},
+	"cramped_cockpit":   {Label: "Cramped Cockpit", PSRMod: 1},
+	"hard_pilot":        {Label: "Hard to Pilot", PSRMod: 1},
+	"no_arms":           {Label: "No/Minimal Arms", NoArms: true},
+	// Only battle armor leg and swarm attacks care, and the sim has none
+	"exposed_actuators":   {Label: "Exposed Actuators", NoEffect: true},
+	"protected_actuators": {Label: "Protected Actuators", NoEffect: true},
+}
+
+var weaponQuirkRegistry = map[string]*WeaponQuirk{
+	"accurate":     {Label: "Accurate Weapon", ToHit: -1},
+	"inaccurate":   {Label: "Inaccurate Weapon", ToHit: 1},
+	"imp_cooling":  {Label: "Improved Cooling Jacket", Heat: -1},
+	"poor_cooling": {Label: "Poor Cooling Jacket", Heat: 1},
+	"no_cooling":   {Label: "No Cooling Jacket", Heat: 2},
+}
+
+func init() {
+	for k, q := range quirkRegistry {
+		q.Key = k
+	}
+	for k, q := range weaponQuirkRegistry {
+		q.Key = k
+	}
+}
+
+// LookupQuirk returns the registered unit quirk for a MegaMek key.
+func LookupQuirk(key string) (*Quirk, bool) {
+	q, ok := quirkRegistry[strings.ToLower(key)]
+	return q, ok
+}
+
+// LookupWeaponQuirk returns the registered weapon quirk for a MegaMek key.
+func LookupWeaponQuirk(key string) (*WeaponQuirk, bool) {
+	q, ok := weaponQuirkRegistry[strings.ToLower(key)]
+	return q, ok
+}
+
+// applyQuirks attaches unit quirks to m and folds weapon quirks into the
+// matching weapons. Unknown quirks are ignored. Several of one weapon in a
+// location are told apart by crit slot: the n-th lowest slot quirked goes
+// to the n-th such weapon.
+func applyQuirks(m *MechState, quirks []string, weaponQuirks []ingestion.WeaponQuirk) {
+	for _, key := range quirks {
+		if q, ok := LookupQuirk(key); ok && !q.NoEffect {
+			m.Quirks = append(m.Quirks, q)
+		}
+	}
+	slots := make(map[string][]int)
+	quirkKey := func(wq ingestion.WeaponQuirk) string {
+		return wq.Location + "|" + normalizeQuirkWeapon(wq.Weapon)
+	}
+	for _, wq := range weaponQuirks {
+		if k := quirkKey(wq); !slices.Contains(slots[k], wq.Slot) {
+			slots[k] = append(slots[k], wq.Slot)
+		}
+	}
+	for _, s := range slots {
+		slices.Sort(s)
+	}
+	for _, wq := range weaponQuirks {
+		q, ok := LookupWeaponQuirk(wq.Quirk)
+		if !ok {
+			continue
+		}
+		loc := locNameToIndex(wq.Location)
+		want := normalizeQuirkWeapon(wq.Weapon)
+		n := slices.Index(slots[quirkKey(wq)], wq.Slot)
+		for i := range m.Weapons {
+			w := &m.Weapons[i]
+			if (loc >= 0 && w.Location != loc) || normalizeQuirkWeapon(w.Name) != want {
+				continue
+			}
+			if n > 0 {
+				n--
+				continue
+			}
+			w.ToHitMod += q.ToHit
+			w.Heat = max(w.Heat+q.Heat, 0)
+			break
+		}
+	}
+}
+
+// normalizeQuirkWeapon reduces "ISMediumLaser" and "Medium Laser" to the same key.
+func normalizeQuirkWeapon(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	for _, p := range []string{"clan", "cl", "is"} {
+		if strings.HasPrefix(s, p) {
+			return s[len(p):]
+		}
+	}
+	return s
+}
+
+// quirkWeaponMod sums the unit-quirk modifiers for a weapon attack with the
+// given range modifier (0, 2 or 4).
+func quirkWeaponMod(quirks []*Quirk, rangeMod int) int {
+	mod := 0
+	for _, q := range quirks {
+		mod += q.AttackMod
+		if b := rangeMod / 2; b >= 0 && b < 3 {
+			mod += q.RangeMod[b]
+		}
+	}
+	return mod
+}
+
+// initiativeBonus is the best quirk initiative bonus among units still in play.
+func initiativeBonus(units ...*MechState) int {
+	best := 0
+	for _, m := range units {
+		if m.isDestroyed() {
+			continue
+		}
+		for _, q := range m.Quirks {
+			best = max(best, q.Initiative)
+		}
+	}
+	return best
+}
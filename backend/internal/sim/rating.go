@@ -0,0 +1,24 @@
+package sim
+
+import "math"
+
+// KFactor scales the log turn ratio onto the CR scale.
+const KFactor = 3.5
+
+// EngineVersion identifies the sim's rules and AI in run manifests. Bump it
+// with any change that is meant to move ratings.
+const EngineVersion = "2.1"
+
+// CombatRating maps offense/defense median turns to the 1–10 CR scale.
+// A mech whose defense/offense ratio equals baselineRatio scores 5.0.
+func CombatRating(offTurns, defTurns, baselineRatio float64) float64 {
+	ratio := defTurns / offTurns
+	score := 5.0 + KFactor*math.Log(ratio/baselineRatio)
+	if score < 1 {
+		score = 1
+	}
+	if score > 10 {
+		score = 10
+	}
+	return score
+}
@@ -1,7 +1,8 @@
-package main
+package sim
 
 import (
 	"encoding/json"
+	"fmt"
 	"math/rand/v2"
 	"strconv"
 	"strings"
@@ -47,6 +48,7 @@ type ReplayWeaponFire struct {
 	Actor    string `json:"actor"`
 	Target   int    `json:"target"`
 	Roll     int    `json:"roll,omitempty"`
+	TN       int    `json:"tn,omitempty"` // to-hit target number
 	Hit      bool   `json:"hit"`
 	Damage   int    `json:"damage"`
 	Location string `json:"location,omitempty"`
@@ -54,12 +56,35 @@ type ReplayWeaponFire struct {
 }
 
 type ReplayEvent struct {
-	Type    string `json:"type"` // "move", "fire", "physical", "heat", "psr", "crit", "destroyed", "fall", "info"
+	Type    string `json:"type"` // "move", "fire", "physical", "terrain", "heat", "psr", "crit", "destroyed", "fall", "info"
 	Actor   string `json:"actor"`
 	Message string `json:"message"`
 	Detail  string `json:"detail,omitempty"`
 }
 
+// physicalEvents turns physical attack rolls into replay events.
+func physicalEvents(actor string, results []PhysicalResult) []ReplayEvent {
+	var events []ReplayEvent
+	for _, r := range results {
+		msg := fmt.Sprintf("%s (TN %d): MISS", r.Name, r.TN)
+		if r.Hit {
+			msg = fmt.Sprintf("%s (TN %d): %d damage", r.Name, r.TN, r.Damage)
+		}
+		events = append(events, ReplayEvent{Type: "physical", Actor: actor, Message: msg})
+	}
+	return events
+}
+
+// noteEvents turns notes from entering a hex, MASC checks and the like into
+// replay events of the given type.
+func noteEvents(kind, actor string, notes []string) []ReplayEvent {
+	var events []ReplayEvent
+	for _, n := range notes {
+		events = append(events, ReplayEvent{Type: kind, Actor: actor, Message: n})
+	}
+	return events
+}
+
 type ReplayTurn struct {
 	Turn     int                  `json:"turn"`
 	Attacker ReplayMechSnapshot   `json:"attacker"`
@@ -69,13 +94,16 @@ type ReplayTurn struct {
 }
 
 type ReplayData struct {
-	AttackerName string       `json:"attackerName"`
-	DefenderName string       `json:"defenderName"`
-	BoardWidth   int          `json:"boardWidth"`
-	BoardHeight  int          `json:"boardHeight"`
-	Hexes        []ReplayHex  `json:"hexes"`
-	Turns        []ReplayTurn `json:"turns"`
-	Result       string       `json:"result"`
+	Version       int                 `json:"version"` // see ReplayVersion
+	AttackerName  string              `json:"attackerName"`
+	DefenderName  string              `json:"defenderName"`
+	BoardWidth    int                 `json:"boardWidth"`
+	BoardHeight   int                 `json:"boardHeight"`
+	Hexes         []ReplayHex         `json:"hexes"`
+	AttackerStart *ReplayMechSnapshot `json:"attackerStart,omitempty"` // as deployed, before turn 1
+	DefenderStart *ReplayMechSnapshot `json:"defenderStart,omitempty"`
+	Turns         []ReplayTurn        `json:"turns"`
+	Result        string              `json:"result"`
 }
 
 // ─── Snapshot helpers ───────────────────────────────────────────────────────
@@ -124,7 +152,9 @@ func snapshotMech(m *MechState) ReplayMechSnapshot {
 
 func boardToReplayHexes(board *Board) []ReplayHex {
 	var hexes []ReplayHex
-	for _, h := range board.Hexes {
+	// Parsed and combined boards only keep the flat Grid (buildGrid drops Hexes)
+	for i := range board.Grid {
+		h := &board.Grid[i]
 		terrain := ""
 		var parts []string
 		for _, f := range h.Terrain {
@@ -145,6 +175,12 @@ func boardToReplayHexes(board *Board) []ReplayHex {
 				parts = append(parts, "pavement")
 			case TerrainRoad:
 				parts = append(parts, "road")
+			case TerrainRubble:
+				parts = append(parts, "rubble")
+			case TerrainFire:
+				parts = append(parts, "fire")
+			case TerrainSmoke:
+				parts = append(parts, "smoke")
 			}
 		}
 		if len(parts) > 0 {
@@ -160,11 +196,13 @@ func boardToReplayHexes(board *Board) []ReplayHex {
 
 // ─── Replay-enabled simulation ──────────────────────────────────────────────
 
-func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) *ReplayData {
+func SimulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) *ReplayData {
+	board = board.forSim()
 	attacker := cloneMech(attackerTemplate)
 	defender := cloneMech(defenderTemplate)
 
 	replay := &ReplayData{
+		Version:      ReplayVersion,
 		AttackerName: attacker.DebugName,
 		DefenderName: defender.DebugName,
 		BoardWidth:   board.Width,
@@ -176,8 +214,10 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 	attacker.Facing = 3
 	defender.Pos = HexCoord{Col: board.Width/2 + 1, Row: board.Height - 1}
 	defender.Facing = 0
+	atkStart, defStart := snapshotMech(attacker), snapshotMech(defender)
+	replay.AttackerStart, replay.DefenderStart = &atkStart, &defStart
 
-	for turn := 1; turn <= maxTurns; turn++ {
+	for turn := 1; turn <= MaxTurns; turn++ {
 		turnData := ReplayTurn{Turn: turn}
 		var events []ReplayEvent
 
@@ -212,10 +252,10 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 				break
 			}
 			attacker.Heat += attacker.HeatPenalty; attacker.HeatPenalty = 0
-			attacker.Heat -= attacker.Dissipation
+			attacker.Heat -= attacker.dissipation(board.Conditions)
 			if attacker.Heat < 0 { attacker.Heat = 0 }
 			defender.Heat += defender.HeatPenalty; defender.HeatPenalty = 0
-			defender.Heat -= defender.Dissipation
+			defender.Heat -= defender.dissipation(board.Conditions)
 			if defender.Heat < 0 { defender.Heat = 0 }
 			turnData.Events = events
 			turnData.Attacker = snapshotMech(attacker)
@@ -247,8 +287,8 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 		}
 
 		// Initiative
-		atkInit := rng.IntN(6) + 1
-		defInit := rng.IntN(6) + 1
+		atkInit := rng.IntN(6) + 1 + initiativeBonus(attacker)
+		defInit := rng.IntN(6) + 1 + initiativeBonus(defender)
 		atkMovesFirst := atkInit < defInit
 		if atkInit == defInit {
 			atkMovesFirst = rng.IntN(2) == 0
@@ -275,8 +315,14 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 			defRun = defender.effectiveRunMP()
 		}
 
-		atkM2 := &MechState2{Pos: attacker.Pos, Facing: attacker.Facing, WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP, Tonnage: attacker.Tonnage, GunnerySkill: gunnerySkill, Heat: attacker.Heat}
-		defM2 := &MechState2{Pos: defender.Pos, Facing: defender.Facing, WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP, Tonnage: defender.Tonnage, GunnerySkill: gunnerySkill, Heat: defender.Heat}
+		atkM2 := &MechState2{Pos: attacker.Pos, Facing: attacker.Facing, WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP, Tonnage: attacker.Tonnage, GunnerySkill: attacker.Gunnery, PilotingSkill: attacker.Piloting, SPAs: attacker.SPAs, Quirks: attacker.Quirks, Heat: attacker.Heat}
+		defM2 := &MechState2{Pos: defender.Pos, Facing: defender.Facing, WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP, Tonnage: defender.Tonnage, GunnerySkill: defender.Gunnery, PilotingSkill: defender.Piloting, SPAs: defender.SPAs, Quirks: defender.Quirks, Heat: defender.Heat}
+		if atkWalk > 0 {
+			atkM2.BoostRunMP = attacker.boostRunMP()
+		}
+		if defWalk > 0 {
+			defM2.BoostRunMP = defender.boostRunMP()
+		}
 		for _, w := range attacker.Weapons {
 			if !w.Destroyed && !w.Jammed {
 				atkM2.Weapons = append(atkM2.Weapons, SimWeapon2{Name: w.Name, Damage: w.Damage, Heat: w.Heat, MinRange: w.MinRange, ShortRange: w.ShortRange, MedRange: w.MedRange, LongRange: w.LongRange, Location: w.Location, ToHitMod: w.ToHitMod})
@@ -293,23 +339,25 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 
 		var atkChoice, defChoice ReachableHex
 		if atkMovesFirst {
-			atkChoice = ChooseMovement(board, atkM2, defM2, false, defM2.Pos, defM2.Facing, defOptions, rng, atkOptions)
-			defChoice = ChooseMovement(board, defM2, atkM2, true, atkChoice.Coord, atkChoice.Facing, atkOptions, rng, defOptions)
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2, false, defM2.Pos, defM2.Facing, defOptions, atkOptions, rng)
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2, true, atkChoice.Coord, atkChoice.Facing, atkOptions, defOptions, rng)
 		} else {
-			defChoice = ChooseMovement(board, defM2, atkM2, false, atkM2.Pos, atkM2.Facing, atkOptions, rng, defOptions)
-			atkChoice = ChooseMovement(board, atkM2, defM2, true, defChoice.Coord, defChoice.Facing, defOptions, rng, atkOptions)
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2, false, atkM2.Pos, atkM2.Facing, atkOptions, defOptions, rng)
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2, true, defChoice.Coord, defChoice.Facing, defOptions, atkOptions, rng)
 		}
 
 		attacker.Pos = atkChoice.Coord
 		attacker.Facing = atkChoice.Facing
 		attacker.LastMoveMode = atkChoice.Mode
 		attacker.LastHexMoved = atkChoice.HexesMoved
+		attacker.clearTurnAttacks()
 		attacker.Heat += atkChoice.MoveHeat
 
 		defender.Pos = defChoice.Coord
 		defender.Facing = defChoice.Facing
 		defender.LastMoveMode = defChoice.Mode
 		defender.LastHexMoved = defChoice.HexesMoved
+		defender.clearTurnAttacks()
 		defender.Heat += defChoice.MoveHeat
 
 		events = append(events, ReplayEvent{
@@ -322,15 +370,24 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 			Message: "Moves to (" + itoa(defender.Pos.Col) + "," + itoa(defender.Pos.Row) + ") " + moveModeStr(defChoice.Mode),
 			Detail:  itoa(defChoice.HexesMoved) + " hexes, +" + itoa(defChoice.MoveHeat) + " heat",
 		})
+		events = append(events, noteEvents("terrain", "attacker", attacker.enterHex(board, rng))...)
+		events = append(events, noteEvents("move", "attacker", attacker.boost(atkChoice.Boosted, rng))...)
+		events = append(events, noteEvents("terrain", "defender", defender.enterHex(board, rng))...)
+		events = append(events, noteEvents("move", "defender", defender.boost(defChoice.Boosted, rng))...)
 
 		// Torso twist
 		attacker.TorsoTwist = BestTorsoTwist(attacker.Pos, attacker.Facing, defender.Pos)
 		defender.TorsoTwist = BestTorsoTwist(defender.Pos, defender.Facing, attacker.Pos)
 
+		// Stealth and Null-Signature, now both have moved
+		attacker.ai().ChooseEW(attacker, board, []*MechState{defender})
+		defender.ai().ChooseEW(defender, board, []*MechState{attacker})
+
 		defender.AMSUsedThisTurn = false
 
 		dist := HexDistance(attacker.Pos, defender.Pos)
 		los := CheckLOS(board, attacker.Pos, defender.Pos)
+		attacker.electronics(board, defender, nil, nil)
 
 		if los.CanSee && dist > 0 {
 			defEffFacing := ((defender.Facing + defender.TorsoTwist) % 6 + 6) % 6
@@ -338,17 +395,10 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 			isRear := arcToDefender == ArcRear
 
 			defTMM := tmmFromHexesMoved(defChoice.HexesMoved, defChoice.Mode)
-			heatThisMod := heatToHitMod(attacker.Heat)
-			baseTarget := gunnerySkill + attacker.SensorHits*2 + heatThisMod
+			heatThisMod := heatToHitMod(attacker.Heat, board.Conditions)
+			baseTarget := attacker.Gunnery + attacker.SensorHits*2 + heatThisMod
 
-			switch atkChoice.Mode {
-			case ModeWalk:
-				baseTarget += 1
-			case ModeRun:
-				baseTarget += 2
-			case ModeJump:
-				baseTarget += 3
-			}
+			baseTarget += attackerMoveMod(atkChoice.Mode, attacker.SPAs)
 			baseTarget += defTMM
 			baseTarget += los.WoodsMod
 			baseTarget += los.TargetCover
@@ -367,33 +417,43 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 				Detail:  "TMM:" + itoa(defTMM) + " woods:" + itoa(los.WoodsMod) + " heat:" + itoa(heatThisMod),
 			})
 
-			firingWeapons, weaponHeatTotal := selectWeaponsEV(attacker, board, defender, dist, baseTarget)
+			declared := attacker.ai().ChoosePhysical(attacker, defender, dist, calcExpectedDamage(attacker, dist, baseTarget, 0))
+			var firingWeapons []int
+			weaponHeatTotal := 0
+			if declared {
+				events = append(events, ReplayEvent{Type: "physical", Actor: "attacker", Message: "Declares " + physicalNames[attacker.DeclaredPhysical] + " instead of firing"})
+			} else {
+				if attacker.guided() && !defender.Tagged {
+					events = tagEvent(events, "attacker", attacker, defender, dist, baseTarget, rng)
+				}
+				firingWeapons, weaponHeatTotal = attacker.ai().ChooseFire(attacker, board, defender, dist, baseTarget)
+			}
 			attacker.Heat += weaponHeatTotal
 
 			totalDmgDealt := 0
 			for _, wi := range firingWeapons {
 				w := &attacker.Weapons[wi]
-				if w.AmmoKey != "" {
-					if attacker.Ammo[w.AmmoKey] <= 0 { continue }
-					attacker.Ammo[w.AmmoKey]--
-				}
-
-				target := baseTarget + w.ToHitMod + attacker.ArmActuatorHit[w.Location]
+				target := baseTarget + w.ToHitMod + attacker.ArmActuatorHit[w.Location] + attacker.attackMod(w, defender, dist)
 				rm := rangeModifier(w, dist)
 				if rm < 0 { continue }
 				target += rm
 				if w.MinRange > 0 && dist <= w.MinRange {
 					target += w.MinRange - dist + 1
 				}
+				if !attacker.load(w, defender, target) { continue }
+				target += attacker.munitionMod(w, w.Loaded, defender)
+				target = attacker.finalTN(w, defender, target)
 
+				before := stateOf(defender)
 				dmg := resolveWeaponFire2D(w, target, isRear, attacker, defender, rng)
 				totalDmgDealt += dmg
+				turnData.Weapons = append(turnData.Weapons, recordShot(w, "attacker", target, dmg, before, defender))
 
 				hitStr := "MISS"
 				if dmg > 0 { hitStr = itoa(dmg) + " dmg" }
 				events = append(events, ReplayEvent{
 					Type: "fire", Actor: "attacker",
-					Message: w.Name + " (TN " + itoa(target) + "): " + hitStr,
+					Message: w.shotName() + " (TN " + itoa(target) + "): " + hitStr,
 				})
 
 				if defender.isDestroyed() {
@@ -427,28 +487,42 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 		} else if dist == 0 {
 			events = append(events, ReplayEvent{Type: "info", Actor: "system", Message: "Same hex - no fire"})
 		} else {
-			events = append(events, ReplayEvent{Type: "info", Actor: "system", Message: "No LOS"})
+			n := len(events)
+			var dmg int
+			var destroyed bool
+			events, dmg, destroyed = fireIndirect(attacker, defender, board, atkChoice, defChoice, nil, "attacker", events, &turnData.Weapons, rng)
+			if destroyed {
+				turnData.Events = events
+				turnData.Attacker = snapshotMech(attacker)
+				turnData.Defender = snapshotMech(defender)
+				replay.Turns = append(replay.Turns, turnData)
+				replay.Result = "defender_destroyed_turn_" + itoa(turn)
+				return replay
+			}
+			if len(events) == n {
+				events = append(events, ReplayEvent{Type: "info", Actor: "system", Message: "No LOS"})
+			}
+			if dmg >= 20 && !defender.Prone {
+				if !defender.rollPSR(1, rng) {
+					defender.applyFall(rng)
+					events = append(events, ReplayEvent{Type: "psr", Actor: "defender", Message: "Failed 20+ dmg PSR, falls!"})
+				} else {
+					events = append(events, ReplayEvent{Type: "psr", Actor: "defender", Message: "Passed 20+ dmg PSR"})
+				}
+			}
 		}
 
 		// Physical
 		if dist == 1 {
-			prevIS := defender.IS
-			resolvePhysical(attacker, defender, rng)
-			kicked := false
-			for i := 0; i < NumLoc; i++ {
-				if defender.IS[i] < prevIS[i] { kicked = true; break }
-			}
-			if kicked {
-				events = append(events, ReplayEvent{Type: "physical", Actor: "attacker", Message: "Kick!"})
-			}
+			events = append(events, physicalEvents("attacker", resolvePhysical(attacker, defender, rng))...)
 		}
 
 		// Heat dissipation (including plasma heat from enemy)
 		attacker.Heat += attacker.HeatPenalty; attacker.HeatPenalty = 0
-		attacker.Heat -= attacker.Dissipation
+		attacker.Heat -= attacker.dissipation(board.Conditions)
 		if attacker.Heat < 0 { attacker.Heat = 0 }
 		defender.Heat += defender.HeatPenalty; defender.HeatPenalty = 0
-		defender.Heat -= defender.Dissipation
+		defender.Heat -= defender.dissipation(board.Conditions)
 		if defender.Heat < 0 { defender.Heat = 0 }
 
 		// Shutdown check
@@ -496,7 +570,7 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 	}
 
 	if replay.Result == "" {
-		replay.Result = "timeout_" + itoa(maxTurns)
+		replay.Result = "timeout_" + itoa(MaxTurns)
 	}
 	return replay
 }
@@ -504,30 +578,27 @@ func simulateReplay(board *Board, attackerTemplate, defenderTemplate *MechState,
 // ─── Duel replay (mutual combat) ────────────────────────────────────────────
 
 // fireWeaponsReplay handles one side firing at the other, returning events, total damage, and whether target was destroyed.
-func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice, targetChoice ReachableHex, actorName string, events []ReplayEvent, rng *rand.Rand) ([]ReplayEvent, int, bool) {
+// Spotters are the shooter's teammates, who can spot for indirect fire.
+func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice, targetChoice ReachableHex, spotters []*MechState, actorName string, events []ReplayEvent, fires *[]ReplayWeaponFire, rng *rand.Rand) ([]ReplayEvent, int, bool) {
 	dist := HexDistance(shooter.Pos, target.Pos)
 	los := CheckLOS(board, shooter.Pos, target.Pos)
 
-	if !los.CanSee || dist <= 0 {
+	if dist <= 0 {
 		return events, 0, false
 	}
+	if !los.CanSee {
+		return fireIndirect(shooter, target, board, shooterChoice, targetChoice, spotters, actorName, events, fires, rng)
+	}
 
 	targetEffFacing := ((target.Facing + target.TorsoTwist) % 6 + 6) % 6
 	arcToTarget := DetermineArc(target.Pos, targetEffFacing, shooter.Pos)
 	isRear := arcToTarget == ArcRear
 
 	targetTMM := tmmFromHexesMoved(targetChoice.HexesMoved, targetChoice.Mode)
-	heatMod := heatToHitMod(shooter.Heat)
-	baseTarget := gunnerySkill + shooter.SensorHits*2 + heatMod
+	heatMod := heatToHitMod(shooter.Heat, board.Conditions)
+	baseTarget := shooter.Gunnery + shooter.SensorHits*2 + heatMod
 
-	switch shooterChoice.Mode {
-	case ModeWalk:
-		baseTarget += 1
-	case ModeRun:
-		baseTarget += 2
-	case ModeJump:
-		baseTarget += 3
-	}
+	baseTarget += attackerMoveMod(shooterChoice.Mode, shooter.SPAs)
 	baseTarget += targetTMM
 	baseTarget += los.WoodsMod
 	baseTarget += los.TargetCover
@@ -554,20 +625,22 @@ func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice,
 		Detail:  "TMM:" + itoa(targetTMM) + " woods:" + itoa(los.WoodsMod) + " heat:" + itoa(heatMod),
 	})
 
-	firingWeapons, weaponHeatTotal := selectWeaponsEV(shooter, board, target, dist, baseTarget)
+	if shooter.ai().ChoosePhysical(shooter, target, dist, calcExpectedDamage(shooter, dist, baseTarget, 0)) {
+		events = append(events, ReplayEvent{Type: "physical", Actor: actorName, Message: "Declares " + physicalNames[shooter.DeclaredPhysical] + " instead of firing"})
+		return events, 0, false
+	}
+
+	if !target.Tagged && (shooter.guided() || anyGuided(spotters)) {
+		events = tagEvent(events, actorName, shooter, target, dist, baseTarget, rng)
+	}
+
+	firingWeapons, weaponHeatTotal := shooter.ai().ChooseFire(shooter, board, target, dist, baseTarget)
 	shooter.Heat += weaponHeatTotal
 
 	totalDmg := 0
 	for _, wi := range firingWeapons {
 		w := &shooter.Weapons[wi]
-		if w.AmmoKey != "" {
-			if shooter.Ammo[w.AmmoKey] <= 0 {
-				continue
-			}
-			shooter.Ammo[w.AmmoKey]--
-		}
-
-		tn := baseTarget + w.ToHitMod + shooter.ArmActuatorHit[w.Location]
+		tn := baseTarget + w.ToHitMod + shooter.ArmActuatorHit[w.Location] + shooter.attackMod(w, target, dist)
 		rm := rangeModifier(w, dist)
 		if rm < 0 {
 			continue
@@ -576,9 +649,21 @@ func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice,
 		if w.MinRange > 0 && dist <= w.MinRange {
 			tn += w.MinRange - dist + 1
 		}
+		if !shooter.load(w, target, tn) {
+			continue
+		}
+		tn += shooter.munitionMod(w, w.Loaded, target)
+		tn = shooter.finalTN(w, target, tn)
 
+		var before shotState
+		if fires != nil {
+			before = stateOf(target)
+		}
 		dmg := resolveWeaponFire2D(w, tn, isRear, shooter, target, rng)
 		totalDmg += dmg
+		if fires != nil {
+			*fires = append(*fires, recordShot(w, actorName, tn, dmg, before, target))
+		}
 
 		hitStr := "MISS"
 		if dmg > 0 {
@@ -586,7 +671,7 @@ func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice,
 		}
 		events = append(events, ReplayEvent{
 			Type: "fire", Actor: actorName,
-			Message: w.Name + " (TN " + itoa(tn) + "): " + hitStr,
+			Message: w.shotName() + " (TN " + itoa(tn) + "): " + hitStr,
 		})
 
 		if target.isDestroyed() {
@@ -602,11 +687,13 @@ func fireWeaponsReplay(shooter, target *MechState, board *Board, shooterChoice,
 	return events, totalDmg, false
 }
 
-func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) *ReplayData {
+func SimulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) *ReplayData {
+	board = board.forSim()
 	attacker := cloneMech(attackerTemplate)
 	defender := cloneMech(defenderTemplate)
 
 	replay := &ReplayData{
+		Version:      ReplayVersion,
 		AttackerName: attacker.DebugName,
 		DefenderName: defender.DebugName,
 		BoardWidth:   board.Width,
@@ -618,8 +705,10 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 	attacker.Facing = 3
 	defender.Pos = HexCoord{Col: board.Width/2 + 1, Row: board.Height - 1}
 	defender.Facing = 0
+	atkStart, defStart := snapshotMech(attacker), snapshotMech(defender)
+	replay.AttackerStart, replay.DefenderStart = &atkStart, &defStart
 
-	for turn := 1; turn <= maxTurns; turn++ {
+	for turn := 1; turn <= MaxTurns; turn++ {
 		turnData := ReplayTurn{Turn: turn}
 		var events []ReplayEvent
 
@@ -704,8 +793,8 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 		}
 
 		// Initiative
-		atkInit := rng.IntN(6) + 1
-		defInit := rng.IntN(6) + 1
+		atkInit := rng.IntN(6) + 1 + initiativeBonus(attacker)
+		defInit := rng.IntN(6) + 1 + initiativeBonus(defender)
 		atkMovesFirst := atkInit < defInit
 		if atkInit == defInit {
 			atkMovesFirst = rng.IntN(2) == 0
@@ -732,8 +821,14 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 			defRun = defender.effectiveRunMP()
 		}
 
-		atkM2 := &MechState2{Pos: attacker.Pos, Facing: attacker.Facing, WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP, Tonnage: attacker.Tonnage, GunnerySkill: gunnerySkill, Heat: attacker.Heat}
-		defM2 := &MechState2{Pos: defender.Pos, Facing: defender.Facing, WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP, Tonnage: defender.Tonnage, GunnerySkill: gunnerySkill, Heat: defender.Heat}
+		atkM2 := &MechState2{Pos: attacker.Pos, Facing: attacker.Facing, WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP, Tonnage: attacker.Tonnage, GunnerySkill: attacker.Gunnery, PilotingSkill: attacker.Piloting, SPAs: attacker.SPAs, Quirks: attacker.Quirks, Heat: attacker.Heat}
+		defM2 := &MechState2{Pos: defender.Pos, Facing: defender.Facing, WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP, Tonnage: defender.Tonnage, GunnerySkill: defender.Gunnery, PilotingSkill: defender.Piloting, SPAs: defender.SPAs, Quirks: defender.Quirks, Heat: defender.Heat}
+		if atkWalk > 0 {
+			atkM2.BoostRunMP = attacker.boostRunMP()
+		}
+		if defWalk > 0 {
+			defM2.BoostRunMP = defender.boostRunMP()
+		}
 		for _, w := range attacker.Weapons {
 			if !w.Destroyed && !w.Jammed {
 				atkM2.Weapons = append(atkM2.Weapons, SimWeapon2{Name: w.Name, Damage: w.Damage, Heat: w.Heat, MinRange: w.MinRange, ShortRange: w.ShortRange, MedRange: w.MedRange, LongRange: w.LongRange, Location: w.Location, ToHitMod: w.ToHitMod})
@@ -750,23 +845,25 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 
 		var atkChoice, defChoice ReachableHex
 		if atkMovesFirst {
-			atkChoice = ChooseMovement(board, atkM2, defM2, false, defM2.Pos, defM2.Facing, defOptions, rng, atkOptions)
-			defChoice = ChooseMovement(board, defM2, atkM2, true, atkChoice.Coord, atkChoice.Facing, atkOptions, rng, defOptions)
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2, false, defM2.Pos, defM2.Facing, defOptions, atkOptions, rng)
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2, true, atkChoice.Coord, atkChoice.Facing, atkOptions, defOptions, rng)
 		} else {
-			defChoice = ChooseMovement(board, defM2, atkM2, false, atkM2.Pos, atkM2.Facing, atkOptions, rng, defOptions)
-			atkChoice = ChooseMovement(board, atkM2, defM2, true, defChoice.Coord, defChoice.Facing, defOptions, rng, atkOptions)
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2, false, atkM2.Pos, atkM2.Facing, atkOptions, defOptions, rng)
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2, true, defChoice.Coord, defChoice.Facing, defOptions, atkOptions, rng)
 		}
 
 		attacker.Pos = atkChoice.Coord
 		attacker.Facing = atkChoice.Facing
 		attacker.LastMoveMode = atkChoice.Mode
 		attacker.LastHexMoved = atkChoice.HexesMoved
+		attacker.clearTurnAttacks()
 		attacker.Heat += atkChoice.MoveHeat
 
 		defender.Pos = defChoice.Coord
 		defender.Facing = defChoice.Facing
 		defender.LastMoveMode = defChoice.Mode
 		defender.LastHexMoved = defChoice.HexesMoved
+		defender.clearTurnAttacks()
 		defender.Heat += defChoice.MoveHeat
 
 		events = append(events, ReplayEvent{
@@ -779,11 +876,19 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 			Message: "Moves to (" + itoa(defender.Pos.Col) + "," + itoa(defender.Pos.Row) + ") " + moveModeStr(defChoice.Mode),
 			Detail:  itoa(defChoice.HexesMoved) + " hexes, +" + itoa(defChoice.MoveHeat) + " heat",
 		})
+		events = append(events, noteEvents("terrain", "attacker", attacker.enterHex(board, rng))...)
+		events = append(events, noteEvents("move", "attacker", attacker.boost(atkChoice.Boosted, rng))...)
+		events = append(events, noteEvents("terrain", "defender", defender.enterHex(board, rng))...)
+		events = append(events, noteEvents("move", "defender", defender.boost(defChoice.Boosted, rng))...)
 
 		// Torso twist
 		attacker.TorsoTwist = BestTorsoTwist(attacker.Pos, attacker.Facing, defender.Pos)
 		defender.TorsoTwist = BestTorsoTwist(defender.Pos, defender.Facing, attacker.Pos)
 
+		// Stealth and Null-Signature, now both have moved
+		attacker.ai().ChooseEW(attacker, board, []*MechState{defender})
+		defender.ai().ChooseEW(defender, board, []*MechState{attacker})
+
 		defender.AMSUsedThisTurn = false
 		attacker.AMSUsedThisTurn = false
 
@@ -794,7 +899,8 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 		destroyed := false
 
 		if dist > 0 {
-			events, atkDmg, destroyed = fireWeaponsReplay(attacker, defender, board, atkChoice, defChoice, "attacker", events, rng)
+			attacker.electronics(board, defender, nil, nil)
+			events, atkDmg, destroyed = fireWeaponsReplay(attacker, defender, board, atkChoice, defChoice, nil, "attacker", events, &turnData.Weapons, rng)
 			if destroyed {
 				turnData.Events = events
 				turnData.Attacker = snapshotMech(attacker)
@@ -804,7 +910,8 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 				return replay
 			}
 
-			events, defDmg, destroyed = fireWeaponsReplay(defender, attacker, board, defChoice, atkChoice, "defender", events, rng)
+			defender.electronics(board, attacker, nil, nil)
+			events, defDmg, destroyed = fireWeaponsReplay(defender, attacker, board, defChoice, atkChoice, nil, "defender", events, &turnData.Weapons, rng)
 			if destroyed {
 				turnData.Events = events
 				turnData.Attacker = snapshotMech(attacker)
@@ -853,104 +960,23 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 
 		// Physical attacks — both sides
 		if dist == 1 {
-			prevDefIS := defender.IS
-			resolvePhysical(attacker, defender, rng)
-			kicked := false
-			for i := 0; i < NumLoc; i++ {
-				if defender.IS[i] < prevDefIS[i] {
-					kicked = true
-					break
-				}
-			}
-			if kicked {
-				events = append(events, ReplayEvent{Type: "physical", Actor: "attacker", Message: "Kick!"})
-			}
-
-			prevAtkIS := attacker.IS
-			resolvePhysical(defender, attacker, rng)
-			kickedBack := false
-			for i := 0; i < NumLoc; i++ {
-				if attacker.IS[i] < prevAtkIS[i] {
-					kickedBack = true
-					break
-				}
-			}
-			if kickedBack {
-				events = append(events, ReplayEvent{Type: "physical", Actor: "defender", Message: "Kick!"})
-			}
-		}
-
-		// Heat dissipation — both (including plasma heat from enemy)
-		attacker.Heat += attacker.HeatPenalty; attacker.HeatPenalty = 0
-		attacker.Heat -= attacker.Dissipation
-		if attacker.Heat < 0 {
-			attacker.Heat = 0
-		}
-		defender.Heat += defender.HeatPenalty; defender.HeatPenalty = 0
-		defender.Heat -= defender.Dissipation
-		if defender.Heat < 0 {
-			defender.Heat = 0
-		}
-
-		// Shutdown/ammo explosion checks — attacker
-		shutdownP := heatShutdownProb(attacker.Heat)
-		if shutdownP >= 1.0 || (shutdownP > 0 && rng.Float64() < shutdownP) {
-			attacker.IsShutdown = true
-			events = append(events, ReplayEvent{Type: "heat", Actor: "attacker", Message: "SHUTDOWN at heat " + itoa(attacker.Heat)})
-		}
-		ammoExpP := heatAmmoExpProb(attacker.Heat)
-		if ammoExpP > 0 && rng.Float64() < ammoExpP {
-			events = append(events, ReplayEvent{Type: "heat", Actor: "attacker", Message: "Ammo explosion from heat!"})
-			type ammoBin struct {
-				key string
-				loc int
-			}
-			var bins []ammoBin
-			for loc := 0; loc < NumLoc; loc++ {
-				for _, slot := range attacker.Slots[loc] {
-					sLower := strings.ToLower(slot)
-					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
-						key := parseAmmoSlotKey(slot)
-						if attacker.Ammo[key] > 0 {
-							bins = append(bins, ammoBin{key, loc})
-						}
-					}
-				}
-			}
-			if len(bins) > 0 {
-				bin := bins[rng.IntN(len(bins))]
-				attacker.ammoExplosion(bin.loc, bin.key, rng)
+			events = append(events, physicalEvents("attacker", resolvePhysical(attacker, defender, rng))...)
+			if !attacker.isDestroyed() && !defender.isDestroyed() {
+				events = append(events, physicalEvents("defender", resolvePhysical(defender, attacker, rng))...)
 			}
 		}
 
-		// Shutdown/ammo explosion checks — defender
-		shutdownPD := heatShutdownProb(defender.Heat)
-		if shutdownPD >= 1.0 || (shutdownPD > 0 && rng.Float64() < shutdownPD) {
-			defender.IsShutdown = true
-			events = append(events, ReplayEvent{Type: "heat", Actor: "defender", Message: "SHUTDOWN at heat " + itoa(defender.Heat)})
-		}
-		ammoExpPD := heatAmmoExpProb(defender.Heat)
-		if ammoExpPD > 0 && rng.Float64() < ammoExpPD {
-			events = append(events, ReplayEvent{Type: "heat", Actor: "defender", Message: "Ammo explosion from heat!"})
-			type ammoBin struct {
-				key string
-				loc int
+		// Heat phase — both (including plasma heat from enemy)
+		for _, side := range [2]struct {
+			m    *MechState
+			name string
+		}{{attacker, "attacker"}, {defender, "defender"}} {
+			shutdown, ammoExp := heatPhase(side.m, board.Conditions, rng)
+			if shutdown {
+				events = append(events, ReplayEvent{Type: "heat", Actor: side.name, Message: "SHUTDOWN at heat " + itoa(side.m.Heat)})
 			}
-			var bins []ammoBin
-			for loc := 0; loc < NumLoc; loc++ {
-				for _, slot := range defender.Slots[loc] {
-					sLower := strings.ToLower(slot)
-					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
-						key := parseAmmoSlotKey(slot)
-						if defender.Ammo[key] > 0 {
-							bins = append(bins, ammoBin{key, loc})
-						}
-					}
-				}
-			}
-			if len(bins) > 0 {
-				bin := bins[rng.IntN(len(bins))]
-				defender.ammoExplosion(bin.loc, bin.key, rng)
+			if ammoExp {
+				events = append(events, ReplayEvent{Type: "heat", Actor: side.name, Message: "Ammo explosion from heat!"})
 			}
 		}
 
@@ -970,7 +996,7 @@ func simulateDuelReplay(board *Board, attackerTemplate, defenderTemplate *MechSt
 	}
 
 	if replay.Result == "" {
-		replay.Result = "timeout_" + itoa(maxTurns)
+		replay.Result = "timeout_" + itoa(MaxTurns)
 	}
 	return replay
 }
@@ -979,6 +1005,6 @@ func itoa(i int) string {
 	return strconv.Itoa(i)
 }
 
-func replayToJSON(r *ReplayData) ([]byte, error) {
+func ReplayToJSON(r *ReplayData) ([]byte, error) {
 	return json.Marshal(r)
 }
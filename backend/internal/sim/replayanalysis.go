@@ -0,0 +1,401 @@
+package sim
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ─── Recording shots ────────────────────────────────────────────────────────
+
+// shotState is what a shot can take from its target, kept from before the
+// shot so recordShot can tell where it hit and what it broke.
+type shotState struct {
+	armor     [NumLoc]int
+	rearArmor [3]int
+	is        [NumLoc]int
+	engine    int
+	gyro      int
+	sensors   int
+	cockpit   bool
+	pilot     int
+	weapons   []bool // destroyed, by index
+}
+
+func stateOf(m *MechState) shotState {
+	s := shotState{
+		armor: m.Armor, rearArmor: m.RearArmor, is: m.IS,
+		engine: m.EngineHits, gyro: m.GyroHits, sensors: m.SensorHits,
+		cockpit: m.CockpitHit, pilot: m.PilotDamage,
+		weapons: make([]bool, len(m.Weapons)),
+	}
+	for i := range m.Weapons {
+		s.weapons[i] = m.Weapons[i].Destroyed
+	}
+	return s
+}
+
+// rearLocs are the locations of RearArmor's entries.
+var rearLocs = [3]int{LocCT, LocLT, LocRT}
+
+// recordShot describes a shot by w at t, which was in state before before
+// it: the locations it damaged and, as Crit, everything it broke.
+func recordShot(w *SimWeapon, actor string, tn, dmg int, before shotState, t *MechState) ReplayWeaponFire {
+	f := ReplayWeaponFire{Weapon: w.shotName(), Actor: actor, Target: 1, TN: tn, Hit: dmg > 0, Damage: dmg}
+	if actor == "defender" {
+		f.Target = 0
+	}
+	if dmg == 0 {
+		return f
+	}
+
+	var lost [NumLoc]int
+	for loc := 0; loc < NumLoc; loc++ {
+		lost[loc] = before.armor[loc] - t.Armor[loc] + max(before.is[loc], 0) - max(t.IS[loc], 0)
+	}
+	for i, loc := range rearLocs {
+		lost[loc] += before.rearArmor[i] - t.RearArmor[i]
+	}
+	var locs, crits []string
+	for loc := 0; loc < NumLoc; loc++ {
+		if lost[loc] > 0 {
+			locs = append(locs, locNames[loc])
+		}
+	}
+	for range t.EngineHits - before.engine {
+		crits = append(crits, "engine hit")
+	}
+	for range t.GyroHits - before.gyro {
+		crits = append(crits, "gyro hit")
+	}
+	for range t.SensorHits - before.sensors {
+		crits = append(crits, "sensor hit")
+	}
+	if t.CockpitHit && !before.cockpit {
+		crits = append(crits, "cockpit hit")
+	}
+	for range t.PilotDamage - before.pilot {
+		crits = append(crits, "pilot hit")
+	}
+	for loc := 0; loc < NumLoc; loc++ {
+		if before.is[loc] > 0 && t.IS[loc] <= 0 {
+			crits = append(crits, locNames[loc]+" destroyed")
+		}
+	}
+	for i := range t.Weapons {
+		if i < len(before.weapons) && t.Weapons[i].Destroyed && !before.weapons[i] {
+			crits = append(crits, t.Weapons[i].Name+" destroyed")
+		}
+	}
+	f.Location = strings.Join(locs, ",")
+	f.Crit = strings.Join(crits, ", ")
+	return f
+}
+
+// ─── Analysis ───────────────────────────────────────────────────────────────
+
+// ReplayAnalysis breaks a replay down turn by turn: where each mech was hurt
+// and when, how hot it ran, how its weapons hit against the odds, what got
+// broken, and the turn the fight was decided.
+type ReplayAnalysis struct {
+	Attacker MechAnalysis `json:"attacker"`
+	Defender MechAnalysis `json:"defender"`
+	Winner   string       `json:"winner,omitempty"` // "attacker" or "defender"; empty if both or neither went down
+	Crits    []ReplayCrit `json:"crits"`
+
+	// DecisiveTurn is the biggest swing against the loser in its last run
+	// of turns behind on damage, 0 without a winner. Decisive says why.
+	DecisiveTurn int    `json:"decisiveTurn,omitempty"`
+	Decisive     string `json:"decisive,omitempty"`
+}
+
+// MechAnalysis is one side's timelines, a DamageTurn and a HeatTurn per
+// turn of the replay.
+type MechAnalysis struct {
+	Name          string        `json:"name"`
+	Damage        []DamageTurn  `json:"damage"`
+	Heat          []HeatTurn    `json:"heat"`
+	Weapons       []WeaponStats `json:"weapons"` // most damage first
+	Shots         int           `json:"shots"`
+	Hits          int           `json:"hits"`
+	ExpectedHits  float64       `json:"expectedHits"`            // summed over the shots' target numbers
+	WeaponDamage  int           `json:"weaponDamage"`            // dealt by weapon fire
+	FirstOverheat int           `json:"firstOverheat,omitempty"` // first turn ending hot enough to risk shutdown
+}
+
+// DamageTurn is the damage a mech has taken by the end of a turn, by
+// location (HD, CT, LT, RT, LA, RA, LL, RL, with rear armor in its torso).
+type DamageTurn struct {
+	Turn      int         `json:"turn"`
+	Armor     [NumLoc]int `json:"armor"`     // armor lost so far
+	Structure [NumLoc]int `json:"structure"` // internal structure lost so far
+	Total     int         `json:"total"`
+	Taken     int         `json:"taken"` // lost this turn
+	Share     float64     `json:"share"` // of the armor and structure it started with
+}
+
+// HeatTurn is a mech's heat at the end of a turn.
+type HeatTurn struct {
+	Turn       int  `json:"turn"`
+	Heat       int  `json:"heat"`
+	ShutdownTN int  `json:"shutdownTN,omitempty"` // to stay up at this heat, 13 when no roll can
+	Shutdown   bool `json:"shutdown,omitempty"`
+}
+
+// WeaponStats is how one weapon shot over the replay.
+type WeaponStats struct {
+	Weapon   string  `json:"weapon"`
+	Shots    int     `json:"shots"`
+	Hits     int     `json:"hits"`
+	Damage   int     `json:"damage"`
+	HitRate  float64 `json:"hitRate"`
+	Expected float64 `json:"expected"` // hit rate the target numbers give
+}
+
+// ReplayCrit is something a mech lost beyond armor and structure.
+type ReplayCrit struct {
+	Turn   int    `json:"turn"`
+	Mech   string `json:"mech"`             // "attacker" or "defender", the one hit
+	What   string `json:"what"`             // e.g. "engine hit", "LA destroyed", "Medium Laser destroyed", "shut down", "fell"
+	Weapon string `json:"weapon,omitempty"` // the shot that did it, if one did
+}
+
+// fireMessage matches a fire event, for replays from before weapon fire
+// was recorded.
+var fireMessage = regexp.MustCompile(`^(.+) \((?:indirect, )?TN (-?\d+)\): (?:MISS|(\d+) dmg)$`)
+
+// shots returns turn t's weapon fire, recovered from its events if it was
+// not recorded.
+func (t *ReplayTurn) shots() []ReplayWeaponFire {
+	if len(t.Weapons) > 0 {
+		return t.Weapons
+	}
+	var fires []ReplayWeaponFire
+	for _, e := range t.Events {
+		if e.Type != "fire" {
+			continue
+		}
+		m := fireMessage.FindStringSubmatch(e.Message)
+		if m == nil {
+			continue
+		}
+		tn, _ := strconv.Atoi(m[2])
+		dmg, _ := strconv.Atoi(m[3])
+		f := ReplayWeaponFire{Weapon: m[1], Actor: e.Actor, Target: 1, TN: tn, Hit: dmg > 0, Damage: dmg}
+		if e.Actor == "defender" {
+			f.Target = 0
+		}
+		fires = append(fires, f)
+	}
+	return fires
+}
+
+// AnalyzeReplay works out r's timelines. Damage is measured from the mechs
+// as deployed; a replay from before those were kept starts from its first
+// turn, with full structure.
+func AnalyzeReplay(r *ReplayData) ReplayAnalysis {
+	a := ReplayAnalysis{
+		Attacker: MechAnalysis{Name: r.AttackerName},
+		Defender: MechAnalysis{Name: r.DefenderName},
+		Crits:    []ReplayCrit{},
+	}
+	if len(r.Turns) == 0 {
+		return a
+	}
+	sides := [2]struct {
+		key   string
+		m     *MechAnalysis
+		start ReplayMechSnapshot
+		snap  func(*ReplayTurn) *ReplayMechSnapshot
+	}{
+		{"attacker", &a.Attacker, startOf(r.AttackerStart, r.Turns[0].Attacker), func(t *ReplayTurn) *ReplayMechSnapshot { return &t.Attacker }},
+		{"defender", &a.Defender, startOf(r.DefenderStart, r.Turns[0].Defender), func(t *ReplayTurn) *ReplayMechSnapshot { return &t.Defender }},
+	}
+
+	weapons := [2]map[string]*WeaponStats{{}, {}}
+	for i := range r.Turns {
+		t := &r.Turns[i]
+		shots := t.shots()
+
+		// What the shots broke, so the snapshots only add what they didn't
+		shotCrits := [2]map[string]int{{}, {}}
+		for _, f := range shots {
+			shooter := 0
+			if f.Actor == "defender" {
+				shooter = 1
+			}
+			ws := weapons[shooter][f.Weapon]
+			if ws == nil {
+				ws = &WeaponStats{Weapon: f.Weapon}
+				weapons[shooter][f.Weapon] = ws
+			}
+			p := hitProb(f.TN)
+			ws.Shots++
+			ws.Expected += p
+			m := sides[shooter].m
+			m.Shots++
+			m.ExpectedHits += p
+			if f.Hit {
+				ws.Hits++
+				ws.Damage += f.Damage
+				m.Hits++
+				m.WeaponDamage += f.Damage
+			}
+			if f.Crit == "" || f.Target < 0 || f.Target > 1 {
+				continue
+			}
+			for _, what := range strings.Split(f.Crit, ", ") {
+				shotCrits[f.Target][what]++
+				a.Crits = append(a.Crits, ReplayCrit{t.Turn, sides[f.Target].key, what, f.Weapon})
+			}
+		}
+
+		for s, side := range sides {
+			cur := side.snap(t)
+			prev := &side.start
+			if i > 0 {
+				prev = side.snap(&r.Turns[i-1])
+			}
+
+			d := DamageTurn{Turn: t.Turn}
+			startTotal := 0
+			for loc := 0; loc < NumLoc; loc++ {
+				d.Armor[loc] = side.start.Armor[loc] - cur.Armor[loc]
+				d.Structure[loc] = max(side.start.IS[loc], 0) - max(cur.IS[loc], 0)
+				startTotal += side.start.Armor[loc] + max(side.start.IS[loc], 0)
+			}
+			for j, loc := range rearLocs {
+				d.Armor[loc] += side.start.RearArmor[j] - cur.RearArmor[j]
+				startTotal += side.start.RearArmor[j]
+			}
+			for loc := 0; loc < NumLoc; loc++ {
+				d.Total += d.Armor[loc] + d.Structure[loc]
+			}
+			d.Taken = d.Total
+			if n := len(side.m.Damage); n > 0 {
+				d.Taken -= side.m.Damage[n-1].Total
+			}
+			if startTotal > 0 {
+				d.Share = float64(d.Total) / float64(startTotal)
+			}
+			side.m.Damage = append(side.m.Damage, d)
+
+			h := HeatTurn{Turn: t.Turn, Heat: cur.Heat, ShutdownTN: heatShutdownTN(cur.Heat), Shutdown: cur.Shutdown}
+			side.m.Heat = append(side.m.Heat, h)
+			if h.ShutdownTN > 0 && side.m.FirstOverheat == 0 {
+				side.m.FirstOverheat = t.Turn
+			}
+
+			// Whatever else it lost this turn, from physical attacks,
+			// falls, ammo explosions and the like
+			crit := func(what string, n int) {
+				for range n - shotCrits[s][what] {
+					a.Crits = append(a.Crits, ReplayCrit{Turn: t.Turn, Mech: side.key, What: what})
+				}
+			}
+			crit("engine hit", cur.EngineHits-prev.EngineHits)
+			crit("gyro hit", cur.GyroHits-prev.GyroHits)
+			crit("pilot hit", cur.PilotDmg-prev.PilotDmg)
+			for loc := 0; loc < NumLoc; loc++ {
+				if prev.IS[loc] > 0 && cur.IS[loc] <= 0 {
+					crit(locNames[loc]+" destroyed", 1)
+				}
+			}
+			if cur.Shutdown && !prev.Shutdown {
+				crit("shut down", 1)
+			}
+			if cur.Prone && !prev.Prone {
+				crit("fell", 1)
+			}
+			if cur.Destroyed && !prev.Destroyed {
+				crit("destroyed", 1)
+			} else if cur.ForcedWD && !prev.ForcedWD {
+				crit("forced withdrawal", 1)
+			}
+		}
+	}
+
+	for s, side := range sides {
+		side.m.Weapons = make([]WeaponStats, 0, len(weapons[s]))
+		for _, ws := range weapons[s] {
+			ws.HitRate = float64(ws.Hits) / float64(ws.Shots)
+			ws.Expected /= float64(ws.Shots)
+			side.m.Weapons = append(side.m.Weapons, *ws)
+		}
+		sort.Slice(side.m.Weapons, func(i, j int) bool {
+			wi, wj := &side.m.Weapons[i], &side.m.Weapons[j]
+			if wi.Damage != wj.Damage {
+				return wi.Damage > wj.Damage
+			}
+			return wi.Weapon < wj.Weapon
+		})
+	}
+
+	// The winner is the one still standing
+	last := &r.Turns[len(r.Turns)-1]
+	out := [2]bool{last.Attacker.Destroyed || last.Attacker.ForcedWD, last.Defender.Destroyed || last.Defender.ForcedWD}
+	if out[0] == out[1] {
+		return a
+	}
+	w, l := 0, 1
+	if out[0] {
+		w, l = 1, 0
+	}
+	a.Winner = sides[w].key
+
+	// The loser spends a run of turns, to the end, behind on damage or out
+	// of the fight; the decisive turn is the one in it that put the loser
+	// furthest further behind
+	winner, loser := sides[w].m, sides[l].m
+	margin := func(i int) float64 {
+		if i < 0 {
+			return 0
+		}
+		return loser.Damage[i].Share - winner.Damage[i].Share
+	}
+	behind := func(i int) bool {
+		s := sides[l].snap(&r.Turns[i])
+		return s.Destroyed || s.ForcedWD || margin(i) > 0
+	}
+	from := len(r.Turns) - 1
+	for from > 0 && behind(from-1) {
+		from--
+	}
+	i := from
+	for j := from + 1; j < len(r.Turns); j++ {
+		if margin(j)-margin(j-1) > margin(i)-margin(i-1) {
+			i = j
+		}
+	}
+	a.DecisiveTurn = r.Turns[i].Turn
+
+	var lost []string
+	seen := map[string]bool{}
+	for _, c := range a.Crits {
+		if c.Turn == a.DecisiveTurn && c.Mech == sides[l].key && !seen[c.What] {
+			seen[c.What] = true
+			lost = append(lost, c.What)
+		}
+	}
+	shares := fmt.Sprintf("%.0f%% lost to %.0f%%", loser.Damage[i].Share*100, winner.Damage[i].Share*100)
+	if len(lost) > 0 {
+		a.Decisive = loser.Name + ": " + strings.Join(lost, ", ") + "; " + shares
+	} else {
+		a.Decisive = fmt.Sprintf("%s took %d damage; %s", loser.Name, loser.Damage[i].Taken, shares)
+	}
+	return a
+}
+
+// startOf is a side's snapshot as deployed, or its first turn's with
+// full structure for a replay that didn't keep one.
+func startOf(start *ReplayMechSnapshot, first ReplayMechSnapshot) ReplayMechSnapshot {
+	if start != nil {
+		return *start
+	}
+	first.IS = first.MaxIS
+	first.EngineHits, first.GyroHits, first.PilotDmg = 0, 0, 0
+	first.Prone, first.Shutdown, first.Destroyed, first.ForcedWD = false, false, false, false
+	return first
+}
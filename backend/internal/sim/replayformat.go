@@ -0,0 +1,361 @@
+package sim
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JustinWhittecar/slic/internal/cbor"
+)
+
+// ─── Replay format ──────────────────────────────────────────────────────────
+//
+// Stored replays (variant_replays.replay_data) are gzipped. Version 1 was
+// the JSON of ReplayData, with both mechs' full snapshots every turn.
+// Version 2 is a CBOR sequence: a ReplayHeader with the board packed into
+// an elevation and a terrain index per hex, then one ReplayTurnDelta per
+// turn carrying only the snapshot fields that changed.
+// The same header and deltas are what the replay stream serves, so a
+// viewer can draw turn 1 before the rest arrives. A reader refuses a
+// version newer than ReplayVersion and skips fields it does not know.
+
+// ReplayVersion is the replay format this code writes.
+const ReplayVersion = 2
+
+// ReplayHeader opens a replay stream: everything but the turns.
+type ReplayHeader struct {
+	Version      int    `json:"version"`
+	AttackerName string `json:"attackerName"`
+	DefenderName string `json:"defenderName"`
+	BoardWidth   int    `json:"boardWidth"`
+	BoardHeight  int    `json:"boardHeight"`
+	Result       string `json:"result"`
+	Turns        int    `json:"turns"` // in the whole replay
+	From         int    `json:"from"`  // index of the first turn in the stream
+
+	// The mechs as deployed, so damage can be measured from the start
+	AttackerStart *ReplayMechSnapshot `json:"attackerStart,omitempty"`
+	DefenderStart *ReplayMechSnapshot `json:"defenderStart,omitempty"`
+
+	// The board, only when the stream starts at turn 0: column by column
+	// as Board.Grid keeps it, each hex's elevation and its index into
+	// Terrains, or as Hexes if it is not a full grid
+	Elevation []int       `json:"elevation,omitempty"`
+	Terrain   []int       `json:"terrain,omitempty"`
+	Terrains  []string    `json:"terrains,omitempty"`
+	Hexes     []ReplayHex `json:"hexes,omitempty"`
+}
+
+// packHexes fills in h's board from hexes.
+func (h *ReplayHeader) packHexes(hexes []ReplayHex) {
+	grid := len(hexes) == h.BoardWidth*h.BoardHeight
+	for i, hex := range hexes {
+		if !grid || hex.Col != i/h.BoardHeight+1 || hex.Row != i%h.BoardHeight+1 {
+			grid = false
+			break
+		}
+	}
+	if !grid {
+		h.Hexes = hexes
+		return
+	}
+	index := map[string]int{"": 0}
+	h.Terrains = []string{""}
+	h.Elevation = make([]int, len(hexes))
+	h.Terrain = make([]int, len(hexes))
+	for i, hex := range hexes {
+		k, ok := index[hex.Terrain]
+		if !ok {
+			k = len(h.Terrains)
+			index[hex.Terrain] = k
+			h.Terrains = append(h.Terrains, hex.Terrain)
+		}
+		h.Elevation[i], h.Terrain[i] = hex.Elevation, k
+	}
+}
+
+// hexes unpacks h's board.
+func (h *ReplayHeader) hexes() ([]ReplayHex, error) {
+	if h.Elevation == nil {
+		return h.Hexes, nil
+	}
+	if len(h.Elevation) != h.BoardWidth*h.BoardHeight || len(h.Terrain) != len(h.Elevation) {
+		return nil, fmt.Errorf("board of %d hexes is not %dx%d", len(h.Elevation), h.BoardWidth, h.BoardHeight)
+	}
+	hexes := make([]ReplayHex, len(h.Elevation))
+	for i := range hexes {
+		k := h.Terrain[i]
+		if k < 0 || k >= len(h.Terrains) {
+			return nil, fmt.Errorf("hex %d has terrain %d of %d", i, k, len(h.Terrains))
+		}
+		hexes[i] = ReplayHex{Col: i/h.BoardHeight + 1, Row: i%h.BoardHeight + 1, Elevation: h.Elevation[i], Terrain: h.Terrains[k]}
+	}
+	return hexes, nil
+}
+
+// ReplaySnapshotDelta holds the fields of a ReplayMechSnapshot that
+// changed since the previous turn of the stream; nil is unchanged.
+type ReplaySnapshotDelta struct {
+	Name       *string  `json:"name,omitempty"`
+	Col        *int     `json:"col,omitempty"`
+	Row        *int     `json:"row,omitempty"`
+	Facing     *int     `json:"facing,omitempty"`
+	Twist      *int     `json:"twist,omitempty"`
+	Heat       *int     `json:"heat,omitempty"`
+	Armor      *[8]int  `json:"armor,omitempty"`
+	RearArmor  *[3]int  `json:"rearArmor,omitempty"`
+	IS         *[8]int  `json:"is,omitempty"`
+	MaxIS      *[8]int  `json:"maxIS,omitempty"`
+	Prone      *bool    `json:"prone,omitempty"`
+	Shutdown   *bool    `json:"shutdown,omitempty"`
+	Destroyed  *bool    `json:"destroyed,omitempty"`
+	EngineHits *int     `json:"engineHits,omitempty"`
+	GyroHits   *int     `json:"gyroHits,omitempty"`
+	PilotDmg   *int     `json:"pilotDmg,omitempty"`
+	WalkMP     *int     `json:"walkMP,omitempty"`
+	RunMP      *int     `json:"runMP,omitempty"`
+	JumpMP     *int     `json:"jumpMP,omitempty"`
+	MoveMode   *string  `json:"moveMode,omitempty"`
+	HexesMoved *int     `json:"hexesMoved,omitempty"`
+	ForcedWD   *bool    `json:"forcedWithdrawal,omitempty"`
+	CRScore    *float64 `json:"crScore,omitempty"`
+}
+
+// ReplayTurnDelta is one turn of a replay stream.
+type ReplayTurnDelta struct {
+	Turn     int                  `json:"turn"`
+	Attacker *ReplaySnapshotDelta `json:"attacker,omitempty"`
+	Defender *ReplaySnapshotDelta `json:"defender,omitempty"`
+	Events   []ReplayEvent        `json:"events"`
+	Weapons  []ReplayWeaponFire   `json:"weapons,omitempty"`
+}
+
+// diff is cur if it differs from prev or full is set, else nil.
+func diff[T comparable](prev, cur T, full bool) *T {
+	if prev == cur && !full {
+		return nil
+	}
+	return &cur
+}
+
+// patch sets *dst to *d unless d is nil.
+func patch[T any](dst *T, d *T) {
+	if d != nil {
+		*dst = *d
+	}
+}
+
+// diffSnapshot is the delta from prev to cur, every field if prev is nil,
+// or nil if nothing changed.
+func diffSnapshot(prev *ReplayMechSnapshot, cur ReplayMechSnapshot) *ReplaySnapshotDelta {
+	var p ReplayMechSnapshot
+	full := prev == nil
+	if !full {
+		p = *prev
+	}
+	d := ReplaySnapshotDelta{
+		Name:       diff(p.Name, cur.Name, full),
+		Col:        diff(p.Col, cur.Col, full),
+		Row:        diff(p.Row, cur.Row, full),
+		Facing:     diff(p.Facing, cur.Facing, full),
+		Twist:      diff(p.Twist, cur.Twist, full),
+		Heat:       diff(p.Heat, cur.Heat, full),
+		Armor:      diff(p.Armor, cur.Armor, full),
+		RearArmor:  diff(p.RearArmor, cur.RearArmor, full),
+		IS:         diff(p.IS, cur.IS, full),
+		MaxIS:      diff(p.MaxIS, cur.MaxIS, full),
+		Prone:      diff(p.Prone, cur.Prone, full),
+		Shutdown:   diff(p.Shutdown, cur.Shutdown, full),
+		Destroyed:  diff(p.Destroyed, cur.Destroyed, full),
+		EngineHits: diff(p.EngineHits, cur.EngineHits, full),
+		GyroHits:   diff(p.GyroHits, cur.GyroHits, full),
+		PilotDmg:   diff(p.PilotDmg, cur.PilotDmg, full),
+		WalkMP:     diff(p.WalkMP, cur.WalkMP, full),
+		RunMP:      diff(p.RunMP, cur.RunMP, full),
+		JumpMP:     diff(p.JumpMP, cur.JumpMP, full),
+		MoveMode:   diff(p.MoveMode, cur.MoveMode, full),
+		HexesMoved: diff(p.HexesMoved, cur.HexesMoved, full),
+		ForcedWD:   diff(p.ForcedWD, cur.ForcedWD, full),
+		CRScore:    diff(p.CRScore, cur.CRScore, full),
+	}
+	if d == (ReplaySnapshotDelta{}) {
+		return nil
+	}
+	return &d
+}
+
+// apply updates s with the fields d changed.
+func (d *ReplaySnapshotDelta) apply(s *ReplayMechSnapshot) {
+	if d == nil {
+		return
+	}
+	patch(&s.Name, d.Name)
+	patch(&s.Col, d.Col)
+	patch(&s.Row, d.Row)
+	patch(&s.Facing, d.Facing)
+	patch(&s.Twist, d.Twist)
+	patch(&s.Heat, d.Heat)
+	patch(&s.Armor, d.Armor)
+	patch(&s.RearArmor, d.RearArmor)
+	patch(&s.IS, d.IS)
+	patch(&s.MaxIS, d.MaxIS)
+	patch(&s.Prone, d.Prone)
+	patch(&s.Shutdown, d.Shutdown)
+	patch(&s.Destroyed, d.Destroyed)
+	patch(&s.EngineHits, d.EngineHits)
+	patch(&s.GyroHits, d.GyroHits)
+	patch(&s.PilotDmg, d.PilotDmg)
+	patch(&s.WalkMP, d.WalkMP)
+	patch(&s.RunMP, d.RunMP)
+	patch(&s.JumpMP, d.JumpMP)
+	patch(&s.MoveMode, d.MoveMode)
+	patch(&s.HexesMoved, d.HexesMoved)
+	patch(&s.ForcedWD, d.ForcedWD)
+	patch(&s.CRScore, d.CRScore)
+}
+
+// Header is the header of a stream of r's turns from index from, with the
+// board only when from is 0.
+func (r *ReplayData) Header(from int) ReplayHeader {
+	h := ReplayHeader{
+		Version:      ReplayVersion,
+		AttackerName: r.AttackerName,
+		DefenderName: r.DefenderName,
+		BoardWidth:   r.BoardWidth,
+		BoardHeight:  r.BoardHeight,
+		Result:       r.Result,
+		Turns:        len(r.Turns),
+		From:         from,
+
+		AttackerStart: r.AttackerStart,
+		DefenderStart: r.DefenderStart,
+	}
+	if from == 0 {
+		h.packHexes(r.Hexes)
+	}
+	return h
+}
+
+// Deltas encodes turns [from, to) of r. The first carries full snapshots,
+// so a stream can start at any turn.
+func (r *ReplayData) Deltas(from, to int) []ReplayTurnDelta {
+	deltas := make([]ReplayTurnDelta, 0, max(to-from, 0))
+	for i := from; i < to; i++ {
+		t := &r.Turns[i]
+		var prevA, prevD *ReplayMechSnapshot
+		if i > from {
+			prevA, prevD = &r.Turns[i-1].Attacker, &r.Turns[i-1].Defender
+		}
+		deltas = append(deltas, ReplayTurnDelta{
+			Turn:     t.Turn,
+			Attacker: diffSnapshot(prevA, t.Attacker),
+			Defender: diffSnapshot(prevD, t.Defender),
+			Events:   t.Events,
+			Weapons:  t.Weapons,
+		})
+	}
+	return deltas
+}
+
+// applyDelta appends the turn d describes to r.
+func (r *ReplayData) applyDelta(d ReplayTurnDelta) {
+	t := ReplayTurn{Turn: d.Turn, Events: d.Events, Weapons: d.Weapons}
+	if n := len(r.Turns); n > 0 {
+		t.Attacker, t.Defender = r.Turns[n-1].Attacker, r.Turns[n-1].Defender
+	}
+	d.Attacker.apply(&t.Attacker)
+	d.Defender.apply(&t.Defender)
+	r.Turns = append(r.Turns, t)
+}
+
+// EncodeReplay returns r in the current stored format.
+func EncodeReplay(r *ReplayData) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := cbor.NewEncoder(gz)
+	if err := enc.Encode(r.Header(0)); err != nil {
+		return nil, err
+	}
+	for _, d := range r.Deltas(0, len(r.Turns)) {
+		if err := enc.Encode(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeReplay reads a stored replay of any version up to ReplayVersion.
+func DecodeReplay(data []byte) (*ReplayData, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	br := bufio.NewReader(gz)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Version 1: a JSON object
+	if first[0] == '{' {
+		var r ReplayData
+		if err := json.NewDecoder(br).Decode(&r); err != nil {
+			return nil, err
+		}
+		r.Version = max(r.Version, 1)
+		return &r, nil
+	}
+
+	dec := cbor.NewDecoder(br)
+	var h ReplayHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("replay header: %w", err)
+	}
+	if h.Version < 2 || h.Version > ReplayVersion {
+		return nil, fmt.Errorf("replay version %d not supported (have 1 to %d)", h.Version, ReplayVersion)
+	}
+	// Checked before it sizes the turn slice; no duel runs past MaxTurns
+	if h.Turns < 0 || h.Turns > MaxTurns {
+		return nil, fmt.Errorf("replay header claims %d turns (have 0 to %d)", h.Turns, MaxTurns)
+	}
+	hexes, err := h.hexes()
+	if err != nil {
+		return nil, err
+	}
+	r := &ReplayData{
+		Version:      h.Version,
+		AttackerName: h.AttackerName,
+		DefenderName: h.DefenderName,
+		BoardWidth:   h.BoardWidth,
+		BoardHeight:  h.BoardHeight,
+		Hexes:        hexes,
+		Turns:        make([]ReplayTurn, 0, h.Turns),
+		Result:       h.Result,
+
+		AttackerStart: h.AttackerStart,
+		DefenderStart: h.DefenderStart,
+	}
+	for {
+		var d ReplayTurnDelta
+		err := dec.Decode(&d)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay turn %d: %w", len(r.Turns)+1, err)
+		}
+		r.applyDelta(d)
+	}
+	if len(r.Turns) != h.Turns {
+		return nil, fmt.Errorf("replay has %d of %d turns", len(r.Turns), h.Turns)
+	}
+	return r, nil
+}
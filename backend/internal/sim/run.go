@@ -0,0 +1,72 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+)
+
+// Config describes a batch of one-sided duels: Attacker tries to destroy
+// Defender on random pairs of boards drawn from Boards.
+type Config struct {
+	Boards     []*Board
+	Attacker   *MechState
+	Defender   *MechState
+	Seed       uint64
+	N          int // sims per board pair, defaults to NumSimsPerBoard
+	BoardPairs int // defaults to NumBoardPairs
+	Conditions Conditions
+}
+
+// Result is the outcome of a Run.
+type Result struct {
+	Turns       []int // turns to destroy the defender per sim, sorted ascending
+	MedianTurns float64
+	MeanTurns   float64
+	Timeouts    int // sims that reached MaxTurns
+}
+
+// Run executes the batch described by cfg. The same Seed always yields the
+// same Result. It stops between board pairs if ctx is cancelled.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if len(cfg.Boards) == 0 {
+		return nil, errors.New("sim: no boards")
+	}
+	if cfg.Attacker == nil || cfg.Defender == nil {
+		return nil, errors.New("sim: attacker and defender are required")
+	}
+	n := cfg.N
+	if n <= 0 {
+		n = NumSimsPerBoard
+	}
+	pairs := cfg.BoardPairs
+	if pairs <= 0 {
+		pairs = NumBoardPairs
+	}
+
+	rng := rand.New(rand.NewPCG(cfg.Seed, 0))
+	res := &Result{Turns: make([]int, 0, n*pairs)}
+	total := 0
+	for bp := 0; bp < pairs; bp++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b1 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+		b2 := cfg.Boards[rng.IntN(len(cfg.Boards))]
+		combined := CombineBoards(b1, b2).WithConditions(cfg.Conditions)
+		for s := 0; s < n; s++ {
+			turns := SimulateCombat2D(combined, cfg.Attacker, cfg.Defender, rng)
+			if turns >= MaxTurns {
+				res.Timeouts++
+			}
+			total += turns
+			res.Turns = append(res.Turns, turns)
+		}
+	}
+
+	sort.Ints(res.Turns)
+	res.MedianTurns = medianTurns(res.Turns)
+	res.MeanTurns = float64(total) / float64(len(res.Turns))
+	return res, nil
+}
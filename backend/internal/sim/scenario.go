@@ -0,0 +1,436 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// ─── Scenarios ──────────────────────────────────────────────────────────────
+//
+// A plain battle deploys the forces on opposite edges and fights until one
+// breaks. A scenario changes where they deploy, how long the game lasts and
+// what wins it, after the usual tournament missions: hold the centre, break
+// through the enemy line, scout its half, or get a convoy across. Force A is
+// the side with the objective and deploys north; force B holds the south
+// half against it. Breakthroughs are played across, A from the west edge to
+// the east, since the long way over a pair of boards is the one that takes
+// some crossing. A force that breaks still loses whatever the objective.
+
+// Objective is what wins a scenario.
+type Objective int
+
+const (
+	ObjDestroy      Objective = iota // break the enemy force
+	ObjHold                          // hold the centre hex, a point per turn held alone
+	ObjBreakthrough                  // A gets half its force off B's edge
+	ObjRecon                         // A scans every recon point in B's half
+	ObjEscort                        // A gets its first unit off B's edge
+)
+
+// Deployment is where a force sets up.
+type Deployment int
+
+const (
+	DeployEdge   Deployment = iota // spread along its home edge
+	DeployCorner                   // bunched in a home corner
+	DeployHidden                   // in cover in its home half, unseen until it fires or is found
+)
+
+// Scenario is a mission a battle is played as.
+type Scenario struct {
+	Name      string
+	Objective Objective
+	Deploy    [2]Deployment // by force, A then B
+	Turns     int           // turn limit; 0 is MaxTurns
+	Across    bool          // A west and B east, rather than north and south
+}
+
+// Scenarios are the built-in missions.
+var Scenarios = map[string]*Scenario{
+	"destruction":  {Name: "Destruction", Objective: ObjDestroy},
+	"hold":         {Name: "Hold the Line", Objective: ObjHold, Turns: 10},
+	"breakthrough": {Name: "Breakthrough", Objective: ObjBreakthrough, Turns: 12, Across: true},
+	"ambush":       {Name: "Ambush", Objective: ObjBreakthrough, Deploy: [2]Deployment{DeployEdge, DeployHidden}, Turns: 12, Across: true},
+	"recon":        {Name: "Recon in Force", Objective: ObjRecon, Deploy: [2]Deployment{DeployCorner, DeployHidden}, Turns: 10},
+	"escort":       {Name: "Escort", Objective: ObjEscort, Deploy: [2]Deployment{DeployEdge, DeployCorner}, Turns: 12, Across: true},
+}
+
+// ScenarioKeys lists the built-in scenarios in sorted order.
+func ScenarioKeys() []string {
+	keys := make([]string, 0, len(Scenarios))
+	for k := range Scenarios {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseScenario looks up a built-in scenario; "" is a plain battle (nil).
+func ParseScenario(key string) (*Scenario, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return nil, nil
+	}
+	sc, ok := Scenarios[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q (have %s)", key, strings.Join(ScenarioKeys(), ", "))
+	}
+	return sc, nil
+}
+
+// scenarioState is a scenario in play. Its methods treat a nil state as a
+// plain battle.
+type scenarioState struct {
+	sc     *Scenario
+	board  *Board
+	center HexCoord
+	recon  []HexCoord // points not yet scanned
+	points [2]int
+	convoy *battleUnit
+}
+
+// score is the objective points each force has scored.
+func (st *scenarioState) score() [2]int {
+	if st == nil {
+		return [2]int{}
+	}
+	return st.points
+}
+
+func newScenarioState(board *Board, sc *Scenario) *scenarioState {
+	if sc == nil {
+		return nil
+	}
+	st := &scenarioState{sc: sc, board: board, center: HexCoord{Col: board.Width/2 + 1, Row: board.Height/2 + 1}}
+	if sc.Objective == ObjRecon {
+		row := board.Height - 3
+		st.recon = []HexCoord{{Col: board.Width / 4, Row: row}, {Col: board.Width/2 + 1, Row: row + 1}, {Col: 3 * board.Width / 4, Row: row}}
+	}
+	return st
+}
+
+// turns is the turn limit.
+func (st *scenarioState) turns() int {
+	if st == nil || st.sc.Turns == 0 {
+		return MaxTurns
+	}
+	return st.sc.Turns
+}
+
+// deploy sets both forces up on board, A on the north edge and B on the
+// south edge unless the scenario says otherwise.
+func (st *scenarioState) deploy(board *Board, units [2][]*battleUnit, rng *rand.Rand) {
+	var deploy [2]Deployment
+	across := false
+	if st != nil {
+		deploy, across = st.sc.Deploy, st.sc.Across
+	}
+	for s := 0; s < 2; s++ {
+		switch deploy[s] {
+		case DeployCorner:
+			deployCorner(board, units[s], s)
+		case DeployHidden:
+			deployHidden(board, units[s], s, across, rng)
+		case DeployEdge:
+			switch {
+			case across:
+				deployColumn(board, units[s], []int{2, board.Width - 1}[s], []int{2, 5}[s])
+			case s == 0:
+				deployForce(board, units[s], 2, 3)
+			default:
+				deployForce(board, units[s], board.Height-1, 0)
+			}
+		}
+	}
+	if st == nil {
+		return
+	}
+	if st.sc.Objective == ObjEscort && len(units[0]) > 0 {
+		st.convoy = units[0][0]
+		st.convoy.priority = true
+	}
+}
+
+// deployColumn spreads a force evenly down one column.
+func deployColumn(board *Board, units []*battleUnit, col, facing int) {
+	for i, u := range units {
+		u.m.Pos = HexCoord{Col: col, Row: 1 + (i+1)*(board.Height-1)/(len(units)+1)}
+		u.m.Facing = facing
+	}
+}
+
+// deployCorner packs a force into its home corner: A the north-west, B the
+// south-east.
+func deployCorner(board *Board, units []*battleUnit, side int) {
+	for i, u := range units {
+		col, row := 2+i/3, 1+i%3
+		u.m.Facing = 3
+		if side == 1 {
+			col, row = board.Width-1-i/3, board.Height-i%3
+			u.m.Facing = 0
+		}
+		u.m.Pos = HexCoord{Col: col, Row: row}
+	}
+}
+
+// deployHidden hides a force in its home half, in the best cover there:
+// buildings, then heavy woods, then light woods, then open ground.
+func deployHidden(board *Board, units []*battleUnit, side int, across bool, rng *rand.Rand) {
+	cols, rows := [2]int{1, board.Width}, [2]int{1, board.Height / 2}
+	facing := 3
+	if side == 1 {
+		rows = [2]int{board.Height/2 + 2, board.Height}
+		facing = 0
+	}
+	if across {
+		cols, rows = [2]int{1, board.Width / 2}, [2]int{1, board.Height}
+		facing = 2
+		if side == 1 {
+			cols = [2]int{board.Width/2 + 2, board.Width}
+			facing = 5
+		}
+	}
+	type spot struct {
+		c     HexCoord
+		cover int
+		tie   float64
+	}
+	var spots []spot
+	for col := cols[0]; col <= cols[1]; col++ {
+		for row := rows[0]; row <= rows[1]; row++ {
+			hex := board.Get(HexCoord{Col: col, Row: row})
+			if hex == nil || hex.WaterDepth() > 1 {
+				continue
+			}
+			cover := 0
+			if ok, lvl := hex.HasTerrain(TerrainWoods); ok {
+				cover = lvl
+			}
+			if ok, _ := hex.HasTerrain(TerrainBuilding); ok {
+				cover = 3
+			}
+			spots = append(spots, spot{hex.Coord, cover, rng.Float64()})
+		}
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		if spots[i].cover != spots[j].cover {
+			return spots[i].cover > spots[j].cover
+		}
+		return spots[i].tie < spots[j].tie
+	})
+	for i, u := range units {
+		u.m.Pos = spots[i%len(spots)].c
+		u.m.Facing = facing
+		u.hidden = true
+	}
+}
+
+// goal is how far u has to go to its objective, or nil if it has none and
+// should fight. A unit with no enemy in sight goes looking for one.
+func (st *scenarioState) goal(u *battleUnit, enemySeen bool) func(HexCoord) int {
+	if st == nil {
+		return nil
+	}
+	b := st.board
+	exit := func(c HexCoord) int { return b.Height - c.Row }
+	if st.sc.Across {
+		exit = func(c HexCoord) int { return b.Width - c.Col }
+	}
+	near := func(p HexCoord) func(HexCoord) int {
+		return func(c HexCoord) int { return max(HexDistance(c, p)-1, 0) }
+	}
+	switch {
+	case st.sc.Objective == ObjHold:
+		return near(st.center)
+	case u.side == 0 && st.sc.Objective == ObjBreakthrough:
+		return exit
+	case u.side == 0 && st.sc.Objective == ObjEscort && u == st.convoy:
+		return exit
+	case u.side == 0 && st.sc.Objective == ObjRecon && len(st.recon) > 0:
+		best := st.recon[0]
+		for _, p := range st.recon[1:] {
+			if HexDistance(u.m.Pos, p) < HexDistance(u.m.Pos, best) {
+				best = p
+			}
+		}
+		return near(best)
+	case !enemySeen:
+		// Search the enemy's home half
+		search := [2]HexCoord{{Col: b.Width/2 + 1, Row: b.Height - 3}, {Col: b.Width/2 + 1, Row: 4}}
+		if st.sc.Across {
+			search = [2]HexCoord{{Col: b.Width - 4, Row: b.Height/2 + 1}, {Col: 5, Row: b.Height/2 + 1}}
+		}
+		return near(search[u.side])
+	}
+	return nil
+}
+
+// afterMove scores the movement phase: units off B's edge, recon
+// points scanned and hidden units found by an adjacent enemy.
+func (st *scenarioState) afterMove(units [2][]*battleUnit) {
+	if st == nil {
+		return
+	}
+	for _, u := range units[0] {
+		if u.out || (!st.sc.Across && u.m.Pos.Row < st.board.Height) || (st.sc.Across && u.m.Pos.Col < st.board.Width) {
+			continue
+		}
+		if st.sc.Objective == ObjBreakthrough || (st.sc.Objective == ObjEscort && u == st.convoy) {
+			u.out, u.exited = true, true
+			st.points[0]++
+		}
+	}
+	if st.sc.Objective == ObjRecon {
+		left := st.recon[:0]
+		for _, p := range st.recon {
+			scanned := false
+			for _, u := range units[0] {
+				if !u.out && HexDistance(u.m.Pos, p) <= 1 {
+					scanned = true
+				}
+			}
+			if scanned {
+				st.points[0]++
+			} else {
+				left = append(left, p)
+			}
+		}
+		st.recon = left
+	}
+	for s := 0; s < 2; s++ {
+		for _, u := range units[s] {
+			if !u.hidden {
+				continue
+			}
+			for _, e := range units[1-s] {
+				if !e.out && HexDistance(u.m.Pos, e.m.Pos) <= 1 {
+					u.hidden = false
+				}
+			}
+		}
+	}
+}
+
+// endTurn scores the end of a turn: a point for holding the centre alone.
+func (st *scenarioState) endTurn(units [2][]*battleUnit) {
+	if st == nil || st.sc.Objective != ObjHold {
+		return
+	}
+	var holds [2]bool
+	for s := 0; s < 2; s++ {
+		for _, u := range units[s] {
+			if !u.out && HexDistance(u.m.Pos, st.center) <= 1 {
+				holds[s] = true
+			}
+		}
+	}
+	if holds[0] != holds[1] {
+		if holds[0] {
+			st.points[0]++
+		} else {
+			st.points[1]++
+		}
+	}
+}
+
+// over checks whether the battle has been decided at the start of a turn:
+// a force has broken or A has met its objective.
+func (st *scenarioState) over(units [2][]*battleUnit, start [2]int, breakFrac float64) (int, bool) {
+	if w, done := battleOver(units, start, breakFrac); done || st == nil {
+		return w, done
+	}
+	switch st.sc.Objective {
+	case ObjBreakthrough:
+		exited := 0
+		for _, u := range units[0] {
+			if u.exited {
+				exited += u.weight
+			}
+		}
+		if 2*exited >= start[0] {
+			return 1, true
+		}
+	case ObjRecon:
+		if len(st.recon) == 0 {
+			return 1, true
+		}
+	case ObjEscort:
+		if st.convoy != nil {
+			switch {
+			case st.convoy.exited:
+				return 1, true
+			case st.convoy.out:
+				return -1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// final decides a battle that ran out of turns.
+func (st *scenarioState) final(units [2][]*battleUnit, start [2]int, breakFrac float64) int {
+	if w, done := battleOver(units, start, breakFrac); done || st == nil {
+		return w
+	}
+	switch st.sc.Objective {
+	case ObjHold:
+		switch {
+		case st.points[0] > st.points[1]:
+			return 1
+		case st.points[1] > st.points[0]:
+			return -1
+		}
+	case ObjBreakthrough, ObjRecon, ObjEscort:
+		return -1 // the defence held
+	}
+	return 0
+}
+
+// toward keeps the options that get furthest towards a goal.
+func toward(opts []ReachableHex, dist func(HexCoord) int) []ReachableHex {
+	best := 1 << 30
+	for _, o := range opts {
+		best = min(best, dist(o.Coord))
+	}
+	var keep []ReachableHex
+	for _, o := range opts {
+		if dist(o.Coord) == best {
+			keep = append(keep, o)
+		}
+	}
+	return keep
+}
+
+// ─── Scenario ratings ───────────────────────────────────────────────────────
+
+// ScenarioRating rates m on sc against a baseline panel: it plays n battles
+// as force A and n as force B against each baseline, and scores its share
+// of the points (a win is 1, a draw 1/2) times 10, so an even record is the
+// baseline's 5.0. A mech that is itself a baseline scores 5.0 against it.
+// The result is the panel's weighted mean.
+func ScenarioRating(boards []*Board, m *MechState, selfID int, sc *Scenario, panel []Baseline, n int, rng *rand.Rand) float64 {
+	scores := make([]float64, len(panel))
+	for i, b := range panel {
+		if selfID != 0 && b.ID == selfID {
+			scores[i] = 5.0
+			continue
+		}
+		me := Force{Units: []ForceUnit{{Mech: m}}}
+		them := Force{Units: []ForceUnit{{Mech: b.Mech}}}
+		won := 0.0
+		for k := 0; k < 2*n; k++ {
+			b1 := boards[rng.IntN(len(boards))]
+			b2 := boards[rng.IntN(len(boards))]
+			board := CombineBoards(b1, b2)
+			if k%2 == 0 {
+				won += float64(1+SimulateScenario(board, me, them, DefaultBreakFraction, sc, rng).Winner) / 2
+			} else {
+				won += float64(1-SimulateScenario(board, them, me, DefaultBreakFraction, sc, rng).Winner) / 2
+			}
+		}
+		scores[i] = 10 * won / float64(2*n)
+	}
+	return WeightedMean(scores, panel)
+}
@@ -0,0 +1,602 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// ─── Constants ──────────────────────────────────────────────────────────────
+
+const (
+	NumSimsPerBoard = 10
+	NumBoardPairs   = 20
+	MaxTurns        = 200
+	DefaultGunnery  = 4
+	DefaultPiloting = 5
+)
+
+// ─── Simulation core ────────────────────────────────────────────────────────
+
+// SimulateCombat2D runs one sim on a 2D hex board.
+// Attacker tries to destroy defender. Returns turns until defender destroyed/withdrawn.
+func SimulateCombat2D(board *Board, attackerTemplate, defenderTemplate *MechState, rng *rand.Rand) int {
+	board = board.forSim()
+	attacker := cloneMech(attackerTemplate)
+	defender := cloneMech(defenderTemplate)
+
+	// Deploy: attacker rows 1-3, defender rows 15-17
+	attacker.Pos = HexCoord{Col: board.Width/2 + 1, Row: 2}
+	attacker.Facing = 3 // face south
+	defender.Pos = HexCoord{Col: board.Width/2 + 1, Row: board.Height - 1}
+	defender.Facing = 0 // face north
+
+	for turn := 1; turn <= MaxTurns; turn++ {
+		if attacker.isDestroyed() || defender.isDestroyed() {
+			return turn - 1
+		}
+		if defender.isForcedWithdrawal() {
+			return turn
+		}
+		// Combat ineffective: gyro destroyed (permanently prone, 0 MP),
+		// or both legs destroyed (immobile + prone), or all weapons destroyed.
+		// Not "destroyed" per BMM but effectively out of the fight for CR purposes.
+		if defender.GyroHits >= 2 || (defender.IS[LocLL] <= 0 && defender.IS[LocRL] <= 0) {
+			return turn
+		}
+		defAllWeaponsGone := true
+		for i := range defender.Weapons {
+			if !defender.Weapons[i].Destroyed {
+				defAllWeaponsGone = false
+				break
+			}
+		}
+		if defAllWeaponsGone {
+			return turn
+		}
+
+		// Unjam RAC weapons (RAC clears jam after 1 turn)
+		for i := range attacker.Weapons {
+			w := &attacker.Weapons[i]
+			if w.Jammed && w.Category == catRotaryAC {
+				w.Jammed = false
+			}
+		}
+		for i := range defender.Weapons {
+			w := &defender.Weapons[i]
+			if w.Jammed && w.Category == catRotaryAC {
+				w.Jammed = false
+			}
+		}
+
+		// Handle shutdown — attacker
+		if attacker.IsShutdown {
+			// Restart requires a roll against shutdown avoidance TN (BMM p.52)
+			tn := heatShutdownTN(attacker.Heat)
+			if tn >= 13 || roll2d6(rng) < tn {
+				// Failed restart — stay shutdown, dissipate heat, skip turn
+				attacker.Heat += attacker.HeatPenalty
+				attacker.HeatPenalty = 0
+				attacker.Heat -= attacker.dissipation(board.Conditions)
+				if attacker.Heat < 0 {
+					attacker.Heat = 0
+				}
+				// Still process defender heat
+				defender.Heat += defender.HeatPenalty
+				defender.HeatPenalty = 0
+				defender.Heat -= defender.dissipation(board.Conditions)
+				if defender.Heat < 0 {
+					defender.Heat = 0
+				}
+				continue
+			}
+			// Successful restart
+			attacker.IsShutdown = false
+			// Involuntary shutdown PSR: piloting + 3 modifier (BMM p.52)
+			if !attacker.Prone {
+				psrTN := attacker.Piloting + 3 + attacker.psrMod()
+				if roll2d6(rng) < psrTN {
+					attacker.applyFall(rng)
+				}
+			}
+			if attacker.isDestroyed() {
+				return MaxTurns // attacker died
+			}
+		}
+
+		// Handle shutdown — defender
+		if defender.IsShutdown {
+			tn := heatShutdownTN(defender.Heat)
+			if tn >= 13 || roll2d6(rng) < tn {
+				// Failed restart — stay shutdown, dissipate heat, skip turn for defender
+				defender.Heat += defender.HeatPenalty
+				defender.HeatPenalty = 0
+				defender.Heat -= defender.dissipation(board.Conditions)
+				if defender.Heat < 0 {
+					defender.Heat = 0
+				}
+				attacker.Heat += attacker.HeatPenalty
+				attacker.HeatPenalty = 0
+				attacker.Heat -= attacker.dissipation(board.Conditions)
+				if attacker.Heat < 0 {
+					attacker.Heat = 0
+				}
+				continue
+			}
+			defender.IsShutdown = false
+			if !defender.Prone {
+				psrTN := defender.Piloting + 3 + defender.psrMod()
+				if roll2d6(rng) < psrTN {
+					defender.applyFall(rng)
+				}
+			}
+			if defender.isDestroyed() {
+				return turn
+			}
+		}
+
+		// Stand from prone
+		if attacker.Prone {
+			attacker.Heat += 1
+			if attacker.rollPSRForStanding(rng) {
+				attacker.Prone = false
+			} else {
+				attacker.applyFall(rng)
+				if attacker.isDestroyed() {
+					return MaxTurns
+				}
+			}
+		}
+		if defender.Prone {
+			defender.Heat += 1
+			if defender.rollPSRForStanding(rng) {
+				defender.Prone = false
+			} else {
+				defender.applyFall(rng)
+				if defender.isDestroyed() {
+					return turn
+				}
+			}
+		}
+
+		// Initiative
+		atkInit := rng.IntN(6) + 1 + initiativeBonus(attacker)
+		defInit := rng.IntN(6) + 1 + initiativeBonus(defender)
+		atkMovesFirst := atkInit < defInit
+		if atkInit == defInit {
+			atkMovesFirst = rng.IntN(2) == 0
+		}
+
+		// Movement
+		atkWalk := 0
+		atkRun := 0
+		if !attacker.Prone {
+			atkWalk = attacker.effectiveWalkMP()
+			atkRun = attacker.effectiveRunMP()
+		}
+		defWalk := 0
+		defRun := 0
+		if !defender.Prone {
+			defWalk = defender.effectiveWalkMP()
+			defRun = defender.effectiveRunMP()
+		}
+
+		// Convert MechState to MechState2-like for tactics
+		atkM2 := &MechState2{
+			Pos: attacker.Pos, Facing: attacker.Facing,
+			WalkMP: atkWalk, RunMP: atkRun, JumpMP: attacker.JumpMP,
+			Tonnage: attacker.Tonnage, GunnerySkill: attacker.Gunnery, PilotingSkill: attacker.Piloting, SPAs: attacker.SPAs, Quirks: attacker.Quirks,
+			Heat: attacker.Heat, OptimalRange: attacker.OptimalRange,
+		}
+		defM2 := &MechState2{
+			Pos: defender.Pos, Facing: defender.Facing,
+			WalkMP: defWalk, RunMP: defRun, JumpMP: defender.JumpMP,
+			Tonnage: defender.Tonnage, GunnerySkill: defender.Gunnery, PilotingSkill: defender.Piloting, SPAs: defender.SPAs, Quirks: defender.Quirks,
+			Heat: defender.Heat, OptimalRange: defender.OptimalRange,
+		}
+		if atkWalk > 0 {
+			atkM2.BoostRunMP = attacker.boostRunMP()
+		}
+		if defWalk > 0 {
+			defM2.BoostRunMP = defender.boostRunMP()
+		}
+		// Copy weapons for damage estimation
+		for _, w := range attacker.Weapons {
+			if !w.Destroyed && !w.Jammed {
+				atkM2.Weapons = append(atkM2.Weapons, SimWeapon2{
+					Name: w.Name, Damage: w.Damage, Heat: w.Heat,
+					MinRange: w.MinRange, ShortRange: w.ShortRange,
+					MedRange: w.MedRange, LongRange: w.LongRange,
+					Location: w.Location, ToHitMod: w.ToHitMod,
+				})
+			}
+		}
+		for _, w := range defender.Weapons {
+			if !w.Destroyed && !w.Jammed {
+				defM2.Weapons = append(defM2.Weapons, SimWeapon2{
+					Name: w.Name, Damage: w.Damage, Heat: w.Heat,
+					MinRange: w.MinRange, ShortRange: w.ShortRange,
+					MedRange: w.MedRange, LongRange: w.LongRange,
+					Location: w.Location, ToHitMod: w.ToHitMod,
+				})
+			}
+		}
+
+		defOptions := collectAllMoveOptions(board, defM2)
+		atkOptions := collectAllMoveOptions(board, atkM2)
+
+		var atkChoice, defChoice ReachableHex
+
+		if atkMovesFirst {
+			// Attacker moves first (blind), defender sees
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2,
+				false, defM2.Pos, defM2.Facing, defOptions, atkOptions, rng)
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2,
+				true, atkChoice.Coord, atkChoice.Facing, atkOptions, defOptions, rng)
+		} else {
+			// Defender moves first (blind), attacker sees
+			defChoice = defender.ai().ChooseMove(board, defM2, atkM2,
+				false, atkM2.Pos, atkM2.Facing, atkOptions, defOptions, rng)
+			atkChoice = attacker.ai().ChooseMove(board, atkM2, defM2,
+				true, defChoice.Coord, defChoice.Facing, defOptions, atkOptions, rng)
+		}
+
+		// Apply movement
+		attacker.Pos = atkChoice.Coord
+		attacker.Facing = atkChoice.Facing
+		attacker.LastMoveMode = atkChoice.Mode
+		attacker.LastHexMoved = atkChoice.HexesMoved
+		attacker.clearTurnAttacks()
+		attacker.Heat += atkChoice.MoveHeat
+		attacker.enterHex(board, rng)
+		attacker.boost(atkChoice.Boosted, rng)
+
+		defender.Pos = defChoice.Coord
+		defender.Facing = defChoice.Facing
+		defender.LastMoveMode = defChoice.Mode
+		defender.LastHexMoved = defChoice.HexesMoved
+		defender.clearTurnAttacks()
+		defender.Heat += defChoice.MoveHeat
+		defender.enterHex(board, rng)
+		defender.boost(defChoice.Boosted, rng)
+
+		// Torso twist
+		attacker.TorsoTwist = BestTorsoTwist(attacker.Pos, attacker.Facing, defender.Pos)
+		defender.TorsoTwist = BestTorsoTwist(defender.Pos, defender.Facing, attacker.Pos)
+
+		// Stealth and Null-Signature, now both have moved
+		attacker.ai().ChooseEW(attacker, board, []*MechState{defender})
+		defender.ai().ChooseEW(defender, board, []*MechState{attacker})
+
+		// Reset AMS
+		defender.AMSUsedThisTurn = false
+
+		// LOS check
+		dist := HexDistance(attacker.Pos, defender.Pos)
+		los := CheckLOS(board, attacker.Pos, defender.Pos)
+		attacker.electronics(board, defender, nil, nil)
+
+		if los.CanSee && dist > 0 {
+			// Determine arc for hit table
+			defEffFacing := ((defender.Facing + defender.TorsoTwist) % 6 + 6) % 6
+			arcToDefender := DetermineArc(defender.Pos, defEffFacing, attacker.Pos)
+			isRear := arcToDefender == ArcRear
+
+			// Compute target number
+			defTMM := tmmFromHexesMoved(defChoice.HexesMoved, defChoice.Mode)
+			heatThisMod := heatToHitMod(attacker.Heat, board.Conditions)
+			// BMM p.49: 2+ sensor hits = weapon fire impossible
+			if attacker.SensorHits >= 2 {
+				continue
+			}
+			baseTarget := attacker.Gunnery + attacker.SensorHits*2 + heatThisMod
+
+			// Attacker movement modifier
+			baseTarget += attackerMoveMod(atkChoice.Mode, attacker.SPAs)
+
+			// Target movement modifier
+			baseTarget += defTMM
+
+			// Terrain modifiers
+			baseTarget += los.WoodsMod
+			baseTarget += los.TargetCover
+			baseTarget += los.ElevationMod
+
+			// Prone modifiers
+			if attacker.Prone {
+				baseTarget += 2
+			}
+			if defender.Prone {
+				if dist <= 1 {
+					baseTarget -= 2 // adjacent: easier to hit (BMM p.28)
+				} else {
+					baseTarget += 1 // non-adjacent: harder to hit (BMM p.28)
+				}
+			}
+
+			// Select and fire weapons
+			var firingWeapons []int
+			weaponHeatTotal := 0
+			if !attacker.ai().ChoosePhysical(attacker, defender, dist, calcExpectedDamage(attacker, dist, baseTarget, 0)) {
+				// TAG first, so guided ammo can use the designation
+				if attacker.guided() && !defender.Tagged {
+					attacker.designate(defender, dist, baseTarget, rng)
+				}
+				firingWeapons, weaponHeatTotal = attacker.ai().ChooseFire(attacker, board, defender, dist, baseTarget)
+			}
+			attacker.Heat += weaponHeatTotal
+
+			totalDmgDealt := 0
+			for _, wi := range firingWeapons {
+				w := &attacker.Weapons[wi]
+				target := baseTarget + w.ToHitMod + attacker.ArmActuatorHit[w.Location] + attacker.attackMod(w, defender, dist)
+				rm := rangeModifier(w, dist)
+				if rm < 0 {
+					continue
+				}
+				target += rm
+				if w.MinRange > 0 && dist <= w.MinRange {
+					target += w.MinRange - dist + 1
+				}
+				if !attacker.load(w, defender, target) {
+					continue
+				}
+				target += attacker.munitionMod(w, w.Loaded, defender)
+				target = attacker.finalTN(w, defender, target)
+
+				dmg := resolveWeaponFire2D(w, target, isRear, attacker, defender, rng)
+				totalDmgDealt += dmg
+
+				if defender.isDestroyed() {
+					return turn
+				}
+			}
+
+			// PSR for 20+ damage
+			if totalDmgDealt >= 20 && !defender.Prone {
+				if !defender.rollPSR(1, rng) {
+					defender.applyFall(rng)
+					if defender.isDestroyed() {
+						return turn
+					}
+				}
+			}
+
+			// PSR from crits
+			if defender.NeedsPSRFromCrit && !defender.isDestroyed() {
+				defender.NeedsPSRFromCrit = false
+				if !defender.rollPSR(0, rng) {
+					defender.applyFall(rng)
+					if defender.isDestroyed() {
+						return turn
+					}
+				}
+			}
+		} else if dist > 0 {
+			// No LOS: Arrow IV can still fire as artillery
+			_, dmg, destroyed := fireIndirect(attacker, defender, board, atkChoice, defChoice, nil, "", nil, nil, rng)
+			if destroyed {
+				return turn
+			}
+			if dmg >= 20 && !defender.Prone {
+				if !defender.rollPSR(1, rng) {
+					defender.applyFall(rng)
+					if defender.isDestroyed() {
+						return turn
+					}
+				}
+			}
+		}
+
+		// Physical attacks
+		if dist == 1 {
+			resolvePhysical(attacker, defender, rng)
+			if defender.isDestroyed() {
+				return turn
+			}
+			// Charges and DFAs can cost the attacker its own 'Mech
+			if attacker.isDestroyed() {
+				return MaxTurns
+			}
+		}
+
+		// End of turn: engine crit heat + heat dissipation
+		// Engine hits do not produce heat if the 'Mech is shut down (BMM p.47)
+		if !attacker.IsShutdown {
+			attacker.Heat += attacker.EngineHits * 5
+		}
+		// Outside heat sources capped at 15 per turn (BMM p.52)
+		if attacker.HeatPenalty > 15 {
+			attacker.HeatPenalty = 15
+		}
+		attacker.Heat += attacker.HeatPenalty // plasma weapon heat from enemy
+		attacker.HeatPenalty = 0
+		attacker.Heat -= attacker.dissipation(board.Conditions)
+		if attacker.Heat < 0 {
+			attacker.Heat = 0
+		}
+		if !defender.IsShutdown {
+			defender.Heat += defender.EngineHits * 5
+		}
+		if defender.HeatPenalty > 15 {
+			defender.HeatPenalty = 15
+		}
+		defender.Heat += defender.HeatPenalty // plasma weapon heat from enemy
+		defender.HeatPenalty = 0
+		defender.Heat -= defender.dissipation(board.Conditions)
+		if defender.Heat < 0 {
+			defender.Heat = 0
+		}
+
+		// Heat shutdown/ammo explosion for attacker
+		shutdownP := heatShutdownProb(attacker.Heat)
+		if shutdownP >= 1.0 || (shutdownP > 0 && rng.Float64() < shutdownP) {
+			attacker.IsShutdown = true
+		}
+
+		ammoExpP := heatAmmoExpProb(attacker.Heat)
+		if ammoExpP > 0 && rng.Float64() < ammoExpP {
+			type ammoBin struct {
+				key string
+				loc int
+			}
+			var bins []ammoBin
+			for loc := 0; loc < NumLoc; loc++ {
+				for _, slot := range attacker.Slots[loc] {
+					sLower := strings.ToLower(slot)
+					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
+						key := parseAmmoSlotKey(slot)
+						if attacker.Ammo[key] > 0 {
+							bins = append(bins, ammoBin{key, loc})
+						}
+					}
+				}
+			}
+			if len(bins) > 0 {
+				bin := bins[rng.IntN(len(bins))]
+				attacker.ammoExplosion(bin.loc, bin.key, rng)
+			}
+		}
+
+		// Heat shutdown/ammo explosion for defender (BMM p.52)
+		shutdownPDef := heatShutdownProb(defender.Heat)
+		if shutdownPDef >= 1.0 || (shutdownPDef > 0 && rng.Float64() < shutdownPDef) {
+			defender.IsShutdown = true
+		}
+
+		ammoExpPDef := heatAmmoExpProb(defender.Heat)
+		if ammoExpPDef > 0 && rng.Float64() < ammoExpPDef {
+			type ammoBin struct {
+				key string
+				loc int
+			}
+			var bins []ammoBin
+			for loc := 0; loc < NumLoc; loc++ {
+				for _, slot := range defender.Slots[loc] {
+					sLower := strings.ToLower(slot)
+					if strings.Contains(sLower, "ammo") && !strings.Contains(sLower, "gauss") {
+						key := parseAmmoSlotKey(slot)
+						if defender.Ammo[key] > 0 {
+							bins = append(bins, ammoBin{key, loc})
+						}
+					}
+				}
+			}
+			if len(bins) > 0 {
+				bin := bins[rng.IntN(len(bins))]
+				defender.ammoExplosion(bin.loc, bin.key, rng)
+				if defender.isDestroyed() {
+					return turn
+				}
+			}
+		}
+	}
+
+	return MaxTurns
+}
+
+// (collectMoveOptions moved to tactics.go as collectAllMoveOptions)
+
+// ─── MechState2 — lightweight state for tactical AI ─────────────────────────
+
+type MechState2 struct {
+	Name          string
+	Pos           HexCoord
+	Facing        int
+	WalkMP        int
+	RunMP         int
+	JumpMP        int
+	Tonnage       int
+	GunnerySkill  int
+	PilotingSkill int
+	SPAs          []*SPA
+	Quirks        []*Quirk
+	Weapons       []SimWeapon2
+	Prone         bool
+	Heat          int
+	OptimalRange  int
+	BoostRunMP    int // running MP on MASC/supercharger, 0 if not ready
+}
+
+type SimWeapon2 struct {
+	Name       string
+	Damage     int
+	Heat       int
+	MinRange   int
+	ShortRange int
+	MedRange   int
+	LongRange  int
+	Location   int
+	ToHitMod   int
+	Destroyed  bool
+}
+
+// ─── Batch sim ──────────────────────────────────────────────────────────────
+
+// PrecomputedBoards holds pre-combined board pairs to avoid re-combining per variant.
+type PrecomputedBoards struct {
+	Boards []*Board
+}
+
+func PrecomputeBoardPairs(boards []*Board, nPairs int, rng *rand.Rand) *PrecomputedBoards {
+	pb := &PrecomputedBoards{Boards: make([]*Board, nPairs)}
+	for i := 0; i < nPairs; i++ {
+		b1 := boards[rng.IntN(len(boards))]
+		b2 := boards[rng.IntN(len(boards))]
+		pb.Boards[i] = CombineBoards(b1, b2)
+	}
+	return pb
+}
+
+// WithConditions returns the same board pairs played under c.
+func (pb *PrecomputedBoards) WithConditions(c Conditions) *PrecomputedBoards {
+	out := &PrecomputedBoards{Boards: make([]*Board, len(pb.Boards))}
+	for i, b := range pb.Boards {
+		out.Boards[i] = b.WithConditions(c)
+	}
+	return out
+}
+
+func RunSimsBatch2D(boards []*Board, attackerTemplate, defenderTemplate *MechState, nBoardPairs int, nSimsPerBoard int, rng *rand.Rand) float64 {
+	var results []int
+
+	for bp := 0; bp < nBoardPairs; bp++ {
+		b1 := boards[rng.IntN(len(boards))]
+		b2 := boards[rng.IntN(len(boards))]
+		combined := CombineBoards(b1, b2)
+
+		for s := 0; s < nSimsPerBoard; s++ {
+			turns := SimulateCombat2D(combined, attackerTemplate, defenderTemplate, rng)
+			results = append(results, turns)
+		}
+	}
+
+	sort.Ints(results)
+	return medianTurns(results)
+}
+
+func RunSimsBatch2DPre(preBoards *PrecomputedBoards, attackerTemplate, defenderTemplate *MechState, nSimsPerBoard int, rng *rand.Rand) float64 {
+	var results []int
+
+	for _, combined := range preBoards.Boards {
+		for s := 0; s < nSimsPerBoard; s++ {
+			turns := SimulateCombat2D(combined, attackerTemplate, defenderTemplate, rng)
+			results = append(results, turns)
+		}
+	}
+
+	sort.Ints(results)
+	return medianTurns(results)
+}
+
+// medianTurns returns the median of sorted turn counts, MaxTurns if empty.
+func medianTurns(sorted []int) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return float64(MaxTurns)
+	}
+	if n%2 == 0 {
+		return float64(sorted[n/2-1]+sorted[n/2]) / 2.0
+	}
+	return float64(sorted[n/2])
+}
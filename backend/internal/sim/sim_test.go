@@ -0,0 +1,1054 @@
+package sim
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JustinWhittecar/slic/internal/cbor"
+	"github.com/JustinWhittecar/slic/internal/ingestion"
+)
+
+func TestHexDistance(t *testing.T) {
+	tests := []struct {
+		a, b HexCoord
+		want int
+	}{
+		{HexCoord{1, 1}, HexCoord{1, 1}, 0},
+		{HexCoord{1, 1}, HexCoord{1, 5}, 4},
+		{HexCoord{1, 1}, HexCoord{2, 1}, 1},
+		{HexCoord{2, 1}, HexCoord{3, 2}, 1},
+		{HexCoord{1, 1}, HexCoord{5, 3}, 4},
+		{HexCoord{8, 2}, HexCoord{8, 33}, 31},
+	}
+	for _, tt := range tests {
+		if got := HexDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HexDistance(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRunDeterministic(t *testing.T) {
+	boards := []*Board{NewBoard(16, 17)}
+	hbk := BuildHBK4P()
+	cfg := Config{Boards: boards, Attacker: hbk, Defender: hbk, Seed: 7, N: 3, BoardPairs: 2}
+
+	r1, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r1.Turns) != 6 {
+		t.Fatalf("got %d sims, want 6", len(r1.Turns))
+	}
+	for i := range r1.Turns {
+		if r1.Turns[i] != r2.Turns[i] {
+			t.Fatalf("same seed gave different turns: %v vs %v", r1.Turns, r2.Turns)
+		}
+	}
+	if r1.MedianTurns <= 0 || r1.MedianTurns > MaxTurns {
+		t.Errorf("MedianTurns = %.1f, out of range", r1.MedianTurns)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hbk := BuildHBK4P()
+	_, err := Run(ctx, Config{Boards: []*Board{NewBoard(16, 17)}, Attacker: hbk, Defender: hbk})
+	if err == nil {
+		t.Fatal("expected error from cancelled context")
+	}
+}
+
+func TestDuelWinner(t *testing.T) {
+	tests := []struct {
+		result string
+		want   int
+	}{
+		{"defender_destroyed_turn_7", 1},
+		{"defender_forced_withdrawal", 1},
+		{"attacker_destroyed", -1},
+		{"attacker_forced_withdrawal", -1},
+		{"mutual_destruction", 0},
+		{"timeout_200", 0},
+	}
+	for _, tt := range tests {
+		if got := duelWinner(tt.result); got != tt.want {
+			t.Errorf("duelWinner(%q) = %d, want %d", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestRunBattles(t *testing.T) {
+	hbk := BuildHBK4P()
+	a := Force{Name: "A", Units: []ForceUnit{{hbk, 1000}, {hbk, 1000}}}
+	b := Force{Name: "B", Units: []ForceUnit{{hbk, 1000}}}
+	res, err := RunBattles(context.Background(), BattleConfig{
+		Boards: []*Board{NewBoard(16, 17)}, A: a, B: b, Seed: 3, N: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.A.Wins + res.B.Wins + res.Draws; got != 4 {
+		t.Errorf("wins+draws = %d, want 4", got)
+	}
+	if len(res.A.Units) != 2 || len(res.B.Units) != 1 {
+		t.Errorf("unit stats = %d/%d, want 2/1", len(res.A.Units), len(res.B.Units))
+	}
+	if res.A.WinRate <= res.B.WinRate {
+		t.Errorf("two Hunchbacks should beat one: A %.2f vs B %.2f", res.A.WinRate, res.B.WinRate)
+	}
+}
+
+func TestMVPWeakestLink(t *testing.T) {
+	f := ForceStats{Units: []UnitStats{
+		{BV: 2000, MeanDamageDealt: 60},
+		{BV: 500, MeanDamageDealt: 20},
+		{BV: 1500, MeanDamageDealt: 25},
+	}}
+	if got := f.MVP(); got != 0 {
+		t.Errorf("MVP = %d, want 0", got)
+	}
+	if got := f.WeakestLink(); got != 2 {
+		t.Errorf("WeakestLink = %d, want 2", got)
+	}
+	if got := (&ForceStats{}).MVP(); got != -1 {
+		t.Errorf("empty MVP = %d, want -1", got)
+	}
+}
+
+func TestSPAHooks(t *testing.T) {
+	parse := func(s string) []*SPA {
+		spa, err := ParseSPA(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []*SPA{spa}
+	}
+	tests := []struct {
+		spa    string
+		weapon string
+		rm     int
+		mode   MoveMode
+		want   int // weapon mod + attacker move mod
+	}{
+		{"sniper", "Large Laser", 4, ModeStand, -2},
+		{"sniper", "Large Laser", 2, ModeWalk, 0}, // medium +1, walked +1
+		{"jumping_jack", "Medium Laser", 0, ModeJump, 1},
+		{"jumping_jack", "Medium Laser", 0, ModeRun, 2},
+		{"weapon_specialist:Medium Laser", "medium laser", 0, ModeStand, -2},
+		{"weapon_specialist:Medium Laser", "Large Laser", 0, ModeStand, 0},
+	}
+	for _, tt := range tests {
+		spas := parse(tt.spa)
+		got := spaWeaponMod(spas, tt.weapon, tt.rm) + attackerMoveMod(tt.mode, spas)
+		if got != tt.want {
+			t.Errorf("%s with %s (rm %d, mode %d) = %d, want %d", tt.spa, tt.weapon, tt.rm, tt.mode, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"gunslinger", "weapon_specialist"} {
+		if _, err := ParseSPA(bad); err == nil {
+			t.Errorf("ParseSPA(%q) succeeded, want error", bad)
+		}
+	}
+
+	m, dodger := BuildHBK4P(), BuildHBK4P()
+	m.SPAs = parse("melee_specialist")
+	dodger.SPAs = parse("dodge")
+	if toHit, dmg, ok := m.spaPhysical(dodger); !ok || toHit != 1 || dmg != 1 {
+		t.Errorf("melee specialist vs dodge = %d/%d/%v, want 1/1/true", toHit, dmg, ok)
+	}
+	if _, _, ok := dodger.spaPhysical(m); ok {
+		t.Error("dodging pilot should not make physical attacks")
+	}
+}
+
+func TestQuirks(t *testing.T) {
+	v := &Variant{
+		Name: "Test", ModelCode: "TST-1", Tonnage: 50, WalkMP: 4, RunMP: 6, HSCount: 10, ArmorTotal: 150,
+		Weapons: []VariantWeapon{
+			{Name: "Medium Laser", Type: "energy", Damage: 5, Heat: 3, Short: 3, Medium: 6, Long: 9, Location: "RA", Quantity: 1},
+			{Name: "Medium Laser", Type: "energy", Damage: 5, Heat: 3, Short: 3, Medium: 6, Long: 9, Location: "LA", Quantity: 1},
+		},
+		Quirks:       []string{"imp_target_long", "cramped_cockpit", "exposed_actuators", "not_a_quirk"},
+		WeaponQuirks: []ingestion.WeaponQuirk{{Quirk: "accurate", Location: "LA", Weapon: "ISMediumLaser"}},
+	}
+	m := BuildMechState(v, nil)
+	if len(m.Quirks) != 2 {
+		t.Fatalf("got %d simulated quirks, want 2", len(m.Quirks))
+	}
+	if ra, la := m.Weapons[0].ToHitMod, m.Weapons[1].ToHitMod; ra != 0 || la != -1 {
+		t.Errorf("ToHitMod RA/LA = %d/%d, want 0/-1", ra, la)
+	}
+	if got := m.weaponMod(&m.Weapons[0], 8); got != -1 {
+		t.Errorf("long-range quirk mod = %d, want -1", got)
+	}
+	if got := m.weaponMod(&m.Weapons[0], 2); got != 0 {
+		t.Errorf("short-range quirk mod = %d, want 0", got)
+	}
+	if got := m.psrMod(); got != 1 {
+		t.Errorf("psrMod = %d, want 1", got)
+	}
+
+	// Two lasers in one arm: each slot's quirks go to its own laser
+	v.Weapons = []VariantWeapon{
+		{Name: "Medium Laser", Type: "energy", Damage: 5, Heat: 3, Short: 3, Medium: 6, Long: 9, Location: "RA", Quantity: 2},
+	}
+	v.WeaponQuirks = []ingestion.WeaponQuirk{
+		{Quirk: "imp_cooling", Location: "RA", Slot: 5, Weapon: "ISMediumLaser"},
+		{Quirk: "accurate", Location: "RA", Slot: 4, Weapon: "ISMediumLaser"},
+		{Quirk: "poor_cooling", Location: "RA", Slot: 4, Weapon: "ISMediumLaser"},
+	}
+	m = BuildMechState(v, nil)
+	if len(m.Weapons) != 2 {
+		t.Fatalf("got %d weapons, want 2", len(m.Weapons))
+	}
+	if a, b := m.Weapons[0], m.Weapons[1]; a.ToHitMod != -1 || a.Heat != 4 || b.ToHitMod != 0 || b.Heat != 2 {
+		t.Errorf("to-hit/heat = %d/%d and %d/%d, want -1/4 and 0/2", a.ToHitMod, a.Heat, b.ToHitMod, b.Heat)
+	}
+}
+
+func TestPhysicalAttacks(t *testing.T) {
+	// 50-ton Hunchback, piloting 5 before modifiers
+	tests := []struct {
+		name    string
+		setup   func(m *MechState)
+		loc     int
+		wantOK  bool
+		wantTN  int
+		wantDmg int
+		wantHow string
+	}{
+		{"punch", func(m *MechState) {}, LocLA, true, 5, 5, "Punch"},
+		{"upper arm hit", func(m *MechState) { m.LimbCrits[LocLA].Upper = true }, LocLA, true, 7, 2, "Punch"},
+		{"hand hit", func(m *MechState) { m.LimbCrits[LocLA].Hand = true }, LocLA, true, 6, 5, "Punch"},
+		{"shoulder hit", func(m *MechState) { m.LimbCrits[LocLA].Shoulder = true }, LocLA, false, 0, 0, ""},
+		{"fired arm", func(m *MechState) { m.FiredFrom[LocRA] = true }, LocRA, false, 0, 0, ""},
+		{"hatchet", func(m *MechState) {
+			m.Slots[LocRA] = append(m.Slots[LocRA], "Hatchet", "Hatchet", "Hatchet", "Hatchet")
+		}, LocRA, true, 4, 10, "Hatchet"},
+		{"hatchet without hand", func(m *MechState) {
+			m.Slots[LocRA] = append(m.Slots[LocRA], "Hatchet")
+			m.LimbCrits[LocRA].Hand = true
+		}, LocRA, true, 6, 5, "Punch"},
+		{"destroyed hatchet", func(m *MechState) {
+			m.Slots[LocRA] = append(m.Slots[LocRA], "Hatchet")
+			m.MeleeDestroyed[LocRA] = true
+		}, LocRA, true, 5, 5, "Punch"},
+	}
+	for _, tt := range tests {
+		m := BuildHBK4P()
+		tt.setup(m)
+		a, ok := m.armAttackFor(tt.loc, m.Piloting)
+		if ok != tt.wantOK || ok && (a.tn != tt.wantTN || a.damage != tt.wantDmg || a.name != tt.wantHow) {
+			t.Errorf("%s: got %s TN %d dmg %d (%v), want %s TN %d dmg %d (%v)",
+				tt.name, a.name, a.tn, a.damage, ok, tt.wantHow, tt.wantTN, tt.wantDmg, tt.wantOK)
+		}
+	}
+
+	m := BuildHBK4P()
+	m.LimbCrits[LocLL].Lower = true
+	if k, ok := m.kickAttack(m.Piloting); !ok || k.loc != LocRL || k.tn != 3 || k.damage != 10 {
+		t.Errorf("kick = %+v (%v), want undamaged right leg TN 3 dmg 10", k, ok)
+	}
+
+	// Charge after running 6 hexes: 50 × 6 / 10 = 30 damage
+	target := BuildHBK4P()
+	target.Piloting = 4
+	m.LastMoveMode, m.LastHexMoved = ModeRun, 6
+	if !m.canCharge(target) || m.chargeDamage() != 30 || m.chargeTN(target) != 6 {
+		t.Errorf("charge: ok %v dmg %d TN %d, want true 30 6", m.canCharge(target), m.chargeDamage(), m.chargeTN(target))
+	}
+}
+
+func TestTerrain(t *testing.T) {
+	board := NewBoard(5, 1)
+	for _, line := range []string{
+		`hex 0101 0 "" ""`,
+		`hex 0201 0 "smoke:2" ""`,
+		`hex 0301 0 "building:3:8;bldg_cf:15;bldg_elev:1" ""`,
+		`hex 0401 0 "water:1" ""`,
+		`hex 0501 0 "water:2" ""`,
+	} {
+		parseHexLine(board, line)
+	}
+	board.buildGrid()
+	play := board.forSim()
+
+	if los := CheckLOS(play, HexCoord{1, 1}, HexCoord{3, 1}); los.CanSee {
+		t.Errorf("heavy smoke in LOS should block")
+	}
+	if los := CheckLOS(play, HexCoord{3, 1}, HexCoord{4, 1}); !los.CanSee || los.TargetCover != 1 {
+		t.Errorf("depth 1 water: %+v, want visible with +1 cover", los)
+	}
+	if los := CheckLOS(play, HexCoord{4, 1}, HexCoord{5, 1}); los.CanSee {
+		t.Errorf("deep water should block LOS")
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	m := BuildHBK4P()
+	m.Pos = HexCoord{4, 1}
+	m.enterHex(play, rng)
+	if !m.submerged(LocLL) || m.submerged(LocRA) {
+		t.Errorf("depth 1 water should submerge the legs only")
+	}
+	if got := m.dissipation(Conditions{}); got != m.Dissipation+4 {
+		t.Errorf("dissipation in water = %d, want %d (4 leg heat sinks)", got, m.Dissipation+4)
+	}
+	armor := m.Armor[LocLL]
+	m.weaponHit(LocLL, 5, false, nil)
+	if m.Armor[LocLL] != armor {
+		t.Errorf("leg hit in depth 1 water should strike the water")
+	}
+
+	// A 50-ton 'Mech brings down a CF 15 heavy building: 2 damage for the
+	// floor, as its CF gives, and 5 for the fall
+	points := func() int {
+		n := m.RearArmor[0] + m.RearArmor[1] + m.RearArmor[2]
+		for loc := 0; loc < NumLoc; loc++ {
+			n += m.Armor[loc] + m.IS[loc]
+		}
+		return n
+	}
+	before := points()
+	m.Pos = HexCoord{3, 1}
+	m.LastMoveMode, m.LastHexMoved = ModeWalk, 1
+	m.enterHex(play, rng)
+	if play.BuildingAt(HexCoord{3, 1}) != nil || m.Building != nil {
+		t.Errorf("building should have collapsed")
+	}
+	if got := before - points(); got != 2+5 {
+		t.Errorf("collapse did %d damage, want 7", got)
+	}
+	if ok, _ := play.Get(HexCoord{3, 1}).HasTerrain(TerrainRubble); !ok {
+		t.Errorf("collapsed building should leave rubble")
+	}
+	if ok, _ := board.Get(HexCoord{3, 1}).HasTerrain(TerrainBuilding); !ok {
+		t.Errorf("collapse must not change the shared board")
+	}
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		spec            string
+		toHit, heat     int
+		walk, run, jump int // from 5/8/3
+		wantErr         bool
+	}{
+		{"", 0, 0, 5, 8, 3, false},
+		{"night", 3, 0, 5, 8, 3, false},
+		{"dusk,heavy_fog,downpour", 4, 0, 5, 8, 3, false},
+		{"cold", 0, -2, 5, 8, 3, false},
+		{"hot", 0, 2, 5, 8, 3, false},
+		{"high_gravity", 0, 0, 3, 5, 2, false},
+		{"low_gravity", 0, 0, 10, 15, 6, false},
+		{"vacuum,rain", 0, 0, 0, 0, 0, true},
+		{"blizzard", 0, 0, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConditions(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseConditions(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		w, r, j := c.scaleMP(5, 8, 3)
+		if c.toHitMod() != tt.toHit || c.heat() != tt.heat || w != tt.walk || r != tt.run || j != tt.jump {
+			t.Errorf("%q: to-hit %d heat %d MP %d/%d/%d, want %d %d %d/%d/%d",
+				tt.spec, c.toHitMod(), c.heat(), w, r, j, tt.toHit, tt.heat, tt.walk, tt.run, tt.jump)
+		}
+	}
+
+	// Cold maps cool every 'Mech, on top of its heat sinks
+	m := BuildHBK4P()
+	cold, _ := ParseConditions("cold")
+	if got := m.dissipation(cold); got != m.Dissipation+2 {
+		t.Errorf("dissipation when cold = %d, want %d", got, m.Dissipation+2)
+	}
+}
+
+func TestIndirectFire(t *testing.T) {
+	for _, tt := range []struct {
+		slot string
+		name string
+	}{
+		{"ISTAG", "TAG"},
+		{"CLLightTAG (omnipod)", "Light TAG"},
+		{"Medium Laser", ""},
+	} {
+		w, ok := tagWeapon(tt.slot, LocRA)
+		if ok != (tt.name != "") || w.Name != tt.name {
+			t.Errorf("tagWeapon(%q) = %q, %v, want %q", tt.slot, w.Name, ok, tt.name)
+		}
+	}
+
+	board := NewBoard(5, 1)
+	for _, line := range []string{
+		`hex 0201 0 "smoke:2" ""`,
+	} {
+		parseHexLine(board, line)
+	}
+	board.buildGrid()
+
+	shooter, spotter, target := BuildHBK4P(), BuildHBK4P(), BuildHBK4P()
+	shooter.Pos, target.Pos, spotter.Pos = HexCoord{1, 1}, HexCoord{4, 1}, HexCoord{5, 1}
+	spotter.LastMoveMode = ModeWalk
+	if los := CheckLOS(board, shooter.Pos, target.Pos); los.CanSee {
+		t.Fatalf("smoke should block the shooter's LOS")
+	}
+
+	lrm := &SimWeapon{Name: "LRM 20", Category: catLRM, RackSize: 20, ShortRange: 7, MedRange: 14, LongRange: 21}
+	arrow := &SimWeapon{Name: "Arrow IV", Category: catArrowIV, RackSize: 20, ShortRange: 17, MedRange: 17, LongRange: 17}
+	base, mod := indirectMods(board, shooter, target, ModeStand, 0, nil)
+	if _, ok := mod(lrm); ok {
+		t.Errorf("LRMs need a spotter to fire indirectly")
+	}
+	if md, ok := mod(arrow); !ok || base+md != shooter.Gunnery+7 {
+		t.Errorf("Arrow IV artillery TN = %d, %v, want %d", base+md, ok, shooter.Gunnery+7)
+	}
+
+	// +1 indirect, +1 for the spotter walking, +1 once it has fired
+	_, mod = indirectMods(board, shooter, target, ModeStand, 0, []*MechState{spotter})
+	if md, ok := mod(lrm); !ok || md != 2 {
+		t.Errorf("spotted LRM modifier = %d, %v, want 2", md, ok)
+	}
+	spotter.FiredFrom[LocRA] = true
+	if _, _, md := spot(board, target, []*MechState{spotter}); md != 3 {
+		t.Errorf("spotter that attacked: modifier %d, want 3", md)
+	}
+
+	// Guided ammo against a tagged target
+	target.Tagged = true
+	target.LastMoveMode, target.LastHexMoved = ModeRun, 7
+	shooter.SemiGuided, shooter.HomingArrowIV = true, true
+	if got, want := shooter.attackMod(lrm, target, 4), -tmmFromHexesMoved(7, ModeRun); got != want {
+		t.Errorf("semi-guided LRM modifier = %d, want %d", got, want)
+	}
+
+	// Homing Arrow IV fires at 4 whatever else applies, seen or not, up to
+	// its longest range
+	shooter.Weapons = []SimWeapon{*arrow}
+	shooter.Weapons[0].Location = LocRA
+	shooter.ArmActuatorHit[LocRA] = 1
+	shooter.Heat, shooter.Prone = 15, true
+	open := NewBoard(18, 1)
+	for _, tt := range []struct {
+		board  *Board
+		target HexCoord
+	}{{board, HexCoord{4, 1}}, {open, HexCoord{18, 1}}} {
+		target.Pos = tt.target
+		for f := 0; f < 6 && !canWeaponFire(&shooter.Weapons[0], shooter.Pos, f, 0, target.Pos); f++ {
+			shooter.Facing = f + 1
+		}
+		var fires []ReplayWeaponFire
+		fireWeaponsReplay(shooter, target, tt.board, ReachableHex{Mode: ModeRun}, ReachableHex{Mode: ModeRun, HexesMoved: 7}, nil, "attacker", nil, &fires, rand.New(rand.NewPCG(1, 2)))
+		if len(fires) != 1 || fires[0].TN != homingTN {
+			t.Errorf("homing Arrow IV at range %d: %+v, want one shot at TN %d", HexDistance(shooter.Pos, target.Pos), fires, homingTN)
+		}
+	}
+}
+
+func TestElectronics(t *testing.T) {
+	for _, tt := range []struct {
+		slots []string
+		want  Electronics
+	}{
+		{[]string{"ISGuardianECMSuite", "ISGuardianECMSuite"}, Electronics{ECM: ecmGuardian, ECMRange: 6}},
+		{[]string{"CLWatchdogECMSuite"}, Electronics{ECM: ecmGuardian, ECMRange: 3, ProbeRange: 4}},
+		{[]string{"ISBloodhoundActiveProbe", "ISAngelECMSuite"}, Electronics{ECM: ecmAngel, ECMRange: 6, ProbeRange: 8, Bloodhound: true}},
+		{[]string{"ISC3SlaveUnit"}, Electronics{C3: c3Slave}},
+		{[]string{"ISC3MasterUnit", "ISC3MasterUnit"}, Electronics{C3: c3Master}},
+		{[]string{"Null Signature System", "IS Ammo C3 Remote Sensor"}, Electronics{NullSig: true}},
+	} {
+		var e Electronics
+		for _, s := range tt.slots {
+			e.add(s)
+		}
+		if e != tt.want {
+			t.Errorf("%v: got %+v, want %+v", tt.slots, e, tt.want)
+		}
+	}
+
+	board := NewBoard(16, 17)
+	board.buildGrid()
+	shooter, target, buddy := BuildHBK4P(), BuildHBK4P(), BuildHBK4P()
+	shooter.HasArtemisIV = true
+	shooter.Pos, target.Pos, buddy.Pos = HexCoord{1, 1}, HexCoord{1, 13}, HexCoord{1, 10}
+
+	target.EW = Electronics{ECM: ecmGuardian, ECMRange: 6}
+	shooter.electronics(board, target, nil, nil)
+	if shooter.UnderECM != ecmGuardian || artemisBonus(shooter, munArtemis) != 0 {
+		t.Errorf("Artemis inside enemy ECM: UnderECM %d, bonus %d", shooter.UnderECM, artemisBonus(shooter, munArtemis))
+	}
+	buddy.EW = Electronics{ProbeRange: 4}
+	shooter.electronics(board, target, []*MechState{buddy}, nil)
+	if shooter.UnderECM != ecmNone {
+		t.Errorf("a probe in range should counter Guardian ECM")
+	}
+	target.EW.ECM = ecmAngel
+	shooter.electronics(board, target, []*MechState{buddy}, nil)
+	if shooter.UnderECM != ecmAngel {
+		t.Errorf("only Angel ECM or a Bloodhound counters Angel ECM")
+	}
+
+	// C3: the slave fires at the master's range
+	target.EW = Electronics{}
+	shooter.EW, buddy.EW = Electronics{C3: c3Slave}, Electronics{C3: c3Master}
+	shooter.electronics(board, target, []*MechState{buddy}, nil)
+	ml := &SimWeapon{Name: "Medium Laser", ShortRange: 3, MedRange: 6, LongRange: 9}
+	if shooter.C3Dist != 3 || shooter.attackMod(ml, target, 8) != -4 {
+		t.Errorf("C3 distance %d, modifier %d; want 3, -4", shooter.C3Dist, shooter.attackMod(ml, target, 8))
+	}
+
+	// Stealth armor runs off the ECM: no bubble, +1/+2 at medium/long, 10 heat
+	target.EW = Electronics{ECM: ecmGuardian, ECMRange: 6, Stealth: true, StealthEngaged: true}
+	if target.ecmRange() != 0 || target.stealthMod(0) != 0 || target.stealthMod(2) != 1 || target.stealthMod(4) != 2 {
+		t.Errorf("stealth: bubble %d, mods %d/%d/%d", target.ecmRange(), target.stealthMod(0), target.stealthMod(2), target.stealthMod(4))
+	}
+	if got := target.dissipation(Conditions{}); got != target.Dissipation-10 {
+		t.Errorf("dissipation with stealth on = %d, want %d", got, target.Dissipation-10)
+	}
+
+	// The AI pays the heat only where the to-hit penalty is worth having:
+	// seen at medium range, not adjacent or out of range
+	target.EW = Electronics{ECM: ecmGuardian, ECMRange: 6, Stealth: true, NullSig: true}
+	for _, tt := range []struct {
+		pos       HexCoord
+		mod, heat int
+	}{
+		{HexCoord{1, 6}, 1, 20}, // range 5: medium for the HBK's lasers
+		{HexCoord{1, 2}, 0, 0},  // adjacent
+		{HexCoord{1, 13}, 0, 0}, // beyond long range
+	} {
+		target.Pos = tt.pos
+		target.ai().ChooseEW(target, board, []*MechState{shooter})
+		dist := HexDistance(shooter.Pos, target.Pos)
+		if mod, heat := target.stealthMod(rangeModifier(ml, dist)), target.ewHeat(); mod != tt.mod || heat != tt.heat {
+			t.Errorf("range %d: stealth modifier %d for %d heat, want %d for %d", dist, mod, heat, tt.mod, tt.heat)
+		}
+		if (target.ecmRange() == 0) != target.stealthOn() {
+			t.Errorf("range %d: stealth on %v, ECM bubble %d", dist, target.stealthOn(), target.ecmRange())
+		}
+	}
+}
+
+func TestEnhancers(t *testing.T) {
+	m := BuildHBK4P()
+	for _, s := range []string{"ISMASC", "Supercharger", "Industrial Triple-Strength Myomer"} {
+		m.addEnhancer(s)
+	}
+	if !m.MASC.Has || !m.Supercharger.Has || m.TSM {
+		t.Fatalf("addEnhancer: MASC %v, supercharger %v, TSM %v", m.MASC.Has, m.Supercharger.Has, m.TSM)
+	}
+	if got := m.boostRunMP(); got != 10 {
+		t.Errorf("MASC and supercharger from walk 4: run %d, want 10", got)
+	}
+	m.Supercharger.Has = false
+	if got := m.boostRunMP(); got != 8 {
+		t.Errorf("MASC from walk 4: run %d, want 8", got)
+	}
+
+	board := NewBoard(16, 17)
+	board.buildGrid()
+	m2 := &MechState2{Pos: HexCoord{8, 8}, WalkMP: 4, RunMP: 6, BoostRunMP: m.boostRunMP()}
+	boosted := 0
+	for _, h := range collectAllMoveOptions(board, m2) {
+		if h.Boosted {
+			boosted++
+			if h.MPSpent <= 6 || h.MPSpent > 8 {
+				t.Errorf("boosted move to %v spends %d MP", h.Coord, h.MPSpent)
+			}
+		}
+	}
+	if boosted == 0 {
+		t.Errorf("no MASC moves offered")
+	}
+
+	// Each use raises the target number; a rested system steps back down
+	rng := rand.New(rand.NewPCG(1, 2))
+	b := booster{Has: true, Level: len(boostTN) - 1}
+	if !b.roll(rng) || !b.Destroyed {
+		t.Errorf("a roll against 13 must fail")
+	}
+	b = booster{Has: true, Level: 2}
+	b.rest()
+	if !b.Has || b.Level != 1 || b.ready() {
+		t.Errorf("rest: level %d, ready %v", b.Level, b.ready())
+	}
+
+	// TSM at 9+ heat: +2 walk (less 1 for heat) and double punches
+	m = BuildHBK4P()
+	m.TSM = true
+	punch, _ := m.armAttackFor(LocRA, 4)
+	m.Heat = 9
+	if got := m.effectiveWalkMP(); got != m.WalkMP+1 {
+		t.Errorf("TSM walk at heat 9 = %d, want %d", got, m.WalkMP+1)
+	}
+	if hot, _ := m.armAttackFor(LocRA, 4); hot.damage != 2*punch.damage {
+		t.Errorf("TSM punch = %d, want %d", hot.damage, 2*punch.damage)
+	}
+}
+
+func TestMunitions(t *testing.T) {
+	for _, tt := range []struct {
+		slot  string
+		key   string
+		shots int
+	}{
+		{"IS Ammo AC/10", "ac/10", 10},
+		{"IS Ammo AC/10 Precision", "ac/10:precision", 5},
+		{"IS Ammo AC/20 Armor-Piercing", "ac/20:armor-piercing", 2},
+		{"IS LB 10-X Cluster Ammo", "lb 10-x ac:cluster", 10},
+		{"IS Ammo LRM-15 Artemis-capable", "lrm-15:artemis", 8},
+		{"IS Ammo SRM-6 Inferno", "srm-6:inferno", 15},
+	} {
+		if key, shots := parseAmmoSlotKey(tt.slot), guessAmmoShots(tt.slot); key != tt.key || shots != tt.shots {
+			t.Errorf("%s: key %q, %d shots; want %q, %d", tt.slot, key, shots, tt.key, tt.shots)
+		}
+	}
+
+	// LB-X: slug when it's easy to hit, cluster when it isn't
+	m, target := BuildHBK4P(), BuildHBK4P()
+	lbx := SimWeapon{Name: "LB 10-X AC", Category: catLBX, Damage: 10, RackSize: 10, AmmoKey: "lb 10-x ac"}
+	m.Ammo = map[string]int{"lb 10-x ac": 1, "lb 10-x ac:cluster": 1}
+	m.addMunition("lb 10-x ac", munCluster)
+	if !m.load(&lbx, target, 4) || lbx.Loaded != munStandard {
+		t.Errorf("LB-X at TN 4 loads %v, want slug", lbx.Loaded)
+	}
+	if !m.load(&lbx, target, 11) || lbx.Loaded != munCluster {
+		t.Errorf("LB-X at TN 11 loads %v, want cluster", lbx.Loaded)
+	}
+	if m.hasAmmo(&lbx) || m.load(&lbx, target, 4) {
+		t.Errorf("LB-X fires with both pools empty")
+	}
+
+	// Precision against a fast target; standard once precision runs out
+	target.LastHexMoved, target.LastMoveMode = 7, ModeRun
+	ac := SimWeapon{Name: "AC/10", Damage: 10, AmmoKey: "ac/10"}
+	m.Ammo = map[string]int{"ac/10": 5, "ac/10:precision": 1}
+	m.addMunition("ac/10", munPrecision)
+	if !m.load(&ac, target, 8) || ac.Loaded != munPrecision || m.munitionMod(&ac, ac.Loaded, target) != -2 {
+		t.Errorf("AC/10 at a running target loads %v", ac.Loaded)
+	}
+	if !m.load(&ac, target, 8) || ac.Loaded != munStandard || m.Ammo["ac/10"] != 4 {
+		t.Errorf("AC/10 with precision spent loads %v", ac.Loaded)
+	}
+
+	// Artemis only guides its own ammo
+	m.HasArtemisIV = true
+	lrm := SimWeapon{Name: "LRM 15", Category: catLRM, RackSize: 15, AmmoKey: "lrm-15"}
+	m.Ammo = map[string]int{"lrm-15": 8, "lrm-15:artemis": 8}
+	m.addMunition("lrm-15", munArtemis)
+	if !m.load(&lrm, target, 8) || lrm.Loaded != munArtemis || artemisBonus(m, munStandard) != 0 {
+		t.Errorf("LRM with Artemis loads %v", lrm.Loaded)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	if ai, err := ParseStrategy(""); err != nil || ai != (Tactician{}) {
+		t.Errorf("empty strategy = %v, %v; want the Tactician", ai, err)
+	}
+	if _, err := ParseStrategy("berserker"); err == nil {
+		t.Errorf("unknown strategy parsed")
+	}
+
+	board := NewBoard(16, 17)
+	board.buildGrid()
+	me, op := BuildHBK4P(), BuildHBK4P()
+	me.Pos, op.Pos = HexCoord{8, 2}, HexCoord{8, 14}
+	me2, op2 := lightState(me), lightState(op)
+	opts := collectAllMoveOptions(board, me2)
+	closest := 1 << 30
+	for _, h := range opts {
+		closest = min(closest, HexDistance(h.Coord, op.Pos))
+	}
+	before := slices.Clone(opts)
+	if h := (Brawler{}).ChooseMove(board, me2, op2, true, op.Pos, op.Facing, nil, opts, nil); HexDistance(h.Coord, op.Pos) != closest {
+		t.Errorf("brawler stops %d hexes out, could reach %d", HexDistance(h.Coord, op.Pos), closest)
+	}
+	// The options are shared with the other side's search, so stay as given
+	if !slices.Equal(opts, before) {
+		t.Errorf("choosing a move rewrote the options")
+	}
+
+	// Adjacent and jumping: the brawler will DFA, the sniper never does
+	me.Pos, op.Pos = HexCoord{8, 8}, HexCoord{8, 9}
+	me.LastMoveMode, me.LastHexMoved = ModeJump, 3
+	if (Sniper{}).ChoosePhysical(me, op, 1, 0) || me.DeclaredPhysical != physNone {
+		t.Errorf("sniper declared %v", me.DeclaredPhysical)
+	}
+	if !(Brawler{}).ChoosePhysical(me, op, 1, 0) {
+		t.Errorf("brawler passed up a DFA")
+	}
+
+	// The sniper runs cool
+	me.Facing = bearingToFacing(me.Pos, op.Pos)
+	me.Heat = 6
+	fire, heat := (Sniper{}).ChooseFire(me, board, op, 1, 4)
+	if len(fire) == 0 || me.Heat+heat-me.dissipation(board.Conditions) > 4 {
+		t.Errorf("sniper fires %v for %d heat from %d", fire, heat, me.Heat)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	sample := func(n, mean int) []int {
+		turns := make([]int, n)
+		for i := range turns {
+			turns[i] = mean - 4 + rng.IntN(9) + rng.IntN(5)
+		}
+		return turns
+	}
+
+	if got := groupedMedian([]int{3, 3, 3, 3}, make([]int, MaxTurns+2), nil); got != 3 {
+		t.Errorf("grouped median of a constant sample = %v", got)
+	}
+
+	small := bootstrapRating(sample(100, 8), sample(100, 10), 1)
+	large := bootstrapRating(sample(1600, 8), sample(1600, 10), 1)
+	for _, r := range []Rating{small, large} {
+		for _, e := range []Estimate{r.Offense, r.Defense, r.CR} {
+			if e.Low > e.Value || e.Value > e.High {
+				t.Errorf("%d sims: %v outside [%v, %v]", r.Sims, e.Value, e.Low, e.High)
+			}
+		}
+	}
+	if large.CR.HalfWidth() >= small.CR.HalfWidth() {
+		t.Errorf("CR ± %.2f at 1600 sims, ± %.2f at 100", large.CR.HalfWidth(), small.CR.HalfWidth())
+	}
+}
+
+func TestTournament(t *testing.T) {
+	// Sampled pairings never repeat and give everyone the same number of games
+	for _, n := range []int{5, 8, 31} {
+		pairs := pairings(n, 4, rand.New(rand.NewPCG(1, 2)))
+		seen, games := map[[2]int]bool{}, make([]int, n)
+		for _, p := range pairs {
+			key := [2]int{min(p[0], p[1]), max(p[0], p[1])}
+			if p[0] == p[1] || seen[key] {
+				t.Errorf("n=%d: pairing %v repeated", n, p)
+			}
+			seen[key] = true
+			games[p[0]]++
+			games[p[1]]++
+		}
+		for i, g := range games {
+			if g != 4 {
+				t.Errorf("n=%d: mech %d plays %d opponents", n, i, g)
+			}
+		}
+	}
+
+	// 0 beats 1 beats 2 beats 0 by the same margin and everyone beats 3: the
+	// cycle fits level with 3 at the bottom
+	elo := FitElo(4, []Matchup{
+		{A: 0, B: 1, AWins: 6, BWins: 2}, {A: 1, B: 2, AWins: 5, BWins: 1, Draws: 2}, {A: 2, B: 0, AWins: 6, BWins: 2},
+		{A: 0, B: 3, AWins: 8}, {A: 1, B: 3, AWins: 8}, {A: 3, B: 2, BWins: 8},
+	})
+	if math.Abs(elo[0]-elo[1]) > 0.5 || math.Abs(elo[1]-elo[2]) > 0.5 || elo[3] >= EloBase-200 {
+		t.Errorf("elo = %.0f", elo)
+	}
+	// A 3:1 record is 191 points
+	if d := FitElo(2, []Matchup{{A: 0, B: 1, AWins: 3000, BWins: 1000}}); math.Abs(d[0]-d[1]-191) > 1 {
+		t.Errorf("3:1 record is %.0f points", d[0]-d[1])
+	}
+}
+
+func TestBaselinePanel(t *testing.T) {
+	est := func(v, hw float64) Estimate { return Estimate{v, v - hw, v + hw} }
+	a := Rating{Offense: est(4, 0.5), Defense: est(6, 0.5), CR: est(6.5, 0.3), Sims: 400}
+	b := Rating{Offense: est(8, 1), Defense: est(5, 1), CR: est(3.5, 0.4), Sims: 600}
+
+	if got := CombineRatings([]Rating{a}, []float64{3}); got != a {
+		t.Errorf("a single rating came back as %+v", got)
+	}
+	// Weighted 2:1, with the half-widths added in quadrature
+	r := CombineRatings([]Rating{a, b}, []float64{2, 1})
+	if math.Abs(r.CR.Value-5.5) > 1e-9 || math.Abs(r.CR.HalfWidth()-math.Hypot(0.2, 0.4/3)) > 1e-9 || r.Sims != 1000 {
+		t.Errorf("combined CR %.3f ± %.3f over %d sims", r.CR.Value, r.CR.HalfWidth(), r.Sims)
+	}
+
+	// A baseline scores exactly 5.0 against itself without playing
+	panel := []Baseline{{ID: 7, Mech: BuildHBK4P(), Weight: 1, Turns: 9}}
+	if pr := RatePanel(nil, panel[0].Mech, 7, panel, RatingConfig{}, nil); pr.CR.Value != 5 || pr.Offense.Value != 9 || pr.Sims != 0 {
+		t.Errorf("baseline rated itself %+v", pr.Rating)
+	}
+}
+
+func TestBoardGen(t *testing.T) {
+	profiles := make([]BoardProfile, 0, len(BoardProfiles))
+	for _, k := range BoardProfileKeys() {
+		profiles = append(profiles, BoardProfiles[k])
+	}
+	boards := GenerateBoardPool(profiles, len(profiles), 3)
+	again := GenerateBoardPool(profiles, len(profiles), 3)
+	dir := t.TempDir()
+	for i, b := range boards {
+		key := BoardProfileKeys()[i]
+		path := dir + "/" + key + ".board"
+		if err := b.SaveBoard(path); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ParseBoard(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Same seed, same board; and it survives the trip through a file
+		count := map[TerrainType]int{}
+		for j := range b.Grid {
+			g, a, r := b.Grid[j], again[i].Grid[j], read.Grid[j]
+			if g.Elevation != a.Elevation || g.Elevation != r.Elevation ||
+				len(g.Terrain) != len(a.Terrain) || len(g.Terrain) != len(r.Terrain) {
+				t.Fatalf("%s: hex %v differs", key, g.Coord)
+			}
+			for k, f := range g.Terrain {
+				if f != a.Terrain[k] || f != r.Terrain[k] {
+					t.Fatalf("%s: hex %v terrain %v, again %v, read %v", key, g.Coord, f, a.Terrain[k], r.Terrain[k])
+				}
+				count[f.Type]++
+			}
+		}
+		switch key {
+		case "woods":
+			if count[TerrainWoods] < 80 {
+				t.Errorf("woods board has %d woods hexes", count[TerrainWoods])
+			}
+		case "urban":
+			if count[TerrainBuilding] < 50 || count[TerrainPavement] == 0 {
+				t.Errorf("urban board has %d buildings, %d pavement", count[TerrainBuilding], count[TerrainPavement])
+			}
+		case "hills":
+			if count[TerrainWater] == 0 {
+				t.Errorf("hills board has no water")
+			}
+		}
+	}
+	if _, err := ParseBoardProfile("swamp"); err == nil {
+		t.Error("unknown profile accepted")
+	}
+}
+
+func TestTerrainClass(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"open", "open"},
+		{"woods", "dense"},
+		{"urban", "urban"},
+	}
+	for _, tt := range tests {
+		boards := GenerateBoardPool([]BoardProfile{BoardProfiles[tt.profile]}, 8, 1)
+		if got := len(ClassifyBoards(boards)[tt.want]); got < 6 {
+			t.Errorf("%d of 8 %s boards are %s", got, tt.profile, tt.want)
+		}
+	}
+
+	// A flat empty board has clear LOS everywhere
+	b := NewBoard(16, 17)
+	b.buildGrid()
+	if s := MeasureBoard(b, rand.New(rand.NewPCG(1, 2))); s.LOSObscured != 0 || s.TerrainClass() != "open" {
+		t.Errorf("empty board: %+v", s)
+	}
+	if _, err := ParseTerrainClass("swamp"); err == nil {
+		t.Error("unknown terrain class accepted")
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	if sc, err := ParseScenario(""); sc != nil || err != nil {
+		t.Errorf("empty scenario = %v, %v", sc, err)
+	}
+	if _, err := ParseScenario("capture the flag"); err == nil {
+		t.Error("unknown scenario accepted")
+	}
+
+	hbk := BuildHBK4P()
+	fast := BuildHBK4P()
+	fast.WalkMP, fast.RunMP = 8, 12
+	boards := GenerateBoardPool([]BoardProfile{BoardProfiles["open"]}, 4, 1)
+	tests := []struct {
+		scenario string
+		minWin   float64 // force A's win rate, a fast mech pair against Hunchbacks
+	}{
+		{"breakthrough", 0.8},
+		{"escort", 0.8},
+		{"recon", 0.6},
+	}
+	for _, tt := range tests {
+		sc, _ := ParseScenario(tt.scenario)
+		cfg := BattleConfig{
+			Boards: boards, Seed: 5, N: 10, Scenario: sc,
+			A: Force{Units: []ForceUnit{{fast, 1000}, {fast, 1000}}},
+			B: Force{Units: []ForceUnit{{hbk, 1000}, {hbk, 1000}}},
+		}
+		res, err := RunBattles(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.A.WinRate < tt.minWin || res.A.MeanPoints == 0 {
+			t.Errorf("%s: A wins %.2f with %.1f points", tt.scenario, res.A.WinRate, res.A.MeanPoints)
+		}
+		if res.MeanTurns > float64(sc.Turns) {
+			t.Errorf("%s: %.1f turns past the limit of %d", tt.scenario, res.MeanTurns, sc.Turns)
+		}
+		again, _ := RunBattles(context.Background(), cfg)
+		if again.A.Wins != res.A.Wins || again.A.MeanPoints != res.A.MeanPoints {
+			t.Errorf("%s: not deterministic", tt.scenario)
+		}
+	}
+
+	// Against a Hunchback panel the fast mech rates above par on the
+	// objective, and a baseline scores exactly 5.0 against itself
+	panel := []Baseline{{ID: 7, Mech: hbk, Weight: 1}}
+	sc := Scenarios["breakthrough"]
+	if cr := ScenarioRating(boards, fast, 0, sc, panel, 5, rand.New(rand.NewPCG(1, 2))); cr <= 5 {
+		t.Errorf("fast mech breakthrough CR %.2f", cr)
+	}
+	if cr := ScenarioRating(boards, hbk, 7, sc, panel, 5, nil); cr != 5 {
+		t.Errorf("baseline rated itself %.2f", cr)
+	}
+}
+
+func TestReplayFormat(t *testing.T) {
+	boards := GenerateBoardPool([]BoardProfile{BoardProfiles["woods"]}, 2, 1)
+	hbk := BuildHBK4P()
+	r := SimulateDuelReplay(CombineBoards(boards[0], boards[1]), hbk, hbk, rand.New(rand.NewPCG(3, 4)))
+
+	data, err := EncodeReplay(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeReplay(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("replay changed in a round trip")
+	}
+
+	// Version 1 rows, gzipped JSON, still read
+	var v1 bytes.Buffer
+	gz := gzip.NewWriter(&v1)
+	r.Version = 0
+	json.NewEncoder(gz).Encode(r)
+	gz.Close()
+	r.Version = ReplayVersion
+	old, err := DecodeReplay(v1.Bytes())
+	if err != nil || old.Version != 1 || !reflect.DeepEqual(old.Turns, r.Turns) {
+		t.Errorf("version 1 replay: %v", err)
+	}
+	if len(data) >= v1.Len() {
+		t.Errorf("version 2 is %d bytes, version 1 %d", len(data), v1.Len())
+	}
+
+	// A corrupt turn count is an error, not a huge allocation
+	for _, turns := range []int{-1, MaxTurns + 1, 1 << 40} {
+		var bad bytes.Buffer
+		gz := gzip.NewWriter(&bad)
+		h := r.Header(0)
+		h.Turns = turns
+		cbor.NewEncoder(gz).Encode(h)
+		gz.Close()
+		if _, err := DecodeReplay(bad.Bytes()); err == nil {
+			t.Errorf("header with %d turns decoded", turns)
+		}
+	}
+
+	// A stream from the middle starts with full snapshots and no board
+	from := len(r.Turns) / 2
+	part := &ReplayData{}
+	for _, d := range r.Deltas(from, len(r.Turns)) {
+		part.applyDelta(d)
+	}
+	if h := r.Header(from); h.Elevation != nil || h.Turns != len(r.Turns) {
+		t.Errorf("header from turn %d: %d hexes, %d turns", from, len(h.Elevation), h.Turns)
+	}
+	if !reflect.DeepEqual(part.Turns, r.Turns[from:]) {
+		t.Errorf("turns from %d differ", from)
+	}
+}
+
+func TestReplayAnalysis(t *testing.T) {
+	boards := GenerateBoardPool([]BoardProfile{BoardProfiles["woods"]}, 2, 1)
+	board := CombineBoards(boards[0], boards[1])
+	hbk := BuildHBK4P()
+	for seed := uint64(0); seed < 8; seed++ {
+		r := SimulateDuelReplay(board, hbk, hbk, rand.New(rand.NewPCG(seed, 5)))
+		a := AnalyzeReplay(r)
+
+		fires, dealt := 0, 0
+		for _, turn := range r.Turns {
+			for _, e := range turn.Events {
+				if m := fireMessage.FindStringSubmatch(e.Message); e.Type == "fire" && m != nil {
+					fires++
+					if e.Actor == "attacker" {
+						n, _ := strconv.Atoi(m[3])
+						dealt += n
+					}
+				}
+			}
+		}
+		if a.Attacker.Shots+a.Defender.Shots != fires || a.Attacker.WeaponDamage != dealt {
+			t.Errorf("seed %d: %d shots and %d attacker damage, events have %d and %d", seed, a.Attacker.Shots+a.Defender.Shots, a.Attacker.WeaponDamage, fires, dealt)
+		}
+
+		for _, m := range []MechAnalysis{a.Attacker, a.Defender} {
+			if len(m.Damage) != len(r.Turns) || len(m.Heat) != len(r.Turns) {
+				t.Fatalf("seed %d: %d damage and %d heat turns for %d turns", seed, len(m.Damage), len(m.Heat), len(r.Turns))
+			}
+			taken := 0
+			for i, d := range m.Damage {
+				taken += d.Taken
+				if d.Taken < 0 || d.Total != taken || d.Share < 0 || d.Share > 1 {
+					t.Errorf("seed %d turn %d: %+v", seed, i+1, d)
+				}
+			}
+			for _, w := range m.Weapons {
+				if w.Hits > w.Shots || w.Expected < 0 || w.Expected > 1 {
+					t.Errorf("seed %d: %+v", seed, w)
+				}
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(r.Result, "defender_destroyed"):
+			if a.Winner != "attacker" {
+				t.Errorf("seed %d: %s won %s", seed, a.Winner, r.Result)
+			}
+		case r.Result == "attacker_destroyed":
+			if a.Winner != "defender" {
+				t.Errorf("seed %d: %s won %s", seed, a.Winner, r.Result)
+			}
+		}
+		if a.Winner != "" && (a.DecisiveTurn < 1 || a.DecisiveTurn > len(r.Turns) || a.Decisive == "") {
+			t.Errorf("seed %d: decisive turn %d of %d: %q", seed, a.DecisiveTurn, len(r.Turns), a.Decisive)
+		}
+
+		// A replay from before shots and deployment were recorded falls
+		// back on its fire events
+		r.AttackerStart, r.DefenderStart = nil, nil
+		for i := range r.Turns {
+			r.Turns[i].Weapons = nil
+		}
+		old := AnalyzeReplay(r)
+		if old.Attacker.Hits != a.Attacker.Hits || old.Defender.WeaponDamage != a.Defender.WeaponDamage || old.Winner != a.Winner {
+			t.Errorf("seed %d: from events %+v, from shots %+v", seed, old.Attacker, a.Attacker)
+		}
+	}
+}
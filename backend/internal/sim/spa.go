@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ─── Special Pilot Abilities ────────────────────────────────────────────────
+//
+// SPAs (Campaign Operations) are hooks on the rolls they change. A nil or zero
+// hook leaves that roll alone; every hook is a modifier added to the target
+// number, so negative is better for the pilot.
+
+// SPA is one Special Pilot Ability.
+type SPA struct {
+	Name string
+
+	// WeaponMod adjusts a weapon attack. rangeMod is the weapon's range
+	// modifier before the ability (0, 2 or 4).
+	WeaponMod func(weapon string, rangeMod int) int
+	// MoveMod adjusts the pilot's own attacker movement modifier.
+	MoveMod func(mode MoveMode) int
+
+	PhysicalMod     int // to-hit for the pilot's physical attacks
+	PhysicalDamage  int // extra damage per physical attack that hits
+	PhysicalDefense int // to-hit for physical attacks against the pilot
+	NoPhysical      bool
+	PSRMod          int
+}
+
+// spaRegistry builds an SPA from its key and optional argument
+// ("weapon_specialist:Medium Laser").
+var spaRegistry = map[string]func(arg string) (*SPA, error){
+	// Range modifiers are halved: medium +1, long +2
+	"sniper": func(string) (*SPA, error) {
+		return &SPA{Name: "Sniper", WeaponMod: func(_ string, rm int) int {
+			return rm/2 - rm
+		}}, nil
+	},
+	// Jumping attacker modifier is +1 instead of +3
+	"jumping_jack": func(string) (*SPA, error) {
+		return &SPA{Name: "Jumping Jack", MoveMod: func(mode MoveMode) int {
+			if mode == ModeJump {
+				return -2
+			}
+			return 0
+		}}, nil
+	},
+	// -2 to-hit with one named weapon type
+	"weapon_specialist": func(arg string) (*SPA, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("weapon_specialist needs a weapon, e.g. weapon_specialist:Medium Laser")
+		}
+		return &SPA{Name: "Weapon Specialist (" + arg + ")", WeaponMod: func(weapon string, _ int) int {
+			if strings.EqualFold(weapon, arg) {
+				return -2
+			}
+			return 0
+		}}, nil
+	},
+	// -1 to-hit and +1 damage on physical attacks
+	"melee_specialist": func(string) (*SPA, error) {
+		return &SPA{Name: "Melee Specialist", PhysicalMod: -1, PhysicalDamage: 1}, nil
+	},
+	// +2 to physical attacks against the pilot. The sim always declares the
+	// dodge, so the pilot makes no physical attacks of their own.
+	"dodge": func(string) (*SPA, error) {
+		return &SPA{Name: "Dodge", PhysicalDefense: 2, NoPhysical: true}, nil
+	},
+	// -1 to piloting skill rolls. The sim has no skids or sideslips, so the
+	// bonus applies to every PSR.
+	"maneuvering_ace": func(string) (*SPA, error) {
+		return &SPA{Name: "Maneuvering Ace", PSRMod: -1}, nil
+	},
+}
+
+// RegisterSPA adds or replaces an ability under key.
+func RegisterSPA(key string, build func(arg string) (*SPA, error)) {
+	spaRegistry[key] = build
+}
+
+// SPAKeys lists the registered ability keys in sorted order.
+func SPAKeys() []string {
+	keys := make([]string, 0, len(spaRegistry))
+	for k := range spaRegistry {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseSPA builds an ability from "key" or "key:argument".
+func ParseSPA(s string) (*SPA, error) {
+	key, arg, _ := strings.Cut(strings.TrimSpace(s), ":")
+	build, ok := spaRegistry[strings.ToLower(strings.TrimSpace(key))]
+	if !ok {
+		return nil, fmt.Errorf("unknown SPA %q (have %s)", key, strings.Join(SPAKeys(), ", "))
+	}
+	return build(strings.TrimSpace(arg))
+}
+
+// ParseSPAs parses a list of abilities, as stored on list entries.
+func ParseSPAs(specs []string) ([]*SPA, error) {
+	var spas []*SPA
+	for _, s := range specs {
+		spa, err := ParseSPA(s)
+		if err != nil {
+			return nil, err
+		}
+		spas = append(spas, spa)
+	}
+	return spas, nil
+}
+
+// attackerMoveMod is the to-hit modifier for the attacker's own movement,
+// after SPAs.
+func attackerMoveMod(mode MoveMode, spas []*SPA) int {
+	mod := 0
+	switch mode {
+	case ModeWalk:
+		mod = 1
+	case ModeRun:
+		mod = 2
+	case ModeJump:
+		mod = 3
+	}
+	for _, s := range spas {
+		if s.MoveMod != nil {
+			mod += s.MoveMod(mode)
+		}
+	}
+	return mod
+}
+
+// spaWeaponMod sums the SPA modifiers for one weapon attack.
+func spaWeaponMod(spas []*SPA, weapon string, rangeMod int) int {
+	mod := 0
+	for _, s := range spas {
+		if s.WeaponMod != nil {
+			mod += s.WeaponMod(weapon, rangeMod)
+		}
+	}
+	return mod
+}
+
+// weaponMod is the SPA and quirk modifier for firing w at range dist.
+func (m *MechState) weaponMod(w *SimWeapon, dist int) int {
+	if len(m.SPAs) == 0 && len(m.Quirks) == 0 {
+		return 0
+	}
+	rm := rangeModifier(w, dist)
+	if rm < 0 {
+		return 0
+	}
+	return spaWeaponMod(m.SPAs, w.Name, rm) + quirkWeaponMod(m.Quirks, rm)
+}
+
+// psrMod is the SPA and quirk modifier to every PSR.
+func (m *MechState) psrMod() int {
+	mod := 0
+	for _, s := range m.SPAs {
+		mod += s.PSRMod
+	}
+	for _, q := range m.Quirks {
+		mod += q.PSRMod
+	}
+	return mod
+}
+
+// spaPhysical returns the to-hit and damage modifiers for a physical attack
+// by m on target, and whether m may attack at all.
+func (m *MechState) spaPhysical(target *MechState) (toHit, damage int, ok bool) {
+	for _, s := range m.SPAs {
+		if s.NoPhysical {
+			return 0, 0, false
+		}
+		toHit += s.PhysicalMod
+		damage += s.PhysicalDamage
+	}
+	for _, s := range target.SPAs {
+		toHit += s.PhysicalDefense
+	}
+	return toHit, damage, true
+}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	"math"
@@ -97,6 +97,16 @@ func PositionScore(board *Board, me *MechState2, myHex ReachableHex,
 		if myHex.Mode == ModeRun {
 			heatPenalty = 3.0
 		}
+		// Water cools
+		if myHexData != nil && myHexData.WaterDepth() == 1 {
+			heatPenalty -= 3.0
+		}
+	}
+	// Standing in fire costs 5 heat
+	if myHexData != nil {
+		if onFire, _ := myHexData.HasTerrain(TerrainFire); onFire {
+			heatPenalty += 5.0
+		}
 	}
 
 	// ─── Weapon Arc Awareness ───
@@ -294,11 +304,11 @@ func optimalRange(mech *MechState2) int {
 	return 1
 }
 
-// calcOptimalRange computes the optimal engagement range using a damage-weighted average.
+// CalcOptimalRange computes the optimal engagement range using a damage-weighted average.
 // At each hex distance (1–30), all weapons that can fire (range ≤ longRange, TN ≤ 12) are
 // greedily selected by EV/heat ratio until heat-neutral. The final range is the damage-weighted
 // average across all ranges, giving a realistic tactical engagement distance.
-func calcOptimalRange(m *MechState) int {
+func CalcOptimalRange(m *MechState) int {
 	const baseTN = 8 // gunnery 4 + running +2 + TMM +2
 
 	// Damage-weighted average: sum(range * dmg) / sum(dmg)
@@ -450,20 +460,12 @@ func expectedDamage(mech *MechState2, dist int, los LOSResult,
 		}
 
 		// Attacker movement modifier
-		atkMoveMod := 0
-		switch myMove.Mode {
-		case ModeWalk:
-			atkMoveMod = 1
-		case ModeRun:
-			atkMoveMod = 2
-		case ModeJump:
-			atkMoveMod = 3
-		}
+		atkMoveMod := attackerMoveMod(myMove.Mode, mech.SPAs)
 
 		// Total target number
 		target := mech.GunnerySkill + rangeMod + minRangeMod + atkMoveMod +
 			los.WoodsMod + los.TargetCover + los.ElevationMod +
-			w.ToHitMod
+			w.ToHitMod + spaWeaponMod(mech.SPAs, w.Name, rangeMod) + quirkWeaponMod(mech.Quirks, rangeMod)
 
 		if target > 12 {
 			continue
@@ -520,6 +522,7 @@ func ChooseMovement(board *Board, me *MechState2, op *MechState2,
 
 func collectAllMoveOptions(board *Board, mech *MechState2) []ReachableHex {
 	var all []ReachableHex
+	walk, run, jump := board.Conditions.scaleMP(mech.WalkMP, mech.RunMP, mech.JumpMP)
 
 	// Standing still
 	all = append(all, ReachableHex{
@@ -528,21 +531,31 @@ func collectAllMoveOptions(board *Board, mech *MechState2) []ReachableHex {
 	})
 
 	// Walk
-	if mech.WalkMP > 0 {
+	if walk > 0 {
 		all = append(all, ReachableHexes(board, mech.Pos, mech.Facing,
-			mech.WalkMP, mech.RunMP, mech.JumpMP, ModeWalk)...)
+			walk, run, jump, ModeWalk)...)
 	}
 
 	// Run
-	if mech.RunMP > 0 {
+	if run > 0 {
 		all = append(all, ReachableHexes(board, mech.Pos, mech.Facing,
-			mech.WalkMP, mech.RunMP, mech.JumpMP, ModeRun)...)
+			walk, run, jump, ModeRun)...)
 	}
 
 	// Jump
-	if mech.JumpMP > 0 {
+	if jump > 0 {
 		all = append(all, ReachableHexes(board, mech.Pos, mech.Facing,
-			mech.WalkMP, mech.RunMP, mech.JumpMP, ModeJump)...)
+			walk, run, jump, ModeJump)...)
+	}
+
+	// MASC/supercharger: only the hexes a normal run can't reach
+	if boost, _, _ := board.Conditions.scaleMP(mech.BoostRunMP, 0, 0); boost > run {
+		for _, h := range ReachableHexes(board, mech.Pos, mech.Facing, walk, boost, jump, ModeRun) {
+			if h.MPSpent > run {
+				h.Boosted = true
+				all = append(all, h)
+			}
+		}
 	}
 
 	return all
@@ -0,0 +1,220 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Terrain effects ────────────────────────────────────────────────────────
+//
+// Water, buildings, rough, rubble, fire and smoke. Boards in the pool are
+// shared between goroutines, so anything that can change during a game
+// (building CF, collapse to rubble) lives on the per-game copy made by
+// forSim.
+
+// Building is one building hex in play.
+type Building struct {
+	Coord  HexCoord
+	Class  int // 1 light, 2 medium, 3 heavy, 4 hardened
+	CF     int // remaining construction factor
+	MaxCF  int // construction factor it started with
+	Height int // levels above the hex
+
+	board *Board
+}
+
+// defaultCF is the top of each building class's CF range, for
+// boards that don't give bldg_cf.
+var defaultCF = [5]int{0, 15, 40, 90, 120}
+
+// WaterDepth returns the hex's water depth, 0 for dry ground.
+func (h *Hex) WaterDepth() int {
+	if ok, depth := h.HasTerrain(TerrainWater); ok {
+		return depth
+	}
+	return 0
+}
+
+// BuildingHeight returns how many levels a building rises above the hex.
+func (h *Hex) BuildingHeight() int {
+	if ok, _ := h.HasTerrain(TerrainBuilding); !ok {
+		return 0
+	}
+	if ok, elev := h.HasTerrain(TerrainBldgElev); ok && elev > 0 {
+		return elev
+	}
+	return 1
+}
+
+// forSim returns a copy of the board that one game may change. Boards
+// without buildings never change and are returned as is.
+func (b *Board) forSim() *Board {
+	var found bool
+	for i := range b.Grid {
+		if ok, _ := b.Grid[i].HasTerrain(TerrainBuilding); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return b
+	}
+	cp := *b
+	cp.Grid = append([]Hex(nil), b.Grid...)
+	cp.buildings = make(map[HexCoord]*Building)
+	for i := range cp.Grid {
+		h := &cp.Grid[i]
+		ok, class := h.HasTerrain(TerrainBuilding)
+		if !ok {
+			continue
+		}
+		class = min(max(class, 1), 4)
+		cf := defaultCF[class]
+		if ok, v := h.HasTerrain(TerrainBldgCF); ok && v > 0 {
+			cf = v
+		}
+		cp.buildings[h.Coord] = &Building{Coord: h.Coord, Class: class, CF: cf, MaxCF: cf, Height: h.BuildingHeight(), board: &cp}
+	}
+	return &cp
+}
+
+// BuildingAt returns the standing building at h, if the board is in play.
+func (b *Board) BuildingAt(h HexCoord) *Building {
+	return b.buildings[h]
+}
+
+// collapse turns the building into rubble. Whoever is inside falls and takes
+// damage for the floors that come down on them, a tenth of the building's
+// starting CF per floor.
+func (bl *Building) collapse(occupant *MechState, rng *rand.Rand) {
+	delete(bl.board.buildings, bl.Coord)
+	hex := bl.board.Get(bl.Coord)
+	terrain := make([]TerrainFeature, 0, len(hex.Terrain))
+	for _, f := range hex.Terrain {
+		switch f.Type {
+		case TerrainBuilding, TerrainBldgCF, TerrainBldgElev:
+		default:
+			terrain = append(terrain, f)
+		}
+	}
+	hex.Terrain = append(terrain, TerrainFeature{Type: TerrainRubble, Level: 1})
+
+	if occupant == nil || occupant.isDestroyed() {
+		return
+	}
+	occupant.Building = nil
+	applyClusters(occupant, ceilDiv(bl.MaxCF, 10)*bl.Height, false, rng)
+	if !occupant.isDestroyed() {
+		occupant.applyFall(rng)
+	}
+}
+
+// enterHex applies the terrain of the hex m has just moved into and returns
+// what happened, for replays.
+func (m *MechState) enterHex(board *Board, rng *rand.Rand) []string {
+	hex := board.Get(m.Pos)
+	m.WaterDepth, m.Building = 0, nil
+	if hex == nil {
+		return nil
+	}
+	var notes []string
+	m.WaterDepth = hex.WaterDepth()
+	m.Vacuum = board.Conditions.Atmosphere == AtmoVacuum
+
+	// Standing in a burning hex: +5 heat, an outside source
+	if ok, _ := hex.HasTerrain(TerrainFire); ok {
+		m.HeatPenalty += 5
+		notes = append(notes, "In a burning hex, +5 heat")
+	}
+
+	// A building that can't carry the 'Mech comes down under it
+	if bl := board.BuildingAt(m.Pos); bl != nil {
+		m.Building = bl
+		if m.Tonnage > bl.CF {
+			bl.collapse(m, rng)
+			notes = append(notes, "Building collapses!")
+			return notes
+		}
+	}
+
+	// Rubble, or running through rough, takes a PSR; jumps land clear
+	if m.LastMoveMode != ModeJump && m.LastHexMoved > 0 && !m.Prone {
+		rubble, _ := hex.HasTerrain(TerrainRubble)
+		rough, _ := hex.HasTerrain(TerrainRough)
+		if rubble || rough && m.LastMoveMode == ModeRun {
+			if !m.rollPSR(0, rng) {
+				m.applyFall(rng)
+				notes = append(notes, "Failed terrain PSR, falls!")
+			}
+		}
+	}
+	return notes
+}
+
+// submerged reports whether loc is under water: legs at depth 1, the whole
+// 'Mech deeper. Submerged weapons can't fire.
+func (m *MechState) submerged(loc int) bool {
+	switch {
+	case m.WaterDepth >= 2:
+		return true
+	case m.WaterDepth == 1:
+		return loc == LocLL || loc == LocRL || m.Prone
+	}
+	return false
+}
+
+// dissipation is m's net heat dissipation this turn under c. Heat sinks
+// under water shed an extra point each, up to 6; stealth armor and
+// Null-Signature eat into it.
+func (m *MechState) dissipation(c Conditions) int {
+	if m.WaterDepth == 0 {
+		return m.Dissipation - c.heat() - m.ewHeat()
+	}
+	extra := m.Dissipation
+	if m.WaterDepth == 1 && !m.Prone {
+		extra = 0
+		for _, loc := range []int{LocLL, LocRL} {
+			extra += heatSinksIn(m.Slots[loc])
+		}
+	}
+	return m.Dissipation + min(extra, 6) - c.heat() - m.ewHeat()
+}
+
+// heatSinksIn counts the heat sinks in a location's crit slots. Double heat
+// sinks take three slots, two for Clan.
+func heatSinksIn(slots []string) int {
+	single, isDouble, clDouble := 0, 0, 0
+	for _, s := range slots {
+		s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+		switch {
+		case !strings.Contains(s, "heatsink"):
+		case strings.Contains(s, "double") && strings.HasPrefix(s, "cl"):
+			clDouble++
+		case strings.Contains(s, "double"):
+			isDouble++
+		default:
+			single++
+		}
+	}
+	return single + isDouble/3 + clDouble/2
+}
+
+// weaponHit applies a weapon hit, letting terrain take its share first. Leg
+// hits on a 'Mech in depth 1 water strike the water (partial cover); a
+// building absorbs CF/10 of each hit and takes the full damage itself.
+func (m *MechState) weaponHit(loc, dmg int, isRear bool, rng *rand.Rand) {
+	if m.WaterDepth == 1 && !m.Prone && (loc == LocLL || loc == LocRL) {
+		return
+	}
+	if bl := m.Building; bl != nil {
+		absorbed := min(dmg, ceilDiv(bl.CF, 10))
+		bl.CF -= dmg
+		dmg -= absorbed
+		if bl.CF <= 0 {
+			bl.collapse(m, rng)
+		}
+	}
+	if dmg > 0 {
+		m.applyDamage(loc, dmg, isRear, rng)
+	}
+}
@@ -0,0 +1,116 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// ─── Terrain classes ────────────────────────────────────────────────────────
+//
+// Boards are sorted into a few broad classes by what is on them, so a mech
+// can be rated on the kind of table a tournament announces: open ground,
+// mixed terrain, dense woods and hills, or a city. LOS density is sampled
+// with CheckLOS between random hexes, as a measure of how much of the board
+// a mech can shoot across.
+
+// BoardStats describes a board's terrain.
+type BoardStats struct {
+	Woods       float64 // share of hexes with woods
+	Water       float64 // share of hexes with water
+	Buildings   float64 // share of hexes with buildings
+	ElevVar     float64 // variance of hex elevation
+	LOSObscured float64 // share of sampled lines that are blocked or through woods or smoke
+}
+
+// losSamples is how many lines MeasureBoard checks.
+const losSamples = 400
+
+// MeasureBoard computes b's terrain stats, sampling LOS between random hexes
+// at least 3 apart.
+func MeasureBoard(b *Board, rng *rand.Rand) BoardStats {
+	var s BoardStats
+	n := float64(b.Width * b.Height)
+	sum, sumSq := 0.0, 0.0
+	for col := 1; col <= b.Width; col++ {
+		for row := 1; row <= b.Height; row++ {
+			hex := b.Get(HexCoord{Col: col, Row: row})
+			if hex == nil {
+				continue
+			}
+			if ok, _ := hex.HasTerrain(TerrainWoods); ok {
+				s.Woods++
+			}
+			if hex.WaterDepth() > 0 {
+				s.Water++
+			}
+			if ok, _ := hex.HasTerrain(TerrainBuilding); ok {
+				s.Buildings++
+			}
+			e := float64(hex.Elevation)
+			sum += e
+			sumSq += e * e
+		}
+	}
+	s.Woods /= n
+	s.Water /= n
+	s.Buildings /= n
+	s.ElevVar = sumSq/n - (sum/n)*(sum/n)
+
+	obscured := 0
+	for i := 0; i < losSamples; i++ {
+		from := b.randomHex(rng)
+		to := b.randomHex(rng)
+		for HexDistance(from, to) < 3 {
+			to = b.randomHex(rng)
+		}
+		if los := CheckLOS(b, from, to); !los.CanSee || los.WoodsMod > 0 {
+			obscured++
+		}
+	}
+	s.LOSObscured = float64(obscured) / losSamples
+	return s
+}
+
+// TerrainClassKeys lists the classes from most to least open.
+func TerrainClassKeys() []string {
+	return []string{"open", "mixed", "dense", "urban"}
+}
+
+// ParseTerrainClass checks that key is a terrain class.
+func ParseTerrainClass(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, k := range TerrainClassKeys() {
+		if k == key {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown terrain class %q (have %s)", key, strings.Join(TerrainClassKeys(), ", "))
+}
+
+// TerrainClass is the class of a board with stats s: urban with a tenth of
+// it built up, dense when most lines of sight are obscured or it is a third
+// woods, open when few are obscured and the ground is flat and dry, mixed
+// otherwise.
+func (s BoardStats) TerrainClass() string {
+	switch {
+	case s.Buildings >= 0.1:
+		return "urban"
+	case s.LOSObscured >= 0.6 || s.Woods >= 0.3:
+		return "dense"
+	case s.LOSObscured < 0.25 && s.Woods+s.Water < 0.1 && s.ElevVar < 0.5:
+		return "open"
+	}
+	return "mixed"
+}
+
+// ClassifyBoards sorts boards by terrain class. Board i's LOS is sampled
+// from RNG stream (1, i), so the same pool always sorts the same way.
+func ClassifyBoards(boards []*Board) map[string][]*Board {
+	classes := map[string][]*Board{}
+	for i, b := range boards {
+		class := MeasureBoard(b, rand.New(rand.NewPCG(1, uint64(i)))).TerrainClass()
+		classes[class] = append(classes[class], b)
+	}
+	return classes
+}
@@ -0,0 +1,202 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// ─── Round-robin tournament ─────────────────────────────────────────────────
+//
+// A tournament plays mutual-fire duels between every pairing in a pool of
+// mechs, or a sampled round-robin when the pool is too big for that: each
+// mech meets Opponents others (rounded up to even), picked by rotating a
+// shuffled order. The
+// mechs of a pairing take turns being the attacker. FitElo turns the
+// records into Bradley–Terry strengths on the Elo scale.
+
+// TournamentConfig describes a tournament.
+type TournamentConfig struct {
+	Boards    []*Board
+	Mechs     []*MechState
+	Opponents int    // per mech, rounded up to even; 0 plays a full round-robin
+	Duels     int    // per pairing, defaults to NumSimsPerBoard
+	Seed      uint64 // pairing k's duel d is RunDuels' duel k*Duels+d
+	Workers   int    // duels in parallel, defaults to 1
+
+	// Progress, if set, is called after each pairing with the number done.
+	Progress func(done, total int)
+}
+
+// Matchup is the record of Mechs[A] against Mechs[B].
+type Matchup struct {
+	A, B  int
+	AWins int
+	BWins int
+	Draws int
+}
+
+// TournamentResult is the outcome of RunTournament.
+type TournamentResult struct {
+	Matchups []Matchup
+	Elo      []float64 // by mech
+}
+
+// EloBase is the Elo of a mech that wins exactly half its games against the
+// field.
+const EloBase = 1500
+
+// RunTournament plays the pairings of cfg and fits Elo ratings to them.
+func RunTournament(ctx context.Context, cfg TournamentConfig) (*TournamentResult, error) {
+	if len(cfg.Boards) == 0 {
+		return nil, errors.New("sim: no boards")
+	}
+	if len(cfg.Mechs) < 2 {
+		return nil, errors.New("sim: a tournament needs at least two mechs")
+	}
+	duels := cfg.Duels
+	if duels <= 0 {
+		duels = NumSimsPerBoard
+	}
+	workers := max(cfg.Workers, 1)
+
+	pairs := pairings(len(cfg.Mechs), cfg.Opponents, rand.New(rand.NewPCG(cfg.Seed, math.MaxUint64)))
+	res := &TournamentResult{Matchups: make([]Matchup, len(pairs))}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				res.Matchups[k] = playPairing(cfg, pairs[k], k, duels)
+				if cfg.Progress != nil {
+					mu.Lock()
+					done++
+					cfg.Progress(done, len(pairs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for k := range pairs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res.Elo = FitElo(len(cfg.Mechs), res.Matchups)
+	return res, nil
+}
+
+// playPairing plays the duels of pairing k between mechs p[0] and p[1].
+func playPairing(cfg TournamentConfig, p [2]int, k, duels int) Matchup {
+	m := Matchup{A: p[0], B: p[1]}
+	dc := DuelConfig{Boards: cfg.Boards, Attacker: cfg.Mechs[p[0]], Defender: cfg.Mechs[p[1]], Seed: cfg.Seed}
+	swapped := dc
+	swapped.Attacker, swapped.Defender = dc.Defender, dc.Attacker
+	for d := 0; d < duels; d++ {
+		var w int
+		if d%2 == 0 {
+			w = duelWinner(playDuel(dc, k*duels+d).Result)
+		} else {
+			w = -duelWinner(playDuel(swapped, k*duels+d).Result)
+		}
+		switch w {
+		case 1:
+			m.AWins++
+		case -1:
+			m.BWins++
+		default:
+			m.Draws++
+		}
+	}
+	return m
+}
+
+// pairings lists the pairings for n mechs each meeting opponents others:
+// everyone plays everyone if opponents is 0 or covers the field, otherwise
+// round r pairs each mech with the one r places on in a shuffled order,
+// which never repeats a pairing.
+func pairings(n, opponents int, rng *rand.Rand) [][2]int {
+	var pairs [][2]int
+	if opponents <= 0 || opponents >= n-1 {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+		return pairs
+	}
+	order := rng.Perm(n)
+	// each round gives every mech two games, one as each neighbour
+	for r := 1; r <= (opponents+1)/2; r++ {
+		for k := 0; k < n; k++ {
+			pairs = append(pairs, [2]int{order[k], order[(k+r)%n]})
+		}
+	}
+	return pairs
+}
+
+// FitElo fits Bradley–Terry strengths to the matchups of n mechs by
+// minorization-maximization and returns them as Elo ratings, so that a
+// mech rated 400 points above another is expected to win 10 games to 1. A
+// draw counts half a win to each side. Each mech also gets one win and one
+// loss against a phantom rated EloBase, which keeps unbeaten and winless
+// mechs finite and anchors the scale.
+func FitElo(n int, matchups []Matchup) []float64 {
+	wins := make([]float64, n)
+	games := make([][]float64, n) // games[i][j], with the phantom at j == n
+	for i := range games {
+		games[i] = make([]float64, n+1)
+		games[i][n] = 2
+		wins[i] = 1
+	}
+	for _, m := range matchups {
+		played := float64(m.AWins + m.BWins + m.Draws)
+		games[m.A][m.B] += played
+		games[m.B][m.A] += played
+		wins[m.A] += float64(m.AWins) + float64(m.Draws)/2
+		wins[m.B] += float64(m.BWins) + float64(m.Draws)/2
+	}
+
+	p := make([]float64, n+1)
+	for i := range p {
+		p[i] = 1
+	}
+	next := make([]float64, n)
+	for iter := 0; iter < 1000; iter++ {
+		change := 0.0
+		for i := 0; i < n; i++ {
+			denom := 0.0
+			for j, g := range games[i] {
+				if g > 0 {
+					denom += g / (p[i] + p[j])
+				}
+			}
+			next[i] = wins[i] / denom
+			change = math.Max(change, math.Abs(math.Log(next[i]/p[i])))
+		}
+		copy(p, next)
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	elo := make([]float64, n)
+	for i := range elo {
+		elo[i] = EloBase + 400*math.Log10(p[i])
+	}
+	return elo
+}
@@ -0,0 +1,91 @@
+// Package simdb loads the simulator's variants from Postgres for the
+// commands that rate or replay them.
+package simdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/JustinWhittecar/slic/internal/sim"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ─── Load variants from DB ──────────────────────────────────────────────────
+
+// LoadVariants loads every variant with its movement and heat sinks, or
+// only those whose name or model code contains one of the comma-separated
+// filter terms. Weapons are loaded separately by LoadWeapons.
+func LoadVariants(ctx context.Context, pool *pgxpool.Pool, filter string) ([]sim.Variant, error) {
+	query := `
+		SELECT v.id, v.name, v.model_code, COALESCE(c.tech_base, 'Inner Sphere'),
+			   c.tonnage, vs.walk_mp, vs.run_mp, vs.jump_mp,
+			   vs.heat_sink_count, vs.heat_sink_type, vs.engine_type,
+			   COALESCE(vs.structure_type, 'Standard'), COALESCE(vs.has_targeting_computer, false),
+			   vs.armor_total
+		FROM variants v
+		JOIN chassis c ON c.id = v.chassis_id
+		JOIN variant_stats vs ON vs.variant_id = v.id`
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []sim.Variant
+	for rows.Next() {
+		var v sim.Variant
+		err := rows.Scan(&v.ID, &v.Name, &v.ModelCode, &v.TechBase,
+			&v.Tonnage, &v.WalkMP, &v.RunMP, &v.JumpMP,
+			&v.HSCount, &v.HSType, &v.EngineType,
+			&v.StructType, &v.HasTC, &v.ArmorTotal)
+		if err != nil {
+			continue
+		}
+		variants = append(variants, v)
+	}
+
+	if filter != "" {
+		filters := strings.Split(filter, ",")
+		var filtered []sim.Variant
+		for _, v := range variants {
+			for _, f := range filters {
+				f = strings.TrimSpace(f)
+				if strings.Contains(v.ModelCode, f) || strings.Contains(v.Name+" "+v.ModelCode, f) {
+					filtered = append(filtered, v)
+					break
+				}
+			}
+		}
+		variants = filtered
+	}
+
+	return variants, nil
+}
+
+// LoadWeapons fills in each variant's weapons.
+func LoadWeapons(ctx context.Context, pool *pgxpool.Pool, variants []sim.Variant) {
+	for i := range variants {
+		v := &variants[i]
+		wRows, err := pool.Query(ctx, `
+			SELECT e.name, COALESCE(e.type,''), COALESCE(e.damage,0), COALESCE(e.heat,0),
+				   COALESCE(e.min_range,0), COALESCE(e.short_range,0), COALESCE(e.medium_range,0),
+				   COALESCE(e.long_range,0), COALESCE(e.to_hit_modifier,0), COALESCE(e.rack_size,0),
+				   ve.location, ve.quantity
+			FROM variant_equipment ve
+			JOIN equipment e ON e.id = ve.equipment_id
+			WHERE ve.variant_id = $1
+			  AND e.type IN ('energy','ballistic','missile','artillery')`, v.ID)
+		if err != nil {
+			continue
+		}
+		for wRows.Next() {
+			var w sim.VariantWeapon
+			wRows.Scan(&w.Name, &w.Type, &w.Damage, &w.Heat,
+				&w.MinRange, &w.Short, &w.Medium, &w.Long,
+				&w.ToHitMod, &w.RackSize, &w.Location, &w.Quantity)
+			v.Weapons = append(v.Weapons, w)
+		}
+		wRows.Close()
+	}
+}
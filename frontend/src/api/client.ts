@@ -55,6 +55,11 @@ export interface MechListItem {
   heat_neutral_range?: string
   game_damage?: number
   combat_rating?: number
+  elo?: number
+  open_cr?: number
+  mixed_cr?: number
+  dense_cr?: number
+  urban_cr?: number
   bv_efficiency?: number
   goonhammer_rating?: string
 }
@@ -240,6 +245,35 @@ export async function fetchMech(id: number): Promise<MechDetail> {
   return res.json()
 }
 
+// Tournament matchups
+export interface MatchupRecord {
+  id: number
+  model_code: string
+  name: string
+  elo: number
+  wins: number
+  losses: number
+  draws: number
+  win_rate: number
+}
+
+export interface MechMatchups {
+  pool: string
+  elo: number
+  games: number
+  wins: number
+  losses: number
+  draws: number
+  best: MatchupRecord[]
+  worst: MatchupRecord[]
+}
+
+export async function fetchMatchups(id: number, limit = 5): Promise<MechMatchups> {
+  const res = await fetch(`${BASE}/mechs/${id}/matchups?limit=${limit}`)
+  if (!res.ok) throw new Error(`Failed to fetch matchups: ${res.status}`)
+  return res.json()
+}
+
 // Physical Models
 export interface PhysicalModel {
   id: number
@@ -20,7 +20,7 @@ interface ReplayEvent {
 }
 
 interface ReplayWeaponFire {
-  weapon: string; target: number; roll?: number; hit: boolean
+  weapon: string; target: number; roll?: number; tn?: number; hit: boolean
   damage: number; location?: string; crit?: string
 }
 
@@ -30,13 +30,48 @@ interface ReplayTurn {
 }
 
 interface ReplayData {
-  attackerName: string; defenderName: string
+  version: number; attackerName: string; defenderName: string
   boardWidth: number; boardHeight: number
   hexes: ReplayHex[]; turns: ReplayTurn[]; result: string
 }
 
+// What /replay/stream sends: a header, then one turn per line with only the
+// snapshot fields that changed (the first turn has them all)
+interface ReplayHeader {
+  version: number; attackerName: string; defenderName: string
+  boardWidth: number; boardHeight: number; result: string; turns: number
+  elevation?: number[]; terrain?: number[]; terrains?: string[]; hexes?: ReplayHex[]
+}
+
+type ReplayTurnDelta = Omit<ReplayTurn, 'attacker' | 'defender'> & {
+  attacker?: Partial<ReplayMechSnapshot>; defender?: Partial<ReplayMechSnapshot>
+}
+
+// What /replay/analysis sends, less the per-turn timelines
+interface WeaponStats {
+  weapon: string; shots: number; hits: number; damage: number
+  hitRate: number; expected: number
+}
+
+interface MechAnalysis {
+  name: string; weapons: WeaponStats[]
+  shots: number; hits: number; expectedHits: number; weaponDamage: number
+  firstOverheat?: number
+}
+
+interface ReplayCrit {
+  turn: number; mech: string; what: string; weapon?: string
+}
+
+interface ReplayAnalysis {
+  attacker: MechAnalysis; defender: MechAnalysis; winner?: string
+  crits: ReplayCrit[]; decisiveTurn?: number; decisive?: string
+}
+
 // ─── Constants ──────────────────────────────────────────────────────────────
 
+const REPLAY_VERSION = 2
+
 const HEX_SIZE = 22
 const SQRT3 = Math.sqrt(3)
 
@@ -71,6 +106,58 @@ function hexPoints(cx: number, cy: number, size: number): string {
   return pts.join(' ')
 }
 
+// ─── Streaming ──────────────────────────────────────────────────────────────
+
+// The header packs the board column by column: an elevation and an index
+// into terrains per hex
+function unpackHexes(h: ReplayHeader): ReplayHex[] {
+  if (!h.elevation) return h.hexes ?? []
+  return h.elevation.map((elevation, i) => ({
+    col: Math.floor(i / h.boardHeight) + 1,
+    row: i % h.boardHeight + 1,
+    elevation,
+    terrain: h.terrains?.[h.terrain?.[i] ?? 0] || undefined,
+  }))
+}
+
+// Reads a replay stream, calling onUpdate with the replay so far as turns
+// arrive
+async function streamReplay(mechId: number, signal: AbortSignal, onUpdate: (r: ReplayData) => void) {
+  const res = await fetch(`/api/variants/${mechId}/replay/stream`, { signal })
+  if (!res.ok || !res.body) throw new Error()
+  const reader = res.body.pipeThrough(new TextDecoderStream()).getReader()
+  let replay: ReplayData | null = null
+  let buf = ''
+  for (;;) {
+    const { value, done } = await reader.read()
+    if (done) break
+    buf += value
+    const lines = buf.split('\n')
+    buf = lines.pop() ?? ''
+    for (const line of lines) {
+      if (!line) continue
+      if (!replay) {
+        const h: ReplayHeader = JSON.parse(line)
+        if (h.version > REPLAY_VERSION) throw new Error()
+        replay = {
+          version: h.version, attackerName: h.attackerName, defenderName: h.defenderName,
+          boardWidth: h.boardWidth, boardHeight: h.boardHeight,
+          hexes: unpackHexes(h), turns: [], result: h.result,
+        }
+        continue
+      }
+      const d: ReplayTurnDelta = JSON.parse(line)
+      const prev = replay.turns[replay.turns.length - 1]
+      replay.turns.push({
+        ...d,
+        attacker: { ...prev?.attacker, ...d.attacker } as ReplayMechSnapshot,
+        defender: { ...prev?.defender, ...d.defender } as ReplayMechSnapshot,
+      })
+    }
+    if (replay) onUpdate({ ...replay, turns: [...replay.turns] })
+  }
+}
+
 // ─── Component ──────────────────────────────────────────────────────────────
 
 interface CombatReplayProps {
@@ -85,6 +172,7 @@ export function CombatReplay({ mechId }: CombatReplayProps) {
   const [playing, setPlaying] = useState(false)
   const [speed, setSpeed] = useState(1)
   const [eventsOpen, setEventsOpen] = useState(false)
+  const [analysis, setAnalysis] = useState<ReplayAnalysis | null>(null)
   const playRef = useRef(false)
   const intervalRef = useRef<ReturnType<typeof setInterval>>()
 
@@ -96,21 +184,39 @@ export function CombatReplay({ mechId }: CombatReplayProps) {
   }>>([])
   const fireIdRef = useRef(0)
 
+  // Stream the replay, showing it from the first turn that arrives
   useEffect(() => {
+    const ctrl = new AbortController()
     setLoading(true)
     setError(false)
-    fetch(`/api/variants/${mechId}/replay`)
-      .then(r => { if (!r.ok) throw new Error(); return r.json() })
-      .then((data: ReplayData) => { setReplay(data); setLoading(false); setCurrentTurn(0) })
-      .catch(() => { setError(true); setLoading(false) })
+    setReplay(null)
+    setCurrentTurn(0)
+    streamReplay(mechId, ctrl.signal, data => {
+      setReplay(data)
+      if (data.turns.length > 0) setLoading(false)
+    })
+      .then(() => setLoading(false))
+      .catch(() => { if (!ctrl.signal.aborted) { setError(true); setLoading(false) } })
+    return () => ctrl.abort()
+  }, [mechId])
+
+  useEffect(() => {
+    const ctrl = new AbortController()
+    setAnalysis(null)
+    fetch(`/api/variants/${mechId}/replay/analysis`, { signal: ctrl.signal })
+      .then(res => res.ok ? res.json() : null)
+      .then(setAnalysis)
+      .catch(() => {})
+    return () => ctrl.abort()
   }, [mechId])
 
   // Auto-play
+  const ready = replay !== null
   useEffect(() => {
-    if (!replay) return
+    if (!ready) return
     const timer = setTimeout(() => { setPlaying(true); playRef.current = true }, 800)
     return () => clearTimeout(timer)
-  }, [replay])
+  }, [ready])
 
   // Playback
   useEffect(() => {
@@ -428,6 +534,32 @@ export function CombatReplay({ mechId }: CombatReplayProps) {
         </div>
       )}
 
+      {/* Analysis */}
+      {analysis && (
+        <div className="text-[10px] font-mono space-y-0.5" style={{ color: 'var(--text-secondary)' }}>
+          {[analysis.attacker, analysis.defender].map((m, i) => (
+            <div key={i}>
+              <span style={{ color: i === 0 ? '#4aa3df' : '#e94560' }}>{i === 0 ? 'ATK' : 'DEF'}:</span>{' '}
+              {m.hits}/{m.shots} hits ({m.shots > 0 ? Math.round(m.hits / m.shots * 100) : 0}% vs{' '}
+              {m.shots > 0 ? Math.round(m.expectedHits / m.shots * 100) : 0}% expected), {m.weaponDamage} dmg
+              {m.firstOverheat ? `, overheated turn ${m.firstOverheat}` : ''}
+            </div>
+          ))}
+          {!!analysis.decisiveTurn && (
+            <button
+              onClick={() => {
+                const i = replay.turns.findIndex(t => t.turn === analysis.decisiveTurn)
+                if (i >= 0) { setCurrentTurn(i); setPlaying(false) }
+              }}
+              className="text-left cursor-pointer hover:underline"
+              style={{ color: '#ffd700' }}
+            >
+              Decisive turn {analysis.decisiveTurn}: {analysis.decisive}
+            </button>
+          )}
+        </div>
+      )}
+
       {/* Events Log */}
       <div>
         <button
@@ -15,7 +15,7 @@ import { useAuth } from '../contexts/AuthContext'
 
 export const DEFAULT_COLUMN_ORDER = [
   'name', 'tonnage', 'tech_base', 'role', 'bv', 'move', 'tmm', 'combat_rating', 'bv_efficiency',
-  'goonhammer', 'era', 'intro_year', 'armor_total', 'heat_neutral_damage', 'alpha_damage', 'optimal_range',
+  'elo', 'open_cr', 'mixed_cr', 'dense_cr', 'urban_cr', 'goonhammer', 'era', 'intro_year', 'armor_total', 'heat_neutral_damage', 'alpha_damage', 'optimal_range',
   'armor_coverage_pct', 'engine_type', 'engine_rating', 'heat_sinks', 'rules_level', 'source', 'config',
 ]
 
@@ -26,7 +26,8 @@ export const DEFAULT_VISIBILITY: VisibilityState = {
   armor_total: false, heat_neutral_damage: false, alpha_damage: false, optimal_range: false,
   armor_coverage_pct: false, era: false, intro_year: false,
   engine_type: false, engine_rating: false, heat_sinks: false,
-  goonhammer: false,
+  goonhammer: false, elo: false,
+  open_cr: false, mixed_cr: false, dense_cr: false, urban_cr: false,
   rules_level: false, source: false, config: false,
 }
 
@@ -45,6 +46,11 @@ export const COLUMN_DEFS_META = [
   { id: 'optimal_range', label: 'Optimal Range' },
   { id: 'combat_rating', label: 'Combat Rating' },
   { id: 'bv_efficiency', label: 'BV Efficiency' },
+  { id: 'elo', label: 'Elo' },
+  { id: 'open_cr', label: 'CR (Open)' },
+  { id: 'mixed_cr', label: 'CR (Mixed)' },
+  { id: 'dense_cr', label: 'CR (Dense)' },
+  { id: 'urban_cr', label: 'CR (Urban)' },
   { id: 'armor_coverage_pct', label: 'Armor %' },
   { id: 'engine_type', label: 'Engine Type' },
   { id: 'engine_rating', label: 'Engine Rating' },
@@ -318,6 +324,31 @@ export function MechTable({ filters, onSelectMech, selectedMechId, onCountChange
       header: () => <span className="tooltip-header" data-tip="Combat value per BV spent (HBK-4P = 5)">BV Eff</span>,
       cell: info => <span className="tabular-nums">{info.getValue() != null ? info.getValue()!.toFixed(1) : '—'}</span>,
     }),
+    columnHelper.accessor('elo', {
+      id: 'elo',
+      header: () => <span className="tooltip-header" data-tip="Rating from a round-robin of duels against other mechs (average = 1500)">Elo</span>,
+      cell: info => <span className="tabular-nums">{info.getValue() != null && info.getValue()! > 0 ? info.getValue()!.toFixed(0) : '—'}</span>,
+    }),
+    columnHelper.accessor('open_cr', {
+      id: 'open_cr',
+      header: () => <span className="tooltip-header" data-tip="Combat rating on open ground">CR Open</span>,
+      cell: info => <span className="tabular-nums">{info.getValue() != null && info.getValue()! > 0 ? info.getValue()!.toFixed(1) : '—'}</span>,
+    }),
+    columnHelper.accessor('mixed_cr', {
+      id: 'mixed_cr',
+      header: () => <span className="tooltip-header" data-tip="Combat rating on mixed terrain">CR Mixed</span>,
+      cell: info => <span className="tabular-nums">{info.getValue() != null && info.getValue()! > 0 ? info.getValue()!.toFixed(1) : '—'}</span>,
+    }),
+    columnHelper.accessor('dense_cr', {
+      id: 'dense_cr',
+      header: () => <span className="tooltip-header" data-tip="Combat rating on dense woods and hills">CR Dense</span>,
+      cell: info => <span className="tabular-nums">{info.getValue() != null && info.getValue()! > 0 ? info.getValue()!.toFixed(1) : '—'}</span>,
+    }),
+    columnHelper.accessor('urban_cr', {
+      id: 'urban_cr',
+      header: () => <span className="tooltip-header" data-tip="Combat rating on city boards">CR Urban</span>,
+      cell: info => <span className="tabular-nums">{info.getValue() != null && info.getValue()! > 0 ? info.getValue()!.toFixed(1) : '—'}</span>,
+    }),
     columnHelper.accessor('armor_coverage_pct', {
       id: 'armor_coverage_pct',
       header: () => <span className="tooltip-header" data-tip="Armor as % of max possible">Armor %</span>,